@@ -8,10 +8,19 @@ import (
 
 	"github.com/99designs/gqlgen/graphql/handler"
 	"github.com/99designs/gqlgen/graphql/playground"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/attachments"
 	"github.com/Zayan-Mohamed/do-task-backend/internal/auth"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/caldav"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/calendar"
 	"github.com/Zayan-Mohamed/do-task-backend/internal/database"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/digest"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/export"
 	"github.com/Zayan-Mohamed/do-task-backend/internal/graph/generated"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/mailer"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/mailin"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/reminders"
 	"github.com/Zayan-Mohamed/do-task-backend/internal/resolvers"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/webhooks"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -25,6 +34,10 @@ func main() {
 	}
 
 	auth.InitJWT()
+	passwords, err := auth.PasswordPolicyFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to load password policy: %v", err)
+	}
 
 	// Set up the database connection
 	db, err := database.Connect()
@@ -42,6 +55,29 @@ func main() {
 		log.Printf("Warning: Failed to initialize sample data: %v", err)
 	}
 
+	// Deliver queued webhook events in the background
+	dispatcher := webhooks.NewDispatcher(db)
+	go dispatcher.Run(context.Background())
+
+	// Fire task reminders and send weekly digests; replicas share the work
+	// through row locks
+	outbox := mailer.FromEnv()
+	scheduler := reminders.NewScheduler(db, outbox)
+	go scheduler.Run(context.Background())
+	digestJob := digest.NewJob(db, outbox)
+	go digestJob.Run(context.Background())
+
+	// Accept email-to-task mail when a listen address is configured
+	if addr := os.Getenv("INBOUND_SMTP_ADDR"); addr != "" {
+		smtpServer := mailin.NewServer(addr, mailin.NewGateway(db))
+		go func() {
+			log.Printf("Inbound SMTP listening on %s", addr)
+			if err := smtpServer.ListenAndServe(); err != nil {
+				log.Printf("Inbound SMTP stopped: %v", err)
+			}
+		}()
+	}
+
 	// Create a new Gin router
 	r := gin.Default()
 
@@ -58,8 +94,10 @@ func main() {
 
 	// Set up the GraphQL handler
 	srv := handler.NewDefaultServer(generated.NewExecutableSchema(generated.Config{
-		Resolvers: &resolvers.Resolver{DB: db},
+		Resolvers: &resolvers.Resolver{DB: db, Webhooks: dispatcher, Mailer: outbox, Passwords: passwords},
 	}))
+	srv.SetErrorPresenter(resolvers.PresentError)
+	srv.SetRecoverFunc(resolvers.RecoverPanic)
 
 	// Routes
 	r.POST("/query", func(c *gin.Context) {
@@ -69,6 +107,22 @@ func main() {
 		srv.ServeHTTP(c.Writer, c.Request)
 	})
 
+	// Data export endpoints
+	exportHandler := &export.Handler{DB: db}
+	exportHandler.Register(r)
+
+	// iCalendar subscription feed
+	calendarHandler := &calendar.Handler{DB: db}
+	calendarHandler.Register(r)
+
+	// CalDAV task sync
+	caldavHandler := &caldav.Handler{DB: db}
+	caldavHandler.Register(r)
+
+	// Task attachment downloads
+	attachmentHandler := &attachments.Handler{DB: db}
+	attachmentHandler.Register(r)
+
 	// Health check endpoint
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{"status": "healthy", "service": "dotask-backend"})
@@ -0,0 +1,123 @@
+// Package apperr defines the errors the API reports to clients. Each carries a
+// code the GraphQL error presenter puts in extensions.code; any other error is
+// internal, so it is logged and masked.
+package apperr
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Code classifies an error for clients
+type Code string
+
+// Error codes
+const (
+	CodeUnauthenticated Code = "UNAUTHENTICATED"
+	CodeNotFound        Code = "NOT_FOUND"
+	CodeValidation      Code = "VALIDATION"
+	CodeConflict        Code = "CONFLICT"
+	CodeForbidden       Code = "FORBIDDEN"
+	CodeRateLimited     Code = "RATE_LIMITED"
+	CodeInternal        Code = "INTERNAL"
+)
+
+// FieldError is a problem with one input field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is an error that is safe to show to clients
+type Error struct {
+	Code    Code
+	Message string
+	Fields  []FieldError           // the invalid fields of a VALIDATION error
+	Details map[string]interface{} // more extensions, such as the current record in a CONFLICT
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// New returns an error with the given code and message
+func New(code Code, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// NotFound reports that a record, such as "task", does not exist or belongs
+// to someone else
+func NotFound(what string) *Error {
+	return New(CodeNotFound, "%s not found", what)
+}
+
+// Unauthenticated reports a request that needs a signed-in user
+func Unauthenticated(format string, args ...interface{}) *Error {
+	return New(CodeUnauthenticated, format, args...)
+}
+
+// Forbidden reports an action the user may not take
+func Forbidden(format string, args ...interface{}) *Error {
+	return New(CodeForbidden, format, args...)
+}
+
+// Conflict reports a clash with the current state, such as a duplicate name
+func Conflict(format string, args ...interface{}) *Error {
+	return New(CodeConflict, format, args...)
+}
+
+// RateLimited reports a client that has to slow down
+func RateLimited(format string, args ...interface{}) *Error {
+	return New(CodeRateLimited, format, args...)
+}
+
+// Invalid reports a bad value in one input field, or in the input as a whole
+// when field is empty
+func Invalid(field string, format string, args ...interface{}) *Error {
+	var v Validation
+	v.Add(field, format, args...)
+	return v.err()
+}
+
+// Validation collects the problems with an input, one field at a time
+type Validation struct {
+	fields []FieldError
+}
+
+// Add records a problem with field
+func (v *Validation) Add(field string, format string, args ...interface{}) {
+	v.fields = append(v.fields, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// Check records a problem with field unless ok
+func (v *Validation) Check(ok bool, field string, format string, args ...interface{}) {
+	if !ok {
+		v.Add(field, format, args...)
+	}
+}
+
+// Err returns a VALIDATION error listing every problem, or nil without any
+func (v *Validation) Err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	return v.err()
+}
+
+func (v *Validation) err() *Error {
+	messages := make([]string, len(v.fields))
+	for i, field := range v.fields {
+		messages[i] = field.Message
+	}
+	return &Error{Code: CodeValidation, Message: strings.Join(messages, "; "), Fields: v.fields}
+}
+
+// CodeOf returns the code of err, INTERNAL for errors that are not an *Error
+func CodeOf(err error) Code {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr.Code
+	}
+	return CodeInternal
+}
@@ -0,0 +1,49 @@
+// Package attachments serves the files attached to tasks
+package attachments
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/auth"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/database"
+	"github.com/gin-gonic/gin"
+)
+
+// Handler serves attachment downloads
+type Handler struct {
+	DB *database.DB
+}
+
+// Register mounts the download route on r. It requires authentication.
+func (h *Handler) Register(r gin.IRouter) {
+	r.GET("/attachments/:id", auth.RequireAuth(), h.Download)
+}
+
+// Path returns the download path of an attachment
+func Path(id string) string {
+	return "/attachments/" + id
+}
+
+// Download sends an attachment of the authenticated user. Files are always
+// served as downloads so uploaded HTML cannot run in the app's origin.
+func (h *Handler) Download(c *gin.Context) {
+	userID, _, _, _ := auth.GetUserFromContext(c)
+
+	attachment, data, err := h.DB.GetAttachmentContent(c.Param("id"), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "attachment not found"})
+		return
+	}
+
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename})
+	if disposition == "" {
+		disposition = "attachment"
+	}
+	c.Header("Content-Disposition", disposition)
+	c.Header("Content-Length", strconv.Itoa(len(data)))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Cache-Control", "private, max-age=0")
+	c.Data(http.StatusOK, attachment.ContentType, data)
+}
@@ -3,8 +3,8 @@ package auth
 
 import (
 	"context"
-	"errors"
 
+	"github.com/Zayan-Mohamed/do-task-backend/internal/apperr"
 	"github.com/gin-gonic/gin"
 )
 
@@ -43,7 +43,7 @@ func RequireAuthentication(ctx context.Context) (*UserInfo, error) {
 	}
 
 	if !userInfo.Authenticated {
-		return nil, errors.New("authentication required")
+		return nil, apperr.Unauthenticated("authentication required")
 	}
 
 	return userInfo, nil
@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxPasswordLength is what bcrypt reads of a password, in bytes
+const MaxPasswordLength = 72
+
+// PasswordPolicy decides which new passwords are accepted
+type PasswordPolicy struct {
+	MinLength int
+	// RequireMixed asks for letters mixed with digits or symbols
+	RequireMixed bool
+	// breached holds the sorted SHA-1 digests of known breached passwords
+	breached [][sha1.Size]byte
+}
+
+// DefaultPasswordPolicy is used when nothing is configured
+func DefaultPasswordPolicy() *PasswordPolicy {
+	return &PasswordPolicy{MinLength: 8, RequireMixed: true}
+}
+
+// PasswordPolicyFromEnv returns the default policy adjusted by
+// PASSWORD_MIN_LENGTH and PASSWORD_REQUIRE_MIXED, with the breached passwords
+// listed in BREACHED_PASSWORDS_FILE when that is set
+func PasswordPolicyFromEnv() (*PasswordPolicy, error) {
+	policy := DefaultPasswordPolicy()
+
+	if value := os.Getenv("PASSWORD_MIN_LENGTH"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > MaxPasswordLength {
+			return nil, fmt.Errorf("PASSWORD_MIN_LENGTH must be between 1 and %d", MaxPasswordLength)
+		}
+		policy.MinLength = n
+	}
+
+	if value := os.Getenv("PASSWORD_REQUIRE_MIXED"); value != "" {
+		mixed, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("PASSWORD_REQUIRE_MIXED must be true or false")
+		}
+		policy.RequireMixed = mixed
+	}
+
+	if path := os.Getenv("BREACHED_PASSWORDS_FILE"); path != "" {
+		if err := policy.LoadBreached(path); err != nil {
+			return nil, err
+		}
+	}
+
+	return policy, nil
+}
+
+// LoadBreached reads a list of breached passwords, one SHA-1 hex digest per
+// line. A ":count" suffix, as in the Pwned Passwords downloads, is ignored, as
+// are blank lines and lines starting with #.
+func (p *PasswordPolicy) LoadBreached(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open breached password list: %w", err)
+	}
+	defer file.Close()
+
+	var digests [][sha1.Size]byte
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		text, _, _ = strings.Cut(text, ":")
+
+		var digest [sha1.Size]byte
+		if n, err := hex.Decode(digest[:], []byte(text)); err != nil || n != sha1.Size {
+			return fmt.Errorf("breached password list line %d: not a SHA-1 hex digest", line)
+		}
+		digests = append(digests, digest)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read breached password list: %w", err)
+	}
+
+	sort.Slice(digests, func(i, j int) bool {
+		return bytes.Compare(digests[i][:], digests[j][:]) < 0
+	})
+	p.breached = digests
+	return nil
+}
+
+// Breached reports whether password is on the breached password list
+func (p *PasswordPolicy) Breached(password string) bool {
+	digest := sha1.Sum([]byte(password))
+	i := sort.Search(len(p.breached), func(i int) bool {
+		return bytes.Compare(p.breached[i][:], digest[:]) >= 0
+	})
+	return i < len(p.breached) && p.breached[i] == digest
+}
+
+// Check returns why password is not accepted, or nil if it is
+func (p *PasswordPolicy) Check(password string) error {
+	if utf8.RuneCountInString(password) < p.MinLength {
+		return fmt.Errorf("password must be at least %d characters", p.MinLength)
+	}
+	if len(password) > MaxPasswordLength {
+		return fmt.Errorf("password cannot be longer than %d bytes", MaxPasswordLength)
+	}
+
+	if p.RequireMixed {
+		var letters, others bool
+		for _, r := range password {
+			if unicode.IsLetter(r) {
+				letters = true
+			} else {
+				others = true
+			}
+		}
+		if !letters || !others {
+			return errors.New("password must mix letters with digits or symbols")
+		}
+	}
+
+	if p.Breached(password) {
+		return errors.New("password has appeared in a data breach; choose another one")
+	}
+	return nil
+}
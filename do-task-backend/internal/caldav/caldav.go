@@ -0,0 +1,599 @@
+// Package caldav exposes a user's categories as CalDAV (RFC 4791) task
+// calendars so apps like Apple Reminders and Thunderbird can sync them
+//
+// Layout:
+//
+//	/caldav/                     principal and calendar home
+//	/caldav/<categoryID>/        one VTODO calendar per category
+//	/caldav/<categoryID>/<name>  one task; <id>.ics unless a client picked the name
+package caldav
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/auth"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/database"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/export"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/ical"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Prefix is the path the CalDAV tree is mounted at
+const Prefix = "/caldav"
+
+// methods lists the HTTP methods the CalDAV tree answers to
+var methods = []string{"OPTIONS", "GET", "HEAD", "PUT", "DELETE", "PROPFIND", "REPORT"}
+
+// Handler serves the CalDAV tree
+type Handler struct {
+	DB *database.DB
+}
+
+// Register mounts the CalDAV routes on r. Clients sign in with HTTP Basic auth
+// (account email and an app password) or send the app password as a Bearer token.
+func (h *Handler) Register(r gin.IRouter) {
+	for _, method := range methods {
+		r.Handle(method, Prefix+"/*path", h.serve)
+	}
+
+	// RFC 6764 service discovery
+	for _, method := range []string{"GET", "HEAD", "PROPFIND"} {
+		r.Handle(method, "/.well-known/caldav", func(c *gin.Context) {
+			c.Redirect(http.StatusMovedPermanently, Prefix+"/")
+		})
+	}
+}
+
+// resourceKind identifies what a CalDAV path points at
+type resourceKind int
+
+const (
+	kindHome resourceKind = iota
+	kindCalendar
+	kindTask
+	kindUnknown
+)
+
+// resource is a parsed CalDAV path
+type resource struct {
+	kind       resourceKind
+	categoryID string
+	name       string
+}
+
+func parsePath(path string) resource {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return resource{kind: kindHome}
+	}
+
+	parts := strings.Split(path, "/")
+	switch {
+	case len(parts) == 1:
+		return resource{kind: kindCalendar, categoryID: parts[0]}
+	case len(parts) == 2 && strings.HasSuffix(parts[1], ".ics"):
+		return resource{kind: kindTask, categoryID: parts[0], name: parts[1]}
+	default:
+		return resource{kind: kindUnknown}
+	}
+}
+
+func homeHref() string {
+	return Prefix + "/"
+}
+
+func calendarHref(categoryID string) string {
+	return Prefix + "/" + url.PathEscape(categoryID) + "/"
+}
+
+func taskHref(task models.Task) string {
+	return calendarHref(task.CategoryID) + url.PathEscape(resourceName(task))
+}
+
+// resourceName is the last path segment of a task
+func resourceName(task models.Task) string {
+	if task.CalDAVName != "" {
+		return task.CalDAVName
+	}
+	return task.ID + ".ics"
+}
+
+// taskETag maps a task's updated_at onto an entity tag
+func taskETag(task models.Task) string {
+	return `"` + strconv.FormatInt(task.UpdatedAt.UnixMicro(), 10) + `"`
+}
+
+func (h *Handler) serve(c *gin.Context) {
+	if c.Request.Method == http.MethodOptions {
+		c.Header("DAV", "1, 3, calendar-access")
+		c.Header("Allow", strings.Join(methods, ", "))
+		c.Status(http.StatusOK)
+		return
+	}
+
+	user, ok := h.authenticate(c)
+	if !ok {
+		c.Header("WWW-Authenticate", `Basic realm="DoTask", charset="UTF-8"`)
+		c.String(http.StatusUnauthorized, "authentication required")
+		return
+	}
+
+	res := parsePath(c.Param("path"))
+	if res.kind == kindUnknown {
+		c.String(http.StatusNotFound, "not found")
+		return
+	}
+
+	switch c.Request.Method {
+	case "PROPFIND":
+		h.propfind(c, user, res)
+	case "REPORT":
+		h.report(c, user, res)
+	case http.MethodGet, http.MethodHead:
+		h.get(c, user, res)
+	case http.MethodPut:
+		h.put(c, user, res)
+	case http.MethodDelete:
+		h.delete(c, user, res)
+	default:
+		c.Status(http.StatusMethodNotAllowed)
+	}
+}
+
+// authenticate checks the app password sent with the request
+func (h *Handler) authenticate(c *gin.Context) (models.User, bool) {
+	username, secret, ok := c.Request.BasicAuth()
+	if !ok {
+		header := c.GetHeader("Authorization")
+		if !strings.HasPrefix(header, "Bearer ") {
+			return models.User{}, false
+		}
+		secret = strings.TrimPrefix(header, "Bearer ")
+	}
+	if secret == "" {
+		return models.User{}, false
+	}
+
+	user, err := h.DB.GetUserByAppPassword(auth.HashSecretToken(secret))
+	if err != nil {
+		return models.User{}, false
+	}
+	if username != "" && !strings.EqualFold(username, user.Email) {
+		return models.User{}, false
+	}
+
+	return user, true
+}
+
+// propfind lists properties of a resource and, unless Depth is 0, its members
+func (h *Handler) propfind(c *gin.Context, user models.User, res resource) {
+	req, err := parseDAVRequest(c.Request.Body)
+	if err != nil {
+		c.String(http.StatusBadRequest, "invalid PROPFIND body")
+		return
+	}
+	withMembers := c.GetHeader("Depth") != "0"
+
+	var responses []davResponse
+	switch res.kind {
+	case kindHome:
+		responses = append(responses, newResponse(homeHref(), homeProps(user), req))
+		if withMembers {
+			categories, err := h.DB.GetAllCategories(user.ID)
+			if err != nil {
+				h.serverError(c, err)
+				return
+			}
+			paths := export.CategoryPaths(categories)
+			for _, category := range categories {
+				props, err := h.calendarProps(user, category, paths[category.ID])
+				if err != nil {
+					h.serverError(c, err)
+					return
+				}
+				responses = append(responses, newResponse(calendarHref(category.ID), props, req))
+			}
+		}
+
+	case kindCalendar:
+		category, err := h.DB.GetCategory(res.categoryID, user.ID)
+		if err != nil {
+			c.String(http.StatusNotFound, "calendar not found")
+			return
+		}
+		path, err := h.DB.GetCategoryPath(category.ID, user.ID)
+		if err != nil {
+			h.serverError(c, err)
+			return
+		}
+		props, err := h.calendarProps(user, category, strings.Join(path, models.CategoryPathSeparator))
+		if err != nil {
+			h.serverError(c, err)
+			return
+		}
+		responses = append(responses, newResponse(calendarHref(category.ID), props, req))
+
+		if withMembers {
+			tasks, err := h.DB.GetTasks(user.ID, &models.TaskFilter{CategoryID: &category.ID}, nil)
+			if err != nil {
+				h.serverError(c, err)
+				return
+			}
+			for _, task := range tasks {
+				responses = append(responses, newResponse(taskHref(task), taskProps(task), req))
+			}
+		}
+
+	case kindTask:
+		task, ok := h.lookupTask(user, res)
+		if !ok {
+			c.String(http.StatusNotFound, "task not found")
+			return
+		}
+		responses = append(responses, newResponse(taskHref(task), taskProps(task), req))
+	}
+
+	writeMultistatus(c, responses)
+}
+
+// report answers calendar-query and calendar-multiget reports. Query filters
+// are not evaluated: every task of the calendar matches, and clients narrow
+// the superset down themselves.
+func (h *Handler) report(c *gin.Context, user models.User, res resource) {
+	req, err := parseDAVRequest(c.Request.Body)
+	if err != nil {
+		c.String(http.StatusBadRequest, "invalid REPORT body")
+		return
+	}
+	if res.kind == kindHome {
+		c.String(http.StatusForbidden, "reports are only supported on calendars")
+		return
+	}
+
+	var responses []davResponse
+	switch req.Root {
+	case elemCalendarQuery:
+		var tasks []models.Task
+		if res.kind == kindTask {
+			task, ok := h.lookupTask(user, res)
+			if !ok {
+				c.String(http.StatusNotFound, "task not found")
+				return
+			}
+			tasks = []models.Task{task}
+		} else {
+			tasks, err = h.DB.GetTasks(user.ID, &models.TaskFilter{CategoryID: &res.categoryID}, nil)
+			if err != nil {
+				h.serverError(c, err)
+				return
+			}
+		}
+		for _, task := range tasks {
+			responses = append(responses, newResponse(taskHref(task), taskProps(task), req))
+		}
+
+	case elemCalendarMultiget:
+		for _, href := range req.Hrefs {
+			target := resource{kind: kindUnknown}
+			if u, err := url.Parse(href); err == nil && strings.HasPrefix(u.Path, Prefix+"/") {
+				target = parsePath(strings.TrimPrefix(u.Path, Prefix))
+			}
+
+			task, ok := h.lookupTask(user, target)
+			if !ok {
+				responses = append(responses, davResponse{href: href, status: http.StatusNotFound})
+				continue
+			}
+			responses = append(responses, newResponse(href, taskProps(task), req))
+		}
+
+	default:
+		c.String(http.StatusForbidden, "unsupported report")
+		return
+	}
+
+	writeMultistatus(c, responses)
+}
+
+// get serves a single task, or a whole calendar, as iCalendar data
+func (h *Handler) get(c *gin.Context, user models.User, res resource) {
+	switch res.kind {
+	case kindTask:
+		task, ok := h.lookupTask(user, res)
+		if !ok {
+			c.String(http.StatusNotFound, "task not found")
+			return
+		}
+		c.Header("ETag", taskETag(task))
+		c.Header("Last-Modified", task.UpdatedAt.UTC().Format(http.TimeFormat))
+		c.Data(http.StatusOK, "text/calendar; charset=utf-8", []byte(renderTask(task)))
+
+	case kindCalendar:
+		category, err := h.DB.GetCategory(res.categoryID, user.ID)
+		if err != nil {
+			c.String(http.StatusNotFound, "calendar not found")
+			return
+		}
+		c.Header("Content-Type", "text/calendar; charset=utf-8")
+		c.Status(http.StatusOK)
+
+		w := ical.NewWriter(c.Writer)
+		w.BeginCalendar(category.Name)
+		err = h.DB.StreamTasks(user.ID, &models.TaskFilter{CategoryID: &category.ID}, func(task models.Task) error {
+			w.Todo(task)
+			return w.Err()
+		})
+		if err != nil {
+			log.Printf("caldav: calendar download aborted: %v", err)
+			return
+		}
+		w.EndCalendar()
+
+	default:
+		c.Status(http.StatusMethodNotAllowed)
+	}
+}
+
+// put creates or replaces a task from a VTODO, going through the same
+// CreateTask and UpdateTask paths as the GraphQL API
+func (h *Handler) put(c *gin.Context, user models.User, res resource) {
+	if res.kind != kindTask {
+		c.String(http.StatusMethodNotAllowed, "only tasks can be written")
+		return
+	}
+	if _, err := h.DB.GetCategory(res.categoryID, user.ID); err != nil {
+		c.String(http.StatusConflict, "calendar not found")
+		return
+	}
+
+	todo, err := ical.ParseTodo(io.LimitReader(c.Request.Body, maxRequestBody))
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	if todo.Summary == "" {
+		c.String(http.StatusBadRequest, "SUMMARY is required")
+		return
+	}
+
+	existing, exists := h.lookupTaskByName(user, res.name)
+	if preconditionFailed(c.Request, existing, exists) {
+		c.Status(http.StatusPreconditionFailed)
+		return
+	}
+
+	status := taskStatus(todo.Status)
+	priority := taskPriority(todo.Priority)
+	// Both dates are optional; without DUE the task is all-day if DTSTART is
+	var dueDate, startDate string
+	allDay := todo.StartAllDay
+	if todo.Due != nil {
+		dueDate = taskDate(*todo.Due, todo.DueAllDay)
+		allDay = todo.DueAllDay
+	}
+	if todo.Start != nil {
+		startDate = taskDate(*todo.Start, todo.StartAllDay)
+	}
+	tags := todo.Categories
+	if tags == nil {
+		tags = []string{}
+	}
+
+	var task models.Task
+	code := http.StatusNoContent
+	if exists {
+		task, err = h.DB.UpdateTask(existing.ID, models.UpdateTaskInput{
+			Title:       &todo.Summary,
+			Description: &todo.Description,
+			Status:      &status,
+			Priority:    &priority,
+			DueDate:     &dueDate,
+			AllDay:      &allDay,
+			StartDate:   &startDate,
+			CategoryID:  &res.categoryID,
+			Tags:        tags,
+		}, user.ID)
+	} else {
+		code = http.StatusCreated
+		task, err = h.DB.CreateTask(models.CreateTaskInput{
+			Title:       todo.Summary,
+			Description: todo.Description,
+			Status:      status,
+			Priority:    priority,
+			DueDate:     optionalString(dueDate),
+			AllDay:      &allDay,
+			StartDate:   optionalString(startDate),
+			CategoryID:  res.categoryID,
+			UserID:      user.ID,
+			Tags:        tags,
+			CalDAVName:  res.name,
+			ICalUID:     todo.UID,
+			CompletedAt: todo.Completed,
+		})
+	}
+	if err != nil {
+		h.serverError(c, err)
+		return
+	}
+
+	c.Header("ETag", taskETag(task))
+	c.Status(code)
+}
+
+// taskDate formats a parsed iCalendar date for the task inputs: a calendar
+// day for DATE values and an RFC 3339 timestamp otherwise
+func taskDate(t time.Time, allDay bool) string {
+	if allDay {
+		return t.Format("2006-01-02")
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// optionalString returns nil for an empty string
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// delete removes a task. Calendars map to categories and cannot be deleted here.
+func (h *Handler) delete(c *gin.Context, user models.User, res resource) {
+	if res.kind != kindTask {
+		c.String(http.StatusForbidden, "calendars cannot be deleted over CalDAV")
+		return
+	}
+
+	task, ok := h.lookupTask(user, res)
+	if !ok {
+		c.String(http.StatusNotFound, "task not found")
+		return
+	}
+	if preconditionFailed(c.Request, task, true) {
+		c.Status(http.StatusPreconditionFailed)
+		return
+	}
+
+	if err := h.DB.DeleteTask(task.ID, user.ID); err != nil {
+		h.serverError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// lookupTask finds the task at res, which must live in res's calendar
+func (h *Handler) lookupTask(user models.User, res resource) (models.Task, bool) {
+	if res.kind != kindTask {
+		return models.Task{}, false
+	}
+	task, ok := h.lookupTaskByName(user, res.name)
+	if !ok || task.CategoryID != res.categoryID {
+		return models.Task{}, false
+	}
+	return task, true
+}
+
+func (h *Handler) lookupTaskByName(user models.User, name string) (models.Task, bool) {
+	task, err := h.DB.GetTaskByCalDAVName(name, user.ID)
+	return task, err == nil
+}
+
+func (h *Handler) serverError(c *gin.Context, err error) {
+	log.Printf("caldav: %s %s: %v", c.Request.Method, c.Request.URL.Path, err)
+	c.String(http.StatusInternalServerError, "internal server error")
+}
+
+// preconditionFailed evaluates If-Match and If-None-Match against a task
+func preconditionFailed(r *http.Request, task models.Task, exists bool) bool {
+	if r.Header.Get("If-None-Match") == "*" && exists {
+		return true
+	}
+
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		return false
+	}
+	if !exists {
+		return true
+	}
+	if ifMatch == "*" {
+		return false
+	}
+
+	current := taskETag(task)
+	for _, candidate := range strings.Split(ifMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == current {
+			return false
+		}
+	}
+	return true
+}
+
+func homeProps(user models.User) propSet {
+	return propSet{
+		propResourceType:           "<d:collection/><d:principal/>",
+		propDisplayName:            escape(user.Name),
+		propCurrentUserPrincipal:   hrefElement(homeHref()),
+		propPrincipalURL:           hrefElement(homeHref()),
+		propCalendarHomeSet:        hrefElement(homeHref()),
+		propCalendarUserAddressSet: hrefElement("mailto:" + user.Email),
+	}
+}
+
+func (h *Handler) calendarProps(user models.User, category models.Category, path string) (propSet, error) {
+	count, lastModified, err := h.DB.GetTaskListVersion(user.ID, &models.TaskFilter{CategoryID: &category.ID})
+	if err != nil {
+		return nil, err
+	}
+	// The ctag changes whenever a task in the calendar or the calendar's name does
+	ctag := fmt.Sprintf("%d-%d", count, category.UpdatedAt.UnixMicro())
+	if lastModified != nil {
+		ctag += fmt.Sprintf("-%d", lastModified.UnixMicro())
+	}
+
+	return propSet{
+		propResourceType:        "<d:collection/><c:calendar/>",
+		propDisplayName:         escape(path),
+		propOwner:               hrefElement(homeHref()),
+		propSupportedComponents: `<c:comp name="VTODO"/>`,
+		propGetCTag:             escape(ctag),
+		propCurrentUserPrivileges: "<d:privilege><d:read/></d:privilege><d:privilege><d:write/></d:privilege>" +
+			"<d:privilege><d:write-content/></d:privilege><d:privilege><d:bind/></d:privilege>" +
+			"<d:privilege><d:unbind/></d:privilege>",
+		propSupportedReportSet: "<d:supported-report><d:report><c:calendar-query/></d:report></d:supported-report>" +
+			"<d:supported-report><d:report><c:calendar-multiget/></d:report></d:supported-report>",
+	}, nil
+}
+
+func taskProps(task models.Task) propSet {
+	return propSet{
+		propResourceType:    "",
+		propGetETag:         escape(taskETag(task)),
+		propGetContentType:  "text/calendar; charset=utf-8; component=VTODO",
+		propGetLastModified: task.UpdatedAt.UTC().Format(http.TimeFormat),
+		propCalendarData:    escape(renderTask(task)),
+	}
+}
+
+// renderTask returns a VCALENDAR holding just the task
+func renderTask(task models.Task) string {
+	var b strings.Builder
+	w := ical.NewWriter(&b)
+	w.BeginCalendar("")
+	w.Todo(task)
+	w.EndCalendar()
+	return b.String()
+}
+
+// taskStatus maps a VTODO STATUS onto a task status
+func taskStatus(status string) models.TaskStatus {
+	switch status {
+	case "COMPLETED":
+		return models.TaskStatusCompleted
+	case "IN-PROCESS":
+		return models.TaskStatusInProgress
+	default:
+		return models.TaskStatusTodo
+	}
+}
+
+// taskPriority maps the RFC 5545 priority scale onto task priorities; 0
+// means undefined
+func taskPriority(priority int) models.TaskPriority {
+	switch {
+	case priority >= 1 && priority <= 4:
+		return models.TaskPriorityHigh
+	case priority >= 6:
+		return models.TaskPriorityLow
+	default:
+		return models.TaskPriorityMedium
+	}
+}
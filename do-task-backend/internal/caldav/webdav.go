@@ -0,0 +1,207 @@
+package caldav
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// XML namespaces used by CalDAV clients
+const (
+	nsDAV          = "DAV:"
+	nsCalDAV       = "urn:ietf:params:xml:ns:caldav"
+	nsCalendarSrv  = "http://calendarserver.org/ns/"
+	maxRequestBody = 1 << 20
+)
+
+// prefixes maps the namespaces we emit to their prefixes in responses
+var prefixes = map[string]string{
+	nsDAV:         "d",
+	nsCalDAV:      "c",
+	nsCalendarSrv: "cs",
+}
+
+// Property names
+var (
+	propResourceType           = xml.Name{Space: nsDAV, Local: "resourcetype"}
+	propDisplayName            = xml.Name{Space: nsDAV, Local: "displayname"}
+	propCurrentUserPrincipal   = xml.Name{Space: nsDAV, Local: "current-user-principal"}
+	propPrincipalURL           = xml.Name{Space: nsDAV, Local: "principal-URL"}
+	propOwner                  = xml.Name{Space: nsDAV, Local: "owner"}
+	propCurrentUserPrivileges  = xml.Name{Space: nsDAV, Local: "current-user-privilege-set"}
+	propSupportedReportSet     = xml.Name{Space: nsDAV, Local: "supported-report-set"}
+	propGetETag                = xml.Name{Space: nsDAV, Local: "getetag"}
+	propGetContentType         = xml.Name{Space: nsDAV, Local: "getcontenttype"}
+	propGetLastModified        = xml.Name{Space: nsDAV, Local: "getlastmodified"}
+	propCalendarHomeSet        = xml.Name{Space: nsCalDAV, Local: "calendar-home-set"}
+	propCalendarUserAddressSet = xml.Name{Space: nsCalDAV, Local: "calendar-user-address-set"}
+	propSupportedComponents    = xml.Name{Space: nsCalDAV, Local: "supported-calendar-component-set"}
+	propCalendarData           = xml.Name{Space: nsCalDAV, Local: "calendar-data"}
+	propGetCTag                = xml.Name{Space: nsCalendarSrv, Local: "getctag"}
+)
+
+// Request element names
+var (
+	elemProp             = xml.Name{Space: nsDAV, Local: "prop"}
+	elemAllProp          = xml.Name{Space: nsDAV, Local: "allprop"}
+	elemPropName         = xml.Name{Space: nsDAV, Local: "propname"}
+	elemHref             = xml.Name{Space: nsDAV, Local: "href"}
+	elemCalendarQuery    = xml.Name{Space: nsCalDAV, Local: "calendar-query"}
+	elemCalendarMultiget = xml.Name{Space: nsCalDAV, Local: "calendar-multiget"}
+)
+
+// davRequest is the part of a PROPFIND or REPORT body we act on
+type davRequest struct {
+	Root    xml.Name
+	AllProp bool
+	Props   []xml.Name
+	Hrefs   []string
+}
+
+// parseDAVRequest reads a PROPFIND or REPORT body. An empty body is an allprop request.
+func parseDAVRequest(r io.Reader) (davRequest, error) {
+	var req davRequest
+	decoder := xml.NewDecoder(io.LimitReader(r, maxRequestBody))
+
+	var stack []xml.Name
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return req, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if len(stack) == 0 {
+				req.Root = t.Name
+			} else if stack[len(stack)-1] == elemProp {
+				req.Props = append(req.Props, t.Name)
+			}
+			if t.Name == elemAllProp || t.Name == elemPropName {
+				req.AllProp = true
+			}
+			stack = append(stack, t.Name)
+		case xml.EndElement:
+			if len(stack) == 0 {
+				return req, errors.New("unbalanced XML")
+			}
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 && stack[len(stack)-1] == elemHref {
+				if href := strings.TrimSpace(string(t)); href != "" {
+					req.Hrefs = append(req.Hrefs, href)
+				}
+			}
+		}
+	}
+
+	if req.Root.Local == "" {
+		req.AllProp = true
+	}
+	return req, nil
+}
+
+// propSet holds the properties of one resource as raw inner XML
+type propSet map[xml.Name]string
+
+// davResponse is one <response> of a multistatus
+type davResponse struct {
+	href    string
+	status  int // set for resources that could not be returned at all
+	found   propSet
+	missing []xml.Name
+}
+
+// newResponse selects the requested properties from props. calendar-data is
+// expensive and only returned when asked for by name.
+func newResponse(href string, props propSet, req davRequest) davResponse {
+	response := davResponse{href: href, found: propSet{}}
+	if req.AllProp {
+		for name, value := range props {
+			if name != propCalendarData {
+				response.found[name] = value
+			}
+		}
+		return response
+	}
+
+	for _, name := range req.Props {
+		if value, ok := props[name]; ok {
+			response.found[name] = value
+		} else {
+			response.missing = append(response.missing, name)
+		}
+	}
+	return response
+}
+
+// writeMultistatus sends a 207 Multi-Status response
+func writeMultistatus(c *gin.Context, responses []davResponse) {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="utf-8"?>` + "\n")
+	b.WriteString(`<d:multistatus xmlns:d="DAV:" xmlns:c="` + nsCalDAV + `" xmlns:cs="` + nsCalendarSrv + `">`)
+	for _, response := range responses {
+		b.WriteString("<d:response><d:href>" + escape(response.href) + "</d:href>")
+		if response.status != 0 {
+			b.WriteString("<d:status>" + statusLine(response.status) + "</d:status>")
+		}
+		if len(response.found) > 0 {
+			b.WriteString("<d:propstat><d:prop>")
+			for name, value := range response.found {
+				b.WriteString(element(name, value))
+			}
+			b.WriteString("</d:prop><d:status>" + statusLine(http.StatusOK) + "</d:status></d:propstat>")
+		}
+		if len(response.missing) > 0 {
+			b.WriteString("<d:propstat><d:prop>")
+			for _, name := range response.missing {
+				b.WriteString(element(name, ""))
+			}
+			b.WriteString("</d:prop><d:status>" + statusLine(http.StatusNotFound) + "</d:status></d:propstat>")
+		}
+		b.WriteString("</d:response>")
+	}
+	b.WriteString("</d:multistatus>\n")
+
+	c.Data(http.StatusMultiStatus, "application/xml; charset=utf-8", []byte(b.String()))
+}
+
+// element renders <name>inner</name>, declaring unknown namespaces inline
+func element(name xml.Name, inner string) string {
+	tag := name.Local
+	attrs := ""
+	if prefix, ok := prefixes[name.Space]; ok {
+		tag = prefix + ":" + name.Local
+	} else if name.Space != "" {
+		tag = "x:" + name.Local
+		attrs = ` xmlns:x="` + escape(name.Space) + `"`
+	}
+
+	if inner == "" {
+		return "<" + tag + attrs + "/>"
+	}
+	return "<" + tag + attrs + ">" + inner + "</" + tag + ">"
+}
+
+func hrefElement(href string) string {
+	return "<d:href>" + escape(href) + "</d:href>"
+}
+
+func statusLine(code int) string {
+	return fmt.Sprintf("HTTP/1.1 %d %s", code, http.StatusText(code))
+}
+
+// escape escapes text for use in XML character data and attributes
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,141 @@
+// Package calendar serves a user's task due dates as an iCalendar feed that
+// calendar apps can subscribe to
+package calendar
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/database"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/export"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/ical"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// feedName is shown by calendar apps as the subscribed calendar's name
+const feedName = "DoTask"
+
+// FeedPath returns the path of the feed for token, relative to the server root
+func FeedPath(token string) string {
+	return "/calendar/" + token + ".ics"
+}
+
+// Handler serves the calendar feed
+type Handler struct {
+	DB *database.DB
+}
+
+// Register mounts the calendar routes on r. The feed is authenticated by the
+// secret token in its URL because calendar apps cannot send our cookies.
+func (h *Handler) Register(r gin.IRouter) {
+	r.GET("/calendar/:file", h.Feed)
+	r.HEAD("/calendar/:file", h.Feed)
+}
+
+// Feed renders the tasks of the token's owner as VEVENT entries, or VTODO
+// entries with ?type=todo. The export filters (status, categoryId, ...) apply.
+func (h *Handler) Feed(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("file"), ".ics")
+
+	userID, feed, err := h.DB.GetCalendarFeedByToken(token)
+	if err != nil {
+		c.String(http.StatusNotFound, "calendar not found")
+		return
+	}
+
+	todos := false
+	switch c.DefaultQuery("type", "event") {
+	case "event":
+	case "todo":
+		todos = true
+	default:
+		c.String(http.StatusBadRequest, "type must be event or todo")
+		return
+	}
+
+	filter, err := export.ParseTaskFilter(c)
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	count, lastModified, err := h.DB.GetTaskListVersion(userID, filter)
+	if err != nil {
+		log.Printf("calendar: failed to get feed version: %v", err)
+		c.String(http.StatusInternalServerError, "failed to render calendar")
+		return
+	}
+
+	modified := feed.CreatedAt
+	if lastModified != nil && lastModified.After(modified) {
+		modified = *lastModified
+	}
+	// HTTP dates have second precision
+	modified = modified.UTC().Truncate(time.Second)
+	etag := feedETag(c.Request.URL.RawQuery, count, modified)
+
+	c.Header("ETag", etag)
+	c.Header("Last-Modified", modified.Format(http.TimeFormat))
+	c.Header("Cache-Control", "private, max-age=300")
+
+	if notModified(c.Request, etag, modified) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Header("Content-Type", "text/calendar; charset=utf-8")
+	c.Header("Content-Disposition", `inline; filename="dotask.ics"`)
+	c.Status(http.StatusOK)
+	if c.Request.Method == http.MethodHead {
+		return
+	}
+
+	w := ical.NewWriter(c.Writer)
+	w.BeginCalendar(feedName)
+	err = h.DB.StreamTasks(userID, filter, func(task models.Task) error {
+		if todos {
+			w.Todo(task)
+		} else {
+			w.Event(task)
+		}
+		return w.Err()
+	})
+	if err != nil {
+		log.Printf("calendar: feed aborted: %v", err)
+		return
+	}
+	w.EndCalendar()
+}
+
+// feedETag derives a validator from everything that shapes the response
+func feedETag(query string, count int, modified time.Time) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%d\x00%d", query, count, modified.UnixNano())))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// notModified evaluates the conditional request headers. If-None-Match takes
+// precedence over If-Modified-Since, as required by RFC 9110.
+func notModified(r *http.Request, etag string, modified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == etag || candidate == "*" {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+		since, err := http.ParseTime(ims)
+		return err == nil && !modified.After(since)
+	}
+
+	return false
+}
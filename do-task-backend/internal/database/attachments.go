@@ -0,0 +1,152 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/apperr"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+)
+
+// attachmentColumns is the select list used for attachment metadata queries
+const attachmentColumns = `a.id, a.task_id, a.filename, a.content_type, a.size, a.created_at`
+
+// scanAttachment scans a row selected with attachmentColumns
+func scanAttachment(row rowScanner) (models.Attachment, error) {
+	var attachment models.Attachment
+	err := row.Scan(
+		&attachment.ID,
+		&attachment.TaskID,
+		&attachment.Filename,
+		&attachment.ContentType,
+		&attachment.Size,
+		&attachment.CreatedAt,
+	)
+	return attachment, err
+}
+
+// CreateTaskWithAttachments creates a task and stores its attachments in one
+// transaction
+func (db *DB) CreateTaskWithAttachments(input models.CreateTaskInput, attachments []models.NewAttachment) (models.Task, error) {
+	var task models.Task
+	err := db.withTx(func(tx *sql.Tx) error {
+		var err error
+		task, err = createTask(tx, input)
+		if err != nil {
+			return err
+		}
+
+		for _, attachment := range attachments {
+			_, err := tx.Exec(`
+				INSERT INTO task_attachments (task_id, user_id, filename, content_type, size, data)
+				VALUES ($1, $2, $3, $4, $5, $6)`,
+				task.ID, input.UserID, attachment.Filename, attachment.ContentType, len(attachment.Data), attachment.Data)
+			if err != nil {
+				return fmt.Errorf("failed to store attachment %q: %w", attachment.Filename, err)
+			}
+		}
+		return nil
+	})
+
+	return task, err
+}
+
+// GetTaskAttachments retrieves the attachment metadata of a task
+func (db *DB) GetTaskAttachments(taskID string, userID string) ([]models.Attachment, error) {
+	rows, err := db.Query(`SELECT `+attachmentColumns+` FROM task_attachments a
+		WHERE a.task_id = $1 AND a.user_id = $2
+		ORDER BY a.created_at, a.filename`, taskID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query attachments: %w", err)
+	}
+	defer rows.Close()
+
+	var attachments []models.Attachment
+	for rows.Next() {
+		attachment, err := scanAttachment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan attachment: %w", err)
+		}
+		attachments = append(attachments, attachment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate attachments: %w", err)
+	}
+
+	return attachments, nil
+}
+
+// GetAttachmentContent retrieves an attachment and its content for a specific user
+func (db *DB) GetAttachmentContent(id string, userID string) (models.Attachment, []byte, error) {
+	var data []byte
+	var attachment models.Attachment
+	err := db.QueryRow(`SELECT `+attachmentColumns+`, a.data FROM task_attachments a
+		WHERE a.id = $1 AND a.user_id = $2`, id, userID).Scan(
+		&attachment.ID,
+		&attachment.TaskID,
+		&attachment.Filename,
+		&attachment.ContentType,
+		&attachment.Size,
+		&attachment.CreatedAt,
+		&data,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Attachment{}, nil, apperr.NotFound("attachment")
+		}
+		return models.Attachment{}, nil, fmt.Errorf("failed to get attachment: %w", err)
+	}
+
+	return attachment, data, nil
+}
+
+// GetInboundEmailToken retrieves the token of a user's inbound email address,
+// or nil if email-to-task is disabled for the user
+func (db *DB) GetInboundEmailToken(userID string) (*string, error) {
+	var token string
+	err := db.QueryRow(`SELECT token FROM inbound_email_addresses WHERE user_id = $1`, userID).Scan(&token)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get inbound email address: %w", err)
+	}
+
+	return &token, nil
+}
+
+// SetInboundEmailToken gives a user a new inbound email address, retiring the old one
+func (db *DB) SetInboundEmailToken(userID string, token string) error {
+	_, err := db.Exec(`
+		INSERT INTO inbound_email_addresses (user_id, token)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET token = EXCLUDED.token, created_at = NOW()`, userID, token)
+	if err != nil {
+		return fmt.Errorf("failed to set inbound email address: %w", err)
+	}
+	return nil
+}
+
+// DeleteInboundEmailToken disables email-to-task for a user
+func (db *DB) DeleteInboundEmailToken(userID string) error {
+	_, err := db.Exec(`DELETE FROM inbound_email_addresses WHERE user_id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete inbound email address: %w", err)
+	}
+	return nil
+}
+
+// GetUserIDByInboundEmailToken resolves an inbound email token to its owner
+func (db *DB) GetUserIDByInboundEmailToken(token string) (string, error) {
+	var userID string
+	err := db.QueryRow(`SELECT user_id FROM inbound_email_addresses WHERE token = $1`, token).Scan(&userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", apperr.NotFound("inbound email address")
+		}
+		return "", fmt.Errorf("failed to get inbound email address: %w", err)
+	}
+
+	return userID, nil
+}
@@ -0,0 +1,133 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/apperr"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+)
+
+// MaxBulkTasks caps how many tasks a single bulk operation may touch
+const MaxBulkTasks = 500
+
+// BulkUpdateTasks applies the same patch to every task in ids. Tasks that fail
+// are reported individually without affecting the others.
+func (db *DB) BulkUpdateTasks(ids []string, input models.UpdateTaskInput, userID string) (models.BulkTaskResult, error) {
+	if input.ExpectedVersion != nil {
+		return models.BulkTaskResult{}, apperr.Invalid("expectedVersion", "expectedVersion applies to a single task and cannot be used in bulk updates")
+	}
+	// Checked once up front so a foreign category fails the whole request
+	if input.CategoryID != nil {
+		if _, err := db.GetCategory(*input.CategoryID, userID); err != nil {
+			return models.BulkTaskResult{}, err
+		}
+	}
+
+	return db.bulkApply(ids, func(tx *sql.Tx, id string) (*models.Task, error) {
+		task, err := updateTask(tx, id, input, userID)
+		if err != nil {
+			return nil, err
+		}
+		return &task, nil
+	})
+}
+
+// BulkMoveTasks moves every task in ids into categoryID
+func (db *DB) BulkMoveTasks(ids []string, categoryID string, userID string) (models.BulkTaskResult, error) {
+	input := models.UpdateTaskInput{CategoryID: &categoryID}
+	return db.BulkUpdateTasks(ids, input, userID)
+}
+
+// BulkDeleteTasks deletes every task in ids
+func (db *DB) BulkDeleteTasks(ids []string, userID string) (models.BulkTaskResult, error) {
+	return db.bulkApply(ids, func(tx *sql.Tx, id string) (*models.Task, error) {
+		return nil, deleteTask(tx, id, userID)
+	})
+}
+
+// withSavepoint runs fn inside a savepoint so a failure only undoes fn's own
+// work. fn's error is returned as itemErr; err is set only when the
+// savepoint itself could not be managed and the transaction is unusable.
+func withSavepoint(tx *sql.Tx, fn func() error) (itemErr error, err error) {
+	if _, err := tx.Exec(`SAVEPOINT item`); err != nil {
+		return nil, fmt.Errorf("failed to create savepoint: %w", err)
+	}
+
+	if itemErr := fn(); itemErr != nil {
+		if _, err := tx.Exec(`ROLLBACK TO SAVEPOINT item`); err != nil {
+			return nil, fmt.Errorf("failed to roll back savepoint: %w", err)
+		}
+		return itemErr, nil
+	}
+
+	if _, err := tx.Exec(`RELEASE SAVEPOINT item`); err != nil {
+		return nil, fmt.Errorf("failed to release savepoint: %w", err)
+	}
+	return nil, nil
+}
+
+// bulkApply runs fn for each distinct ID inside a single transaction. Every
+// item gets its own savepoint so one failure only rolls back that item.
+func (db *DB) bulkApply(ids []string, fn func(tx *sql.Tx, id string) (*models.Task, error)) (models.BulkTaskResult, error) {
+	ids = uniqueIDs(ids)
+	if len(ids) == 0 {
+		return models.BulkTaskResult{}, apperr.Invalid("ids", "no task IDs provided")
+	}
+	if len(ids) > MaxBulkTasks {
+		return models.BulkTaskResult{}, apperr.Invalid("ids", "cannot process more than %d tasks at once", MaxBulkTasks)
+	}
+
+	var summary models.BulkTaskResult
+	err := db.withTx(func(tx *sql.Tx) error {
+		for _, id := range ids {
+			item := &models.BulkTaskItemResult{ID: id}
+
+			var task *models.Task
+			itemErr, err := withSavepoint(tx, func() error {
+				var err error
+				task, err = fn(tx, id)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+
+			if itemErr != nil {
+				message, ok := ClientMessage(itemErr)
+				if !ok {
+					log.Printf("bulk: task %s failed: %v", id, itemErr)
+				}
+				item.Error = &message
+				summary.Failed++
+			} else {
+				item.Success = true
+				item.Task = task
+				summary.Succeeded++
+			}
+			summary.Results = append(summary.Results, item)
+		}
+		return nil
+	})
+
+	if err != nil {
+		return models.BulkTaskResult{}, err
+	}
+
+	return summary, nil
+}
+
+// uniqueIDs drops duplicate and empty IDs while preserving order
+func uniqueIDs(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	var result []string
+	for _, id := range ids {
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, id)
+	}
+	return result
+}
@@ -0,0 +1,125 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/apperr"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+)
+
+// maxAppPasswordNameLength mirrors the size of the app_passwords.name column
+const maxAppPasswordNameLength = 100
+
+// CreateAppPassword stores a new app password for a specific user. Only the
+// hash of the secret is kept.
+func (db *DB) CreateAppPassword(name string, tokenHash string, userID string) (models.AppPassword, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return models.AppPassword{}, apperr.Invalid("name", "app password name cannot be empty")
+	}
+	if utf8.RuneCountInString(name) > maxAppPasswordNameLength {
+		return models.AppPassword{}, apperr.Invalid("name", "app password name cannot be longer than %d characters", maxAppPasswordNameLength)
+	}
+
+	var appPassword models.AppPassword
+	err := db.QueryRow(`
+		INSERT INTO app_passwords (user_id, name, token_hash)
+		VALUES ($1, $2, $3)
+		RETURNING id, name, created_at, last_used_at`, userID, name, tokenHash).Scan(
+		&appPassword.ID,
+		&appPassword.Name,
+		&appPassword.CreatedAt,
+		&appPassword.LastUsedAt,
+	)
+	if err != nil {
+		return models.AppPassword{}, fmt.Errorf("failed to create app password: %w", err)
+	}
+
+	return appPassword, nil
+}
+
+// GetAppPasswords retrieves the app passwords of a specific user, newest first
+func (db *DB) GetAppPasswords(userID string) ([]models.AppPassword, error) {
+	rows, err := db.Query(`
+		SELECT id, name, created_at, last_used_at FROM app_passwords
+		WHERE user_id = $1
+		ORDER BY created_at DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query app passwords: %w", err)
+	}
+	defer rows.Close()
+
+	var appPasswords []models.AppPassword
+	for rows.Next() {
+		var appPassword models.AppPassword
+		if err := rows.Scan(&appPassword.ID, &appPassword.Name, &appPassword.CreatedAt, &appPassword.LastUsedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan app password: %w", err)
+		}
+		appPasswords = append(appPasswords, appPassword)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate app passwords: %w", err)
+	}
+
+	return appPasswords, nil
+}
+
+// DeleteAppPassword revokes an app password of a specific user
+func (db *DB) DeleteAppPassword(id string, userID string) error {
+	result, err := db.Exec(`DELETE FROM app_passwords WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete app password: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return apperr.NotFound("app password")
+	}
+
+	return nil
+}
+
+// GetUserByAppPassword resolves the hash of an app password to its owner and
+// records that the password was used
+func (db *DB) GetUserByAppPassword(tokenHash string) (models.User, error) {
+	query := `
+		UPDATE app_passwords ap SET last_used_at = NOW()
+		FROM users u
+		WHERE ap.token_hash = $1 AND u.id = ap.user_id
+		RETURNING ` + userColumns
+
+	user, err := scanUser(db.QueryRow(query, tokenHash))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.User{}, apperr.Unauthenticated("invalid app password")
+		}
+		return models.User{}, fmt.Errorf("failed to check app password: %w", err)
+	}
+
+	return user, nil
+}
+
+// GetTaskByCalDAVName retrieves the task stored at a CalDAV resource name.
+// Tasks created outside CalDAV live at "<id>.ics".
+func (db *DB) GetTaskByCalDAVName(name string, userID string) (models.Task, error) {
+	query := `SELECT ` + taskColumns + ` FROM tasks t
+		WHERE t.user_id = $2 AND t.deleted_at IS NULL
+		AND (t.caldav_name = $1 OR (t.caldav_name IS NULL AND t.id::text || '.ics' = $1))`
+
+	task, err := scanTask(db.QueryRow(query, name, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Task{}, apperr.NotFound("task")
+		}
+		return models.Task{}, fmt.Errorf("failed to get task: %w", err)
+	}
+
+	return task, nil
+}
@@ -0,0 +1,91 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/apperr"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+)
+
+// GetCalendarFeed retrieves the calendar feed of a specific user, or nil if
+// the user has not enabled one
+func (db *DB) GetCalendarFeed(userID string) (*models.CalendarFeed, error) {
+	var feed models.CalendarFeed
+	err := db.QueryRow(`SELECT token, created_at FROM calendar_feeds WHERE user_id = $1`, userID).
+		Scan(&feed.Token, &feed.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get calendar feed: %w", err)
+	}
+
+	return &feed, nil
+}
+
+// SetCalendarFeedToken enables the calendar feed of a specific user with a new
+// token, invalidating any previous one
+func (db *DB) SetCalendarFeedToken(userID string, token string) (models.CalendarFeed, error) {
+	var feed models.CalendarFeed
+	err := db.QueryRow(`
+		INSERT INTO calendar_feeds (user_id, token)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET token = EXCLUDED.token, created_at = NOW()
+		RETURNING token, created_at`, userID, token).Scan(&feed.Token, &feed.CreatedAt)
+	if err != nil {
+		return models.CalendarFeed{}, fmt.Errorf("failed to set calendar feed token: %w", err)
+	}
+
+	return feed, nil
+}
+
+// DeleteCalendarFeed disables the calendar feed of a specific user
+func (db *DB) DeleteCalendarFeed(userID string) error {
+	_, err := db.Exec(`DELETE FROM calendar_feeds WHERE user_id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete calendar feed: %w", err)
+	}
+	return nil
+}
+
+// GetCalendarFeedByToken resolves a feed token to its owner
+func (db *DB) GetCalendarFeedByToken(token string) (string, models.CalendarFeed, error) {
+	var userID string
+	var feed models.CalendarFeed
+	err := db.QueryRow(`SELECT user_id, token, created_at FROM calendar_feeds WHERE token = $1`, token).
+		Scan(&userID, &feed.Token, &feed.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", models.CalendarFeed{}, apperr.NotFound("calendar feed")
+		}
+		return "", models.CalendarFeed{}, fmt.Errorf("failed to get calendar feed: %w", err)
+	}
+
+	return userID, feed, nil
+}
+
+// GetTaskListVersion returns the number of tasks matching filter and the
+// latest updated_at among them. Together they change whenever a task in the
+// list is created, edited or removed, which makes them usable as a cache
+// validator. lastModified is nil when no task matches.
+func (db *DB) GetTaskListVersion(userID string, filter *models.TaskFilter) (count int, lastModified *time.Time, err error) {
+	args := &sqlArgs{}
+	where, err := buildTaskFilter(filter, userID, args)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	query := `SELECT COUNT(*), MAX(t.updated_at) FROM tasks t WHERE ` + where
+
+	var latest sql.NullTime
+	if err := db.QueryRow(query, args.values...).Scan(&count, &latest); err != nil {
+		return 0, nil, fmt.Errorf("failed to get task list version: %w", err)
+	}
+	if latest.Valid {
+		lastModified = &latest.Time
+	}
+
+	return count, lastModified, nil
+}
@@ -3,12 +3,12 @@ package database
 
 import (
 	"database/sql"
-	"errors"
 	"fmt"
 	"os"
 	"strings"
 	"time"
 
+	"github.com/Zayan-Mohamed/do-task-backend/internal/apperr"
 	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
@@ -72,57 +72,61 @@ func (db *DB) InitSchema() error {
 	return nil
 }
 
-// CreateTask creates a new task
-func (db *DB) CreateTask(input models.CreateTaskInput) (models.Task, error) {
-	query := `
-		INSERT INTO tasks (title, description, status, priority, due_date, category_id, user_id, tags)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-		RETURNING id, title, description, status, priority, due_date, created_at, updated_at, category_id, user_id, tags`
+// querier is satisfied by both *sql.DB and *sql.Tx so helpers can run inside
+// or outside a transaction
+type querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
 
-	dueDate, err := time.Parse(time.RFC3339, input.DueDate)
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// withTx runs fn inside a transaction, committing on success and rolling back on error
+func (db *DB) withTx(fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
 	if err != nil {
-		return models.Task{}, err
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 
-	var task models.Task
-	err = db.QueryRow(query,
-		input.Title,
-		input.Description,
-		input.Status,
-		input.Priority,
-		dueDate,
-		input.CategoryID,
-		input.UserID,
-		pq.Array(input.Tags),
-	).Scan(
-		&task.ID,
-		&task.Title,
-		&task.Description,
-		&task.Status,
-		&task.Priority,
-		&task.DueDate,
-		&task.CreatedAt,
-		&task.UpdatedAt,
-		&task.CategoryID,
-		&task.UserID,
-		pq.Array(&task.Tags),
-	)
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
 
-	if err != nil {
-		return models.Task{}, fmt.Errorf("failed to create task: %w", err)
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	return task, nil
+	return nil
 }
 
-// GetTask retrieves a task by ID for a specific user
-func (db *DB) GetTask(id string, userID string) (models.Task, error) {
-	query := `
-		SELECT id, title, description, status, priority, due_date, created_at, updated_at, category_id, user_id, tags
-		FROM tasks WHERE id = $1 AND user_id = $2`
+// versionConflict is returned when an update expected a version of a record
+// that is no longer current. It carries the record as it is now, a
+// models.Task or a models.Category, so clients can merge without a refetch.
+func versionConflict(kind string, version int, current interface{}) error {
+	err := apperr.Conflict("%s was changed by someone else and is now at version %d", kind, version)
+	err.Details = map[string]interface{}{"version": version, "current": current}
+	return err
+}
 
+// taskColumns is the select list used for every task query. Tags are stored in
+// the task_tags join table and aggregated back into an array so Task.Tags keeps
+// its original shape.
+const taskColumns = `t.id, t.title, COALESCE(t.description, ''), t.status, t.priority, t.due_date,
+		t.created_at, t.updated_at, COALESCE(t.category_id::text, ''), t.user_id,
+		ARRAY(SELECT tg.name FROM task_tags tt JOIN tags tg ON tg.id = tt.tag_id
+			WHERE tt.task_id = t.id ORDER BY tt.position, tg.name),
+		t.deleted_at, COALESCE(t.caldav_name, ''), COALESCE(t.ical_uid, ''), t.completed_at,
+		t.all_day, t.start_date, ` + userTimezoneSQL + `, t.estimate_minutes, t.version`
+
+// scanTask scans a row selected with taskColumns
+func scanTask(row rowScanner) (models.Task, error) {
 	var task models.Task
-	err := db.QueryRow(query, id, userID).Scan(
+	err := row.Scan(
 		&task.ID,
 		&task.Title,
 		&task.Description,
@@ -134,25 +138,22 @@ func (db *DB) GetTask(id string, userID string) (models.Task, error) {
 		&task.CategoryID,
 		&task.UserID,
 		pq.Array(&task.Tags),
+		&task.DeletedAt,
+		&task.CalDAVName,
+		&task.ICalUID,
+		&task.CompletedAt,
+		&task.AllDay,
+		&task.StartDate,
+		&task.Timezone,
+		&task.EstimateMinutes,
+		&task.Version,
 	)
-
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return models.Task{}, errors.New("task not found")
-		}
-		return models.Task{}, fmt.Errorf("failed to get task: %w", err)
-	}
-
-	return task, nil
+	return task, err
 }
 
-// GetAllTasks retrieves all tasks for a specific user
-func (db *DB) GetAllTasks() ([]models.Task, error) {
-	query := `
-		SELECT id, title, description, status, priority, due_date, created_at, updated_at, category_id, user_id, tags
-		FROM tasks ORDER BY created_at DESC`
-
-	rows, err := db.Query(query)
+// queryTasks runs a query selecting taskColumns and collects the results
+func queryTasks(q querier, query string, args ...interface{}) ([]models.Task, error) {
+	rows, err := q.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query tasks: %w", err)
 	}
@@ -160,66 +161,221 @@ func (db *DB) GetAllTasks() ([]models.Task, error) {
 
 	var tasks []models.Task
 	for rows.Next() {
-		var task models.Task
-		err := rows.Scan(
-			&task.ID,
-			&task.Title,
-			&task.Description,
-			&task.Status,
-			&task.Priority,
-			&task.DueDate,
-			&task.CreatedAt,
-			&task.UpdatedAt,
-			&task.CategoryID,
-			&task.UserID,
-			pq.Array(&task.Tags),
-		)
+		task, err := scanTask(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan task: %w", err)
 		}
 		tasks = append(tasks, task)
 	}
 
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate tasks: %w", err)
+	}
+
 	return tasks, nil
 }
 
-func (db *DB) GetAllTasksByUser(userID string) ([]models.Task, error) {
+// getTask loads a single task owned by userID. Trashed tasks are not returned.
+func getTask(q querier, id string, userID string) (models.Task, error) {
+	query := `SELECT ` + taskColumns + ` FROM tasks t WHERE t.id = $1 AND t.user_id = $2 AND t.deleted_at IS NULL`
+
+	task, err := scanTask(q.QueryRow(query, id, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Task{}, apperr.NotFound("task")
+		}
+		return models.Task{}, fmt.Errorf("failed to get task: %w", err)
+	}
+
+	return task, nil
+}
+
+// CreateTask creates a new task
+func (db *DB) CreateTask(input models.CreateTaskInput) (models.Task, error) {
+	var task models.Task
+	err := db.withTx(func(tx *sql.Tx) error {
+		var err error
+		task, err = createTask(tx, input)
+		return err
+	})
+	return task, err
+}
+
+// ApplyDefaultCategory puts a task without a category into the user's first
+// category, the rule every task creation path follows
+func (db *DB) ApplyDefaultCategory(input *models.CreateTaskInput) error {
+	if input.CategoryID != "" {
+		return nil
+	}
+
+	categories, err := db.GetAllCategories(input.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to find categories: %w", err)
+	}
+
+	if len(categories) == 0 {
+		return apperr.Invalid("categoryId", "cannot create task without a category. Please create a category first")
+	}
+
+	// Use the first available category
+	input.CategoryID = categories[0].ID
+	return nil
+}
+
+// createTask inserts a task and its tags using q
+func createTask(q querier, input models.CreateTaskInput) (models.Task, error) {
+	// Imported tasks carry an import key; a second import of the same row is a
+	// no-op and surfaces as ErrDuplicateImport
 	query := `
-		SELECT id, title, description, status, priority, due_date, created_at, updated_at, category_id, user_id, tags
-		FROM tasks WHERE user_id = $1 ORDER BY created_at DESC`
+		INSERT INTO tasks (title, description, status, priority, due_date, category_id, user_id,
+			import_key, caldav_name, ical_uid, completed_at, all_day, due_on, start_date, start_on,
+			estimate_minutes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), NULLIF($9, ''), NULLIF($10, ''), $11,
+			$12, $13, $14, $15, $16)
+		ON CONFLICT (user_id, import_key) WHERE import_key IS NOT NULL DO NOTHING
+		RETURNING id`
+
+	dates, err := newTaskDates(q, input)
+	if err != nil {
+		return models.Task{}, err
+	}
+	if input.EstimateMinutes != nil && *input.EstimateMinutes <= 0 {
+		return models.Task{}, errInvalidEstimate
+	}
+
+	// Tasks brought in from elsewhere keep the source's completion time, or
+	// none, so old completions don't count as done today
+	var completedAt *time.Time
+	if input.Status == models.TaskStatusCompleted {
+		completedAt = input.CompletedAt
+		if completedAt == nil && input.ImportKey == "" && input.CalDAVName == "" {
+			now := time.Now()
+			completedAt = &now
+		}
+	}
+
+	var id string
+	err = q.QueryRow(query,
+		input.Title,
+		input.Description,
+		input.Status,
+		input.Priority,
+		dates.dueAt(),
+		input.CategoryID,
+		input.UserID,
+		input.ImportKey,
+		input.CalDAVName,
+		input.ICalUID,
+		completedAt,
+		dates.allDay,
+		dates.dueDay(),
+		dates.startAt(),
+		dates.startDay(),
+		input.EstimateMinutes,
+	).Scan(&id)
 
-	rows, err := db.Query(query, userID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query tasks for user: %w", err)
+		if err == sql.ErrNoRows {
+			return models.Task{}, ErrDuplicateImport
+		}
+		return models.Task{}, fmt.Errorf("failed to create task: %w", err)
 	}
-	defer rows.Close()
 
-	var tasks []models.Task
-	for rows.Next() {
-		var task models.Task
-		err := rows.Scan(
-			&task.ID,
-			&task.Title,
-			&task.Description,
-			&task.Status,
-			&task.Priority,
-			&task.DueDate,
-			&task.CreatedAt,
-			&task.UpdatedAt,
-			&task.CategoryID,
-			&task.UserID,
-			pq.Array(&task.Tags),
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan task: %w", err)
+	if err := setTaskTags(q, id, input.UserID, input.Tags); err != nil {
+		return models.Task{}, err
+	}
+
+	task, err := getTask(q, id, input.UserID)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	if err := emitTaskEvent(q, models.EventTaskCreated, task); err != nil {
+		return models.Task{}, err
+	}
+
+	return task, nil
+}
+
+// GetTask retrieves a task by ID for a specific user
+func (db *DB) GetTask(id string, userID string) (models.Task, error) {
+	return getTask(db, id, userID)
+}
+
+// LookupTask is GetTask for optional references: it returns nil when the task
+// does not exist or is in the trash
+func (db *DB) LookupTask(id string, userID string) (*models.Task, error) {
+	query := `SELECT ` + taskColumns + ` FROM tasks t WHERE t.id = $1 AND t.user_id = $2 AND t.deleted_at IS NULL`
+
+	task, err := scanTask(db.QueryRow(query, id, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
 		}
-		tasks = append(tasks, task)
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+
+	return &task, nil
+}
+
+// GetAllTasks retrieves all tasks for a specific user
+func (db *DB) GetAllTasks() ([]models.Task, error) {
+	query := `SELECT ` + taskColumns + ` FROM tasks t WHERE t.deleted_at IS NULL ORDER BY t.created_at DESC`
+
+	return queryTasks(db, query)
+}
+
+func (db *DB) GetAllTasksByUser(userID string) ([]models.Task, error) {
+	tasks, err := db.GetTasks(userID, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tasks for user: %w", err)
 	}
 
 	return tasks, nil
 }
 
 func (db *DB) UpdateTask(id string, input models.UpdateTaskInput, userID string) (models.Task, error) {
+	var task models.Task
+	err := db.withTx(func(tx *sql.Tx) error {
+		var err error
+		task, err = updateTask(tx, id, input, userID)
+		return err
+	})
+	return task, err
+}
+
+// updateTask applies the non-nil fields of input to a task using q
+func updateTask(q querier, id string, input models.UpdateTaskInput, userID string) (models.Task, error) {
+	// Lock the row and remember the old status for the status_changed event,
+	// and the old dates that date changes apply to
+	var previousStatus models.TaskStatus
+	var current taskDates
+	var currentDue, currentStart *time.Time
+	var currentDueDay, currentStartDay *string
+	var version int
+	err := q.QueryRow(`
+		SELECT status, all_day, due_date, to_char(due_on, 'YYYY-MM-DD'),
+			start_date, to_char(start_on, 'YYYY-MM-DD'), version
+		FROM tasks
+		WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+		FOR UPDATE`, id, userID).Scan(&previousStatus, &current.allDay, &currentDue, &currentDueDay,
+		&currentStart, &currentStartDay, &version)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Task{}, apperr.NotFound("task")
+		}
+		return models.Task{}, fmt.Errorf("failed to get task: %w", err)
+	}
+
+	// The row is locked, so the version cannot move between here and the update
+	if input.ExpectedVersion != nil && *input.ExpectedVersion != version {
+		task, err := getTask(q, id, userID)
+		if err != nil {
+			return models.Task{}, err
+		}
+		return models.Task{}, versionConflict("task", task.Version, task)
+	}
+
 	// Build dynamic query based on provided fields
 	setParts := []string{"updated_at = NOW()"}
 	args := []interface{}{}
@@ -239,167 +395,243 @@ func (db *DB) UpdateTask(id string, input models.UpdateTaskInput, userID string)
 		setParts = append(setParts, fmt.Sprintf("status = $%d", argIndex))
 		args = append(args, *input.Status)
 		argIndex++
+
+		// Completion time is kept for the weekly digest
+		if *input.Status != previousStatus {
+			if *input.Status == models.TaskStatusCompleted {
+				setParts = append(setParts, "completed_at = NOW()")
+			} else {
+				setParts = append(setParts, "completed_at = NULL")
+			}
+		}
 	}
 	if input.Priority != nil {
 		setParts = append(setParts, fmt.Sprintf("priority = $%d", argIndex))
 		args = append(args, *input.Priority)
 		argIndex++
 	}
-	if input.DueDate != nil {
-		dueDate, err := time.Parse(time.RFC3339, *input.DueDate)
+	datesChanged := input.DueDate != nil || input.AllDay != nil || input.StartDate != nil
+	if datesChanged {
+		if currentDue != nil {
+			current.due = &taskDate{at: *currentDue, day: currentDueDay}
+		}
+		if currentStart != nil {
+			current.start = &taskDate{at: *currentStart, day: currentStartDay}
+		}
+		dates, err := updatedTaskDates(q, current, userID, input)
 		if err != nil {
 			return models.Task{}, err
 		}
-		setParts = append(setParts, fmt.Sprintf("due_date = $%d", argIndex))
-		args = append(args, dueDate)
+		setParts = append(setParts, fmt.Sprintf(
+			"all_day = $%d, due_date = $%d, due_on = $%d, start_date = $%d, start_on = $%d",
+			argIndex, argIndex+1, argIndex+2, argIndex+3, argIndex+4))
+		args = append(args, dates.allDay, dates.dueAt(), dates.dueDay(), dates.startAt(), dates.startDay())
+		argIndex += 5
+	}
+	if input.EstimateMinutes != nil {
+		if *input.EstimateMinutes < 0 {
+			return models.Task{}, errInvalidEstimate
+		}
+		setParts = append(setParts, fmt.Sprintf("estimate_minutes = NULLIF($%d, 0)", argIndex))
+		args = append(args, *input.EstimateMinutes)
 		argIndex++
 	}
 	if input.CategoryID != nil {
+		owned, err := ownsCategory(q, *input.CategoryID, userID)
+		if err != nil {
+			return models.Task{}, err
+		}
+		if !owned {
+			return models.Task{}, apperr.NotFound("category")
+		}
 		setParts = append(setParts, fmt.Sprintf("category_id = $%d", argIndex))
 		args = append(args, *input.CategoryID)
 		argIndex++
 	}
-	if input.Tags != nil {
-		setParts = append(setParts, fmt.Sprintf("tags = $%d", argIndex))
-		args = append(args, pq.Array(input.Tags))
-		argIndex++
-	}
 
 	// Add ID and userID as the last arguments
 	args = append(args, id, userID)
 
-	// Join all setParts
-	setClause := ""
-	for i, part := range setParts {
-		if i > 0 {
-			setClause += ", "
-		}
-		setClause += part
-	}
-
 	query := fmt.Sprintf(`
 		UPDATE tasks SET %s
-		WHERE id = $%d AND user_id = $%d
-		RETURNING id, title, description, status, priority, due_date, created_at, updated_at, category_id, user_id, tags`,
-		setClause, argIndex, argIndex+1)
-
-	var task models.Task
-	err := db.QueryRow(query, args...).Scan(
-		&task.ID,
-		&task.Title,
-		&task.Description,
-		&task.Status,
-		&task.Priority,
-		&task.DueDate,
-		&task.CreatedAt,
-		&task.UpdatedAt,
-		&task.CategoryID,
-		&task.UserID,
-		pq.Array(&task.Tags),
-	)
+		WHERE id = $%d AND user_id = $%d AND deleted_at IS NULL
+		RETURNING id`,
+		strings.Join(setParts, ", "), argIndex, argIndex+1)
 
+	var taskID string
+	err = q.QueryRow(query, args...).Scan(&taskID)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return models.Task{}, errors.New("task not found")
+			return models.Task{}, apperr.NotFound("task")
 		}
 		return models.Task{}, fmt.Errorf("failed to update task: %w", err)
 	}
 
-	return task, nil
-}
-
-// UpdateTaskStatus updates the status of a task for a specific user
-func (db *DB) UpdateTaskStatus(id string, status models.TaskStatus, userID string) (models.Task, error) {
-	query := `
-		UPDATE tasks SET status = $1, updated_at = NOW()
-		WHERE id = $2 AND user_id = $3
-		RETURNING id, title, description, status, priority, due_date, created_at, updated_at, category_id, user_id, tags`
+	if input.Tags != nil {
+		if err := setTaskTags(q, taskID, userID, input.Tags); err != nil {
+			return models.Task{}, err
+		}
+	}
 
-	var task models.Task
-	err := db.QueryRow(query, status, id, userID).Scan(
-		&task.ID,
-		&task.Title,
-		&task.Description,
-		&task.Status,
-		&task.Priority,
-		&task.DueDate,
-		&task.CreatedAt,
-		&task.UpdatedAt,
-		&task.CategoryID,
-		&task.UserID,
-		pq.Array(&task.Tags),
-	)
+	if datesChanged {
+		if err := rescheduleReminders(q, taskID); err != nil {
+			return models.Task{}, err
+		}
+	}
 
+	task, err := getTask(q, taskID, userID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return models.Task{}, errors.New("task not found")
+		return models.Task{}, err
+	}
+
+	if err := emitTaskEvent(q, models.EventTaskUpdated, task); err != nil {
+		return models.Task{}, err
+	}
+	if task.Status != previousStatus {
+		err := emitEvent(q, userID, models.EventTaskStatusChanged, map[string]interface{}{
+			"task":           task,
+			"previousStatus": previousStatus,
+		})
+		if err != nil {
+			return models.Task{}, err
 		}
-		return models.Task{}, fmt.Errorf("failed to update task status: %w", err)
 	}
 
 	return task, nil
 }
 
+// UpdateTaskStatus updates the status of a task for a specific user. A
+// non-nil expectedVersion must be the task's current version.
+func (db *DB) UpdateTaskStatus(id string, status models.TaskStatus, expectedVersion *int, userID string) (models.Task, error) {
+	return db.UpdateTask(id, models.UpdateTaskInput{Status: &status, ExpectedVersion: expectedVersion}, userID)
+}
+
 // DeleteTask deletes a task by ID for a specific user
 func (db *DB) DeleteTask(id string, userID string) error {
-	query := `DELETE FROM tasks WHERE id = $1 AND user_id = $2`
-	result, err := db.Exec(query, id, userID)
-	if err != nil {
-		return fmt.Errorf("failed to delete task: %w", err)
-	}
+	return db.withTx(func(tx *sql.Tx) error {
+		return deleteTask(tx, id, userID)
+	})
+}
 
-	rowsAffected, err := result.RowsAffected()
+// deleteTask permanently deletes a task, live or trashed, using q
+func deleteTask(q querier, id string, userID string) error {
+	query := `SELECT ` + taskColumns + ` FROM tasks t WHERE t.id = $1 AND t.user_id = $2 FOR UPDATE`
+
+	task, err := scanTask(q.QueryRow(query, id, userID))
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		if err == sql.ErrNoRows {
+			return apperr.NotFound("task")
+		}
+		return fmt.Errorf("failed to get task: %w", err)
 	}
 
-	if rowsAffected == 0 {
-		return errors.New("task not found")
+	if _, err := q.Exec(`DELETE FROM tasks WHERE id = $1 AND user_id = $2`, id, userID); err != nil {
+		return fmt.Errorf("failed to delete task: %w", err)
 	}
 
-	return nil
+	return emitTaskEvent(q, models.EventTaskDeleted, task)
 }
 
-// CreateCategory creates a new category for a specific user
-func (db *DB) CreateCategory(name string, userID string) (models.Category, error) {
-	query := `
-		INSERT INTO categories (name, user_id)
-		VALUES ($1, $2)
-		RETURNING id, name, created_at, updated_at`
+// categoryColumns is the select list used for every category query
+const categoryColumns = `c.id, c.name, c.parent_id, c.created_at, c.updated_at, c.version`
 
+// scanCategory scans a row selected with categoryColumns
+func scanCategory(row rowScanner) (models.Category, error) {
 	var category models.Category
-	err := db.QueryRow(query, name, userID).Scan(
+	err := row.Scan(
 		&category.ID,
 		&category.Name,
+		&category.ParentID,
 		&category.CreatedAt,
 		&category.UpdatedAt,
+		&category.Version,
 	)
+	return category, err
+}
 
+// queryCategories runs a query selecting categoryColumns and collects the results
+func queryCategories(q querier, query string, args ...interface{}) ([]models.Category, error) {
+	rows, err := q.Query(query, args...)
 	if err != nil {
-		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" { // unique violation
-			return models.Category{}, errors.New("category with this name already exists")
+		return nil, fmt.Errorf("failed to query categories: %w", err)
+	}
+	defer rows.Close()
+
+	var categories []models.Category
+	for rows.Next() {
+		category, err := scanCategory(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan category: %w", err)
 		}
-		return models.Category{}, fmt.Errorf("failed to create category: %w", err)
+		categories = append(categories, category)
 	}
 
-	return category, nil
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate categories: %w", err)
+	}
+
+	return categories, nil
 }
 
-// GetCategory retrieves a category by ID for a specific user
-func (db *DB) GetCategory(id string, userID string) (models.Category, error) {
-	query := `SELECT id, name, created_at, updated_at FROM categories WHERE id = $1 AND user_id = $2`
+// categoryTreeCTE selects the IDs of category $1 and all of its descendants
+// owned by user $2. The depth guard protects against corrupt cycles.
+const categoryTreeCTE = `
+	WITH RECURSIVE category_tree AS (
+		SELECT id, 0 AS depth FROM categories WHERE id = $1 AND user_id = $2
+		UNION ALL
+		SELECT c.id, ct.depth + 1 FROM categories c
+		JOIN category_tree ct ON c.parent_id = ct.id
+		WHERE c.user_id = $2 AND ct.depth < 100
+	)`
+
+// lockUserCategories serialises structural category changes for a user so
+// concurrent moves cannot create a cycle
+func lockUserCategories(q querier, userID string) error {
+	if _, err := q.Exec(`SELECT pg_advisory_xact_lock(hashtext('categories:' || $1))`, userID); err != nil {
+		return fmt.Errorf("failed to lock categories: %w", err)
+	}
+	return nil
+}
 
+// CreateCategory creates a new category for a specific user, optionally
+// nested under parentID
+func (db *DB) CreateCategory(name string, parentID *string, userID string) (models.Category, error) {
 	var category models.Category
-	err := db.QueryRow(query, id, userID).Scan(
-		&category.ID,
-		&category.Name,
-		&category.CreatedAt,
-		&category.UpdatedAt,
-	)
+	err := db.withTx(func(tx *sql.Tx) error {
+		if parentID != nil {
+			if _, err := getCategory(tx, *parentID, userID); err != nil {
+				return fmt.Errorf("parent %w", err)
+			}
+		}
+
+		query := `
+			INSERT INTO categories (name, parent_id, user_id)
+			VALUES ($1, $2, $3)
+			RETURNING id, name, parent_id, created_at, updated_at, version`
+
+		var err error
+		category, err = scanCategory(tx.QueryRow(query, name, parentID, userID))
+		if err != nil {
+			if isUniqueViolation(err) {
+				return apperr.Conflict("category with this name already exists")
+			}
+			return fmt.Errorf("failed to create category: %w", err)
+		}
+
+		return emitCategoryEvent(tx, userID, models.EventCategoryCreated, category)
+	})
 
+	return category, err
+}
+
+// getCategory loads a single category owned by userID
+func getCategory(q querier, id string, userID string) (models.Category, error) {
+	query := `SELECT ` + categoryColumns + ` FROM categories c WHERE c.id = $1 AND c.user_id = $2`
+
+	category, err := scanCategory(q.QueryRow(query, id, userID))
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return models.Category{}, errors.New("category not found")
+			return models.Category{}, apperr.NotFound("category")
 		}
 		return models.Category{}, fmt.Errorf("failed to get category: %w", err)
 	}
@@ -407,157 +639,371 @@ func (db *DB) GetCategory(id string, userID string) (models.Category, error) {
 	return category, nil
 }
 
+// GetCategory retrieves a category by ID for a specific user
+func (db *DB) GetCategory(id string, userID string) (models.Category, error) {
+	return getCategory(db, id, userID)
+}
+
 // GetAllCategories retrieves all categories for a specific user
 func (db *DB) GetAllCategories(userID string) ([]models.Category, error) {
-	query := `SELECT id, name, created_at, updated_at FROM categories WHERE user_id = $1 ORDER BY name`
+	query := `SELECT ` + categoryColumns + ` FROM categories c WHERE c.user_id = $1 ORDER BY c.name`
+
+	return queryCategories(db, query, userID)
+}
+
+// GetChildCategories retrieves the direct subcategories of a category
+func (db *DB) GetChildCategories(parentID string, userID string) ([]models.Category, error) {
+	query := `SELECT ` + categoryColumns + `
+		FROM categories c WHERE c.parent_id = $1 AND c.user_id = $2 ORDER BY c.name`
+
+	return queryCategories(db, query, parentID, userID)
+}
+
+// GetCategoryPath returns the names of a category and its ancestors, root first
+func (db *DB) GetCategoryPath(id string, userID string) ([]string, error) {
+	query := `
+		WITH RECURSIVE ancestors AS (
+			SELECT id, name, parent_id, 0 AS depth FROM categories WHERE id = $1 AND user_id = $2
+			UNION ALL
+			SELECT c.id, c.name, c.parent_id, a.depth + 1 FROM categories c
+			JOIN ancestors a ON c.id = a.parent_id
+			WHERE c.user_id = $2 AND a.depth < 100
+		)
+		SELECT name FROM ancestors ORDER BY depth DESC`
 
-	rows, err := db.Query(query, userID)
+	rows, err := db.Query(query, id, userID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query categories: %w", err)
+		return nil, fmt.Errorf("failed to query category path: %w", err)
 	}
 	defer rows.Close()
 
-	var categories []models.Category
+	var path []string
 	for rows.Next() {
-		var category models.Category
-		err := rows.Scan(
-			&category.ID,
-			&category.Name,
-			&category.CreatedAt,
-			&category.UpdatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan category: %w", err)
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan category path: %w", err)
 		}
-		categories = append(categories, category)
+		path = append(path, name)
 	}
 
-	return categories, nil
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate category path: %w", err)
+	}
+
+	if len(path) == 0 {
+		return nil, apperr.NotFound("category")
+	}
+
+	return path, nil
 }
 
-// UpdateCategory updates an existing category for a specific user
-func (db *DB) UpdateCategory(id string, name string, userID string) (models.Category, error) {
+// UpdateCategory updates an existing category for a specific user. A non-nil
+// expectedVersion must be the category's current version.
+func (db *DB) UpdateCategory(id string, name string, expectedVersion *int, userID string) (models.Category, error) {
 	query := `
 		UPDATE categories SET name = $1, updated_at = NOW()
-		WHERE id = $2 AND user_id = $3
-		RETURNING id, name, created_at, updated_at`
+		WHERE id = $2 AND user_id = $3 AND ($4::integer IS NULL OR version = $4)
+		RETURNING id, name, parent_id, created_at, updated_at, version`
 
 	var category models.Category
-	err := db.QueryRow(query, name, id, userID).Scan(
-		&category.ID,
-		&category.Name,
-		&category.CreatedAt,
-		&category.UpdatedAt,
-	)
+	err := db.withTx(func(tx *sql.Tx) error {
+		var err error
+		category, err = scanCategory(tx.QueryRow(query, name, id, userID, expectedVersion))
+		if err != nil {
+			if err == sql.ErrNoRows {
+				// Either there is no such category or its version moved on
+				current, err := getCategory(tx, id, userID)
+				if err != nil {
+					return err
+				}
+				return versionConflict("category", current.Version, current)
+			}
+			if isUniqueViolation(err) {
+				return apperr.Conflict("category with this name already exists")
+			}
+			return fmt.Errorf("failed to update category: %w", err)
+		}
 
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return models.Category{}, errors.New("category not found")
+		return emitCategoryEvent(tx, userID, models.EventCategoryUpdated, category)
+	})
+
+	return category, err
+}
+
+// MoveCategory re-parents a category for a specific user. A nil parentID moves
+// it to the top level. Moving a category underneath itself or one of its
+// descendants is rejected.
+func (db *DB) MoveCategory(id string, parentID *string, userID string) (models.Category, error) {
+	var category models.Category
+	err := db.withTx(func(tx *sql.Tx) error {
+		if err := lockUserCategories(tx, userID); err != nil {
+			return err
 		}
-		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
-			return models.Category{}, errors.New("category with this name already exists")
+
+		if _, err := getCategory(tx, id, userID); err != nil {
+			return err
 		}
-		return models.Category{}, fmt.Errorf("failed to update category: %w", err)
-	}
 
-	return category, nil
+		if parentID != nil {
+			if *parentID == id {
+				return apperr.Invalid("parentId", "a category cannot be its own parent")
+			}
+			if _, err := getCategory(tx, *parentID, userID); err != nil {
+				return fmt.Errorf("parent %w", err)
+			}
+
+			var isDescendant bool
+			err := tx.QueryRow(categoryTreeCTE+`
+				SELECT EXISTS (SELECT 1 FROM category_tree WHERE id = $3)`,
+				id, userID, *parentID).Scan(&isDescendant)
+			if err != nil {
+				return fmt.Errorf("failed to check category hierarchy: %w", err)
+			}
+			if isDescendant {
+				return apperr.Invalid("parentId", "cannot move a category into one of its own subcategories")
+			}
+		}
+
+		query := `
+			UPDATE categories SET parent_id = $1, updated_at = NOW()
+			WHERE id = $2 AND user_id = $3
+			RETURNING id, name, parent_id, created_at, updated_at, version`
+
+		var err error
+		category, err = scanCategory(tx.QueryRow(query, parentID, id, userID))
+		if err != nil {
+			if isUniqueViolation(err) {
+				return apperr.Conflict("a category with this name already exists in the destination")
+			}
+			return fmt.Errorf("failed to move category: %w", err)
+		}
+
+		return emitCategoryEvent(tx, userID, models.EventCategoryUpdated, category)
+	})
+
+	return category, err
 }
 
-// DeleteCategory deletes a category by ID for a specific user
-func (db *DB) DeleteCategory(id string, userID string) error {
-	// Check if any tasks are using this category
-	var count int
-	err := db.QueryRow("SELECT COUNT(*) FROM tasks WHERE category_id = $1 AND user_id = $2", id, userID).Scan(&count)
-	if err != nil {
-		return fmt.Errorf("failed to check category usage: %w", err)
-	}
+// DeleteCategory deletes a category by ID for a specific user. The strategy
+// decides what happens to the tasks in it: FAIL keeps the old behaviour and
+// refuses while tasks or subcategories exist, while the other strategies
+// delete the whole subtree and reassign, delete or trash its tasks.
+func (db *DB) DeleteCategory(id string, strategy models.CategoryDeleteStrategy, targetCategoryID *string, userID string) (models.DeleteCategoryResult, error) {
+	result := models.DeleteCategoryResult{Strategy: strategy}
 
-	if count > 0 {
-		return errors.New("cannot delete category with associated tasks")
-	}
+	err := db.withTx(func(tx *sql.Tx) error {
+		if err := lockUserCategories(tx, userID); err != nil {
+			return err
+		}
 
-	query := `DELETE FROM categories WHERE id = $1 AND user_id = $2`
-	result, err := db.Exec(query, id, userID)
-	if err != nil {
-		return fmt.Errorf("failed to delete category: %w", err)
-	}
+		category, err := getCategory(tx, id, userID)
+		if err != nil {
+			return err
+		}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
-	}
+		// Deepest categories first so children are removed before their parents
+		rows, err := tx.Query(categoryTreeCTE+`
+			SELECT id FROM category_tree ORDER BY depth DESC`, id, userID)
+		if err != nil {
+			return fmt.Errorf("failed to load subcategories: %w", err)
+		}
+		var subtree []string
+		for rows.Next() {
+			var categoryID string
+			if err := rows.Scan(&categoryID); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan subcategory: %w", err)
+			}
+			subtree = append(subtree, categoryID)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to iterate subcategories: %w", err)
+		}
 
-	if rowsAffected == 0 {
-		return errors.New("category not found")
+		var taskCount int
+		err = tx.QueryRow(`
+			SELECT COUNT(*) FROM tasks
+			WHERE category_id = ANY($1) AND user_id = $2 AND deleted_at IS NULL`,
+			pq.Array(subtree), userID).Scan(&taskCount)
+		if err != nil {
+			return fmt.Errorf("failed to check category usage: %w", err)
+		}
+
+		var affected sql.Result
+		switch strategy {
+		case models.CategoryDeleteStrategyFail:
+			if taskCount > 0 {
+				return apperr.Conflict("cannot delete category with associated tasks")
+			}
+			if len(subtree) > 1 {
+				return apperr.Conflict("cannot delete category with subcategories")
+			}
+
+		case models.CategoryDeleteStrategyReassignTo:
+			if targetCategoryID == nil || *targetCategoryID == "" {
+				return apperr.Invalid("targetCategoryId", "a target category is required to reassign tasks")
+			}
+			for _, categoryID := range subtree {
+				if categoryID == *targetCategoryID {
+					return apperr.Invalid("targetCategoryId", "cannot reassign tasks to a category that is being deleted")
+				}
+			}
+			if _, err := getCategory(tx, *targetCategoryID, userID); err != nil {
+				return fmt.Errorf("target %w", err)
+			}
+			affected, err = tx.Exec(`
+				UPDATE tasks SET category_id = $1, updated_at = NOW()
+				WHERE category_id = ANY($2) AND user_id = $3 AND deleted_at IS NULL`,
+				*targetCategoryID, pq.Array(subtree), userID)
+
+		case models.CategoryDeleteStrategyDeleteTasks:
+			// Trashed tasks of the categories are purged as well, but only live
+			// tasks count as affected, as with the other strategies
+			tasks, err := queryTasks(tx, `SELECT `+taskColumns+` FROM tasks t
+				WHERE t.category_id = ANY($1) AND t.user_id = $2 FOR UPDATE`, pq.Array(subtree), userID)
+			if err != nil {
+				return err
+			}
+			_, err = tx.Exec(`DELETE FROM tasks WHERE category_id = ANY($1) AND user_id = $2`,
+				pq.Array(subtree), userID)
+			if err != nil {
+				return fmt.Errorf("failed to delete category tasks: %w", err)
+			}
+			for _, task := range tasks {
+				if task.DeletedAt == nil {
+					result.AffectedTasks++
+				}
+				if err := emitTaskEvent(tx, models.EventTaskDeleted, task); err != nil {
+					return err
+				}
+			}
+
+		case models.CategoryDeleteStrategyTrashTasks:
+			affected, err = tx.Exec(`
+				UPDATE tasks SET deleted_at = NOW(), updated_at = NOW()
+				WHERE category_id = ANY($1) AND user_id = $2 AND deleted_at IS NULL`,
+				pq.Array(subtree), userID)
+
+		default:
+			return apperr.Invalid("strategy", "unknown delete strategy %q", strategy)
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to update category tasks: %w", err)
+		}
+		if affected != nil {
+			count, err := affected.RowsAffected()
+			if err != nil {
+				return fmt.Errorf("failed to get rows affected: %w", err)
+			}
+			result.AffectedTasks = int(count)
+		}
+
+		for _, categoryID := range subtree {
+			if _, err := tx.Exec(`DELETE FROM categories WHERE id = $1 AND user_id = $2`, categoryID, userID); err != nil {
+				return fmt.Errorf("failed to delete category: %w", err)
+			}
+		}
+
+		result.DeletedCategoryIDs = subtree
+		result.Success = true
+
+		return emitEvent(tx, userID, models.EventCategoryDeleted, map[string]interface{}{
+			"category":           category,
+			"strategy":           result.Strategy,
+			"deletedCategoryIds": result.DeletedCategoryIDs,
+			"affectedTasks":      result.AffectedTasks,
+		})
+	})
+
+	if err != nil {
+		return models.DeleteCategoryResult{}, err
 	}
 
-	return nil
+	return result, nil
 }
 
-// GetTasksInCategory retrieves all tasks in a category for a specific user
-func (db *DB) GetTasksInCategory(categoryID string, userID string) ([]models.Task, error) {
+// GetTasksInCategory retrieves all tasks in a category for a specific user.
+// When includeDescendants is set, tasks in every subcategory are included too.
+func (db *DB) GetTasksInCategory(categoryID string, userID string, includeDescendants bool) ([]models.Task, error) {
 	// First check if category exists for this user
 	_, err := db.GetCategory(categoryID, userID)
 	if err != nil {
 		return nil, err
 	}
 
-	query := `
-		SELECT id, title, description, status, priority, due_date, created_at, updated_at, category_id, user_id, tags
-		FROM tasks WHERE category_id = $1 AND user_id = $2 ORDER BY created_at DESC`
+	query := `SELECT ` + taskColumns + `
+		FROM tasks t WHERE t.category_id = $1 AND t.user_id = $2 AND t.deleted_at IS NULL
+		ORDER BY t.created_at DESC`
+	if includeDescendants {
+		query = categoryTreeCTE + `
+		SELECT ` + taskColumns + `
+		FROM tasks t WHERE t.category_id IN (SELECT id FROM category_tree) AND t.user_id = $2
+			AND t.deleted_at IS NULL
+		ORDER BY t.created_at DESC`
+	}
 
-	rows, err := db.Query(query, categoryID, userID)
+	tasks, err := queryTasks(db, query, categoryID, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query tasks in category: %w", err)
 	}
-	defer rows.Close()
-
-	var tasks []models.Task
-	for rows.Next() {
-		var task models.Task
-		err := rows.Scan(
-			&task.ID,
-			&task.Title,
-			&task.Description,
-			&task.Status,
-			&task.Priority,
-			&task.DueDate,
-			&task.CreatedAt,
-			&task.UpdatedAt,
-			&task.CategoryID,
-			&task.UserID,
-			pq.Array(&task.Tags),
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan task: %w", err)
-		}
-		tasks = append(tasks, task)
-	}
 
 	return tasks, nil
 }
 
 // User management functions
 
-// CreateUser creates a new user
-func (db *DB) CreateUser(name, email, hashedPassword string) (models.User, error) {
-	query := `
-		INSERT INTO users (name, email, password)
-		VALUES ($1, $2, $3)
-		RETURNING id, name, email, created_at, updated_at`
+// userColumns is the select list used for every user query; users are aliased u
+const userColumns = `u.id, u.name, u.email, u.created_at, u.updated_at,
+		u.theme, u.language, u.timezone, u.date_format, u.start_of_week,
+		u.email_notifications, u.push_notifications, u.task_reminders, u.weekly_digest,
+		u.marketing_emails, u.session_timeout_minutes, u.email_verified_at IS NOT NULL, u.pending_email`
 
+// scanUser scans a row selected with userColumns followed by any extra columns
+func scanUser(row rowScanner, extra ...interface{}) (models.User, error) {
 	var user models.User
-	err := db.QueryRow(query, name, email, hashedPassword).Scan(
+	dest := []interface{}{
 		&user.ID,
 		&user.Name,
 		&user.Email,
 		&user.CreatedAt,
 		&user.UpdatedAt,
-	)
+		&user.Preferences.Theme,
+		&user.Preferences.Language,
+		&user.Preferences.Timezone,
+		&user.Preferences.DateFormat,
+		&user.Preferences.StartOfWeek,
+		&user.Preferences.Notifications.Email,
+		&user.Preferences.Notifications.Push,
+		&user.Preferences.Notifications.TaskReminders,
+		&user.Preferences.Notifications.WeeklyDigest,
+		&user.Preferences.Notifications.MarketingEmails,
+		&user.Preferences.SessionTimeoutMinutes,
+		&user.EmailVerified,
+		&user.PendingEmail,
+	}
+	err := row.Scan(append(dest, extra...)...)
+	return user, err
+}
+
+// NormalizeEmail returns the form emails are stored and looked up in
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// CreateUser creates a new user with an unverified email
+func (db *DB) CreateUser(name, email, hashedPassword string) (models.User, error) {
+	query := `
+		INSERT INTO users AS u (name, email, password)
+		VALUES ($1, $2, $3)
+		RETURNING ` + userColumns
+
+	user, err := scanUser(db.QueryRow(query, name, NormalizeEmail(email), hashedPassword))
 
 	if err != nil {
-		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" { // unique violation
-			return models.User{}, errors.New("user with this email already exists")
+		if isUniqueViolation(err) {
+			return models.User{}, apperr.Conflict("email already in use")
 		}
 		return models.User{}, fmt.Errorf("failed to create user: %w", err)
 	}
@@ -567,44 +1013,31 @@ func (db *DB) CreateUser(name, email, hashedPassword string) (models.User, error
 
 // GetUserByEmail retrieves a user by email
 func (db *DB) GetUserByEmail(email string) (models.User, error) {
-	query := `SELECT id, name, email, password, created_at, updated_at FROM users WHERE email = $1`
+	query := `SELECT ` + userColumns + `, u.password FROM users u WHERE lower(u.email) = $1`
 
-	var user models.User
-	err := db.QueryRow(query, email).Scan(
-		&user.ID,
-		&user.Name,
-		&user.Email,
-		&user.Password,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+	var password string
+	user, err := scanUser(db.QueryRow(query, NormalizeEmail(email)), &password)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return models.User{}, errors.New("user not found")
+			return models.User{}, apperr.NotFound("user")
 		}
 		return models.User{}, fmt.Errorf("failed to get user: %w", err)
 	}
 
+	user.Password = password
 	return user, nil
 }
 
 // GetUserByID retrieves a user by ID
 func (db *DB) GetUserByID(id string) (models.User, error) {
-	query := `SELECT id, name, email, created_at, updated_at FROM users WHERE id = $1`
+	query := `SELECT ` + userColumns + ` FROM users u WHERE u.id = $1`
 
-	var user models.User
-	err := db.QueryRow(query, id).Scan(
-		&user.ID,
-		&user.Name,
-		&user.Email,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+	user, err := scanUser(db.QueryRow(query, id))
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return models.User{}, errors.New("user not found")
+			return models.User{}, apperr.NotFound("user")
 		}
 		return models.User{}, fmt.Errorf("failed to get user: %w", err)
 	}
@@ -612,7 +1045,8 @@ func (db *DB) GetUserByID(id string) (models.User, error) {
 	return user, nil
 }
 
-// UpdateUserProfile updates a user's profile information
+// UpdateUserProfile updates a user's profile information. A new email is
+// kept as the pending email until it is verified; the current email clears it.
 func (db *DB) UpdateUserProfile(id string, input models.UpdateProfileInput) (models.User, error) {
 	// Start building the query dynamically
 	setParts := []string{}
@@ -626,13 +1060,36 @@ func (db *DB) UpdateUserProfile(id string, input models.UpdateProfileInput) (mod
 	}
 
 	if input.Email != nil {
-		setParts = append(setParts, fmt.Sprintf("email = $%d", argCount))
-		args = append(args, *input.Email)
+		setParts = append(setParts, fmt.Sprintf("pending_email = NULLIF($%d, u.email)", argCount))
+		args = append(args, NormalizeEmail(*input.Email))
+		argCount++
+	}
+
+	if input.TaskReminders != nil {
+		setParts = append(setParts, fmt.Sprintf("task_reminders = $%d", argCount))
+		args = append(args, *input.TaskReminders)
+		argCount++
+	}
+
+	if input.WeeklyDigest != nil {
+		setParts = append(setParts, fmt.Sprintf("weekly_digest = $%d", argCount))
+		args = append(args, *input.WeeklyDigest)
+		argCount++
+	}
+
+	if input.Timezone != nil {
+		setParts = append(setParts, fmt.Sprintf("timezone = $%d", argCount))
+		args = append(args, *input.Timezone)
 		argCount++
 	}
 
+	// The digest job works out the next send time again in the new setting
+	if input.WeeklyDigest != nil || input.Timezone != nil {
+		setParts = append(setParts, "digest_next_at = NULL")
+	}
+
 	if len(setParts) == 0 {
-		return models.User{}, errors.New("no fields to update")
+		return models.User{}, apperr.Invalid("", "no fields to update")
 	}
 
 	// Add updated_at and user ID
@@ -643,32 +1100,36 @@ func (db *DB) UpdateUserProfile(id string, input models.UpdateProfileInput) (mod
 	args = append(args, id)
 
 	query := fmt.Sprintf(`
-		UPDATE users 
+		UPDATE users u
 		SET %s 
-		WHERE id = $%d
-		RETURNING id, name, email, created_at, updated_at`,
-		strings.Join(setParts, ", "), argCount)
+		WHERE u.id = $%d
+		RETURNING %s`,
+		strings.Join(setParts, ", "), argCount, userColumns)
 
 	var user models.User
-	err := db.QueryRow(query, args...).Scan(
-		&user.ID,
-		&user.Name,
-		&user.Email,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
-
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return models.User{}, errors.New("user not found")
+	err := db.withTx(func(tx *sql.Tx) error {
+		if input.Email != nil {
+			if err := checkEmailAvailable(tx, *input.Email, id); err != nil {
+				return err
+			}
 		}
-		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" { // unique violation
-			return models.User{}, errors.New("email already exists")
+
+		var err error
+		user, err = scanUser(tx.QueryRow(query, args...))
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return apperr.NotFound("user")
+			}
+			return fmt.Errorf("failed to update user profile: %w", err)
 		}
-		return models.User{}, fmt.Errorf("failed to update user profile: %w", err)
-	}
 
-	return user, nil
+		// All-day tasks stay on their day in the new timezone
+		if input.Timezone != nil {
+			return moveAllDayTasks(tx, id)
+		}
+		return nil
+	})
+	return user, err
 }
 
 // ChangeUserPassword changes a user's password
@@ -686,7 +1147,7 @@ func (db *DB) ChangeUserPassword(id string, newHashedPassword string) error {
 	}
 
 	if rowsAffected == 0 {
-		return errors.New("user not found")
+		return apperr.NotFound("user")
 	}
 
 	return nil
@@ -694,24 +1155,18 @@ func (db *DB) ChangeUserPassword(id string, newHashedPassword string) error {
 
 // GetUserWithPassword retrieves a user with password for authentication
 func (db *DB) GetUserWithPassword(id string) (models.User, error) {
-	query := `SELECT id, name, email, password, created_at, updated_at FROM users WHERE id = $1`
+	query := `SELECT ` + userColumns + `, u.password FROM users u WHERE u.id = $1`
 
-	var user models.User
-	err := db.QueryRow(query, id).Scan(
-		&user.ID,
-		&user.Name,
-		&user.Email,
-		&user.Password,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+	var password string
+	user, err := scanUser(db.QueryRow(query, id), &password)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return models.User{}, errors.New("user not found")
+			return models.User{}, apperr.NotFound("user")
 		}
 		return models.User{}, fmt.Errorf("failed to get user: %w", err)
 	}
 
+	user.Password = password
 	return user, nil
 }
@@ -0,0 +1,247 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/apperr"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+)
+
+// dayLayout is the form of a calendar day, as used for all-day dates
+const dayLayout = "2006-01-02"
+
+// taskDate is a due or start date as stored. All-day dates keep their calendar
+// day alongside the instant, which is midnight of that day in the owner's
+// timezone.
+type taskDate struct {
+	at  time.Time
+	day *string
+}
+
+// isDay reports whether value is a calendar day (YYYY-MM-DD) rather than a timestamp
+func isDay(value string) bool {
+	_, err := time.Parse(dayLayout, value)
+	return err == nil
+}
+
+// parseTaskDate reads a YYYY-MM-DD day or an RFC 3339 timestamp given in the
+// input field named field. An all-day date taken from a timestamp keeps the
+// day as written in its offset.
+func parseTaskDate(field string, value string, allDay bool, loc *time.Location) (taskDate, error) {
+	var t time.Time
+	var err error
+	if isDay(value) {
+		t, err = time.Parse(dayLayout, value)
+	} else {
+		t, err = time.Parse(time.RFC3339, value)
+	}
+	if err != nil {
+		return taskDate{}, apperr.Invalid(field, "invalid %s %q: use YYYY-MM-DD or an RFC 3339 timestamp", field, value)
+	}
+
+	if !allDay {
+		return taskDate{at: t}, nil
+	}
+	return allDayDate(t.Year(), t.Month(), t.Day(), loc), nil
+}
+
+// allDayDate returns the stored form of an all-day date
+func allDayDate(year int, month time.Month, day int, loc *time.Location) taskDate {
+	at := time.Date(year, month, day, 0, 0, 0, 0, loc)
+	name := at.Format(dayLayout)
+	return taskDate{at: at, day: &name}
+}
+
+// asAllDay converts a stored instant to the all-day date of its day in loc
+func asAllDay(t time.Time, loc *time.Location) taskDate {
+	local := t.In(loc)
+	return allDayDate(local.Year(), local.Month(), local.Day(), loc)
+}
+
+// taskDates are the stored due and start dates of a task. Either may be missing.
+type taskDates struct {
+	allDay bool
+	due    *taskDate
+	start  *taskDate
+}
+
+// errStartAfterDue is returned when a task would start after it is due
+var errStartAfterDue = apperr.Invalid("startDate", "start date must not be after the due date")
+
+// dueAt and dueDay are the due date columns; both are nil without a due date
+func (d taskDates) dueAt() *time.Time {
+	if d.due == nil {
+		return nil
+	}
+	return &d.due.at
+}
+
+func (d taskDates) dueDay() *string {
+	if d.due == nil {
+		return nil
+	}
+	return d.due.day
+}
+
+// startAt and startDay are the start date columns; both are nil without a start date
+func (d taskDates) startAt() *time.Time {
+	if d.start == nil {
+		return nil
+	}
+	return &d.start.at
+}
+
+func (d taskDates) startDay() *string {
+	if d.start == nil {
+		return nil
+	}
+	return d.start.day
+}
+
+// newTaskDates works out the stored dates of a task being created. Without
+// an explicit allDay, a due date (or else a start date) given as a day makes
+// the task all-day.
+func newTaskDates(q querier, input models.CreateTaskInput) (taskDates, error) {
+	var dates taskDates
+	if input.DueDate != nil && *input.DueDate != "" {
+		dates.allDay = isDay(*input.DueDate)
+	} else if input.StartDate != nil && *input.StartDate != "" {
+		dates.allDay = isDay(*input.StartDate)
+	}
+	if input.AllDay != nil {
+		dates.allDay = *input.AllDay
+	}
+
+	loc := time.UTC
+	if dates.allDay {
+		var err error
+		if loc, err = userLocation(q, input.UserID); err != nil {
+			return taskDates{}, err
+		}
+	}
+
+	if input.DueDate != nil && *input.DueDate != "" {
+		due, err := parseTaskDate("dueDate", *input.DueDate, dates.allDay, loc)
+		if err != nil {
+			return taskDates{}, err
+		}
+		dates.due = &due
+	}
+	if input.StartDate != nil && *input.StartDate != "" {
+		start, err := parseTaskDate("startDate", *input.StartDate, dates.allDay, loc)
+		if err != nil {
+			return taskDates{}, err
+		}
+		dates.start = &start
+	}
+
+	return dates, dates.check()
+}
+
+// check rejects a start date after the due date
+func (d taskDates) check() error {
+	if d.start != nil && d.due != nil && d.start.at.After(d.due.at) {
+		return errStartAfterDue
+	}
+	return nil
+}
+
+// updatedTaskDates applies the date fields of input to a task's current
+// dates. Switching allDay alone converts the existing dates: to the day they
+// fall on in the owner's timezone, or to that day's midnight.
+func updatedTaskDates(q querier, current taskDates, userID string, input models.UpdateTaskInput) (taskDates, error) {
+	dates := current
+	if input.AllDay != nil {
+		dates.allDay = *input.AllDay
+	} else if input.DueDate != nil && *input.DueDate != "" {
+		dates.allDay = isDay(*input.DueDate)
+	}
+
+	loc := time.UTC
+	if dates.allDay {
+		var err error
+		if loc, err = userLocation(q, userID); err != nil {
+			return taskDates{}, err
+		}
+	}
+
+	convert := func(d taskDate) taskDate {
+		if dates.allDay {
+			return asAllDay(d.at, loc)
+		}
+		return taskDate{at: d.at}
+	}
+
+	if input.DueDate != nil {
+		dates.due = nil
+		if *input.DueDate != "" {
+			due, err := parseTaskDate("dueDate", *input.DueDate, dates.allDay, loc)
+			if err != nil {
+				return taskDates{}, err
+			}
+			dates.due = &due
+		}
+	} else if current.due != nil && dates.allDay != current.allDay {
+		due := convert(*current.due)
+		dates.due = &due
+	}
+
+	if input.StartDate != nil {
+		dates.start = nil
+		if *input.StartDate != "" {
+			start, err := parseTaskDate("startDate", *input.StartDate, dates.allDay, loc)
+			if err != nil {
+				return taskDates{}, err
+			}
+			dates.start = &start
+		}
+	} else if current.start != nil && dates.allDay != current.allDay {
+		start := convert(*current.start)
+		dates.start = &start
+	}
+
+	return dates, dates.check()
+}
+
+// userLocation loads the timezone from a user's preferences
+func userLocation(q querier, userID string) (*time.Location, error) {
+	var name string
+	if err := q.QueryRow(`SELECT timezone FROM users WHERE id = $1`, userID).Scan(&name); err != nil {
+		return nil, fmt.Errorf("failed to get user timezone: %w", err)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC, nil
+	}
+	return loc, nil
+}
+
+// userTimezoneSQL is the owner's timezone name for a query over tasks aliased t
+const userTimezoneSQL = `(SELECT tz.timezone FROM users tz WHERE tz.id = t.user_id)`
+
+// overdueSQL is true for open tasks over tasks aliased t that are past due at
+// the timestamptz expression now. All-day tasks are overdue once their day has
+// ended in the owner's timezone; tasks without a due date never are, so the
+// expression is never NULL.
+func overdueSQL(now string) string {
+	return `(t.status <> 'COMPLETED' AND t.due_date IS NOT NULL AND CASE WHEN t.all_day
+		THEN t.due_on < (` + now + ` AT TIME ZONE ` + userTimezoneSQL + `)::date
+		ELSE t.due_date < ` + now + ` END)`
+}
+
+// moveAllDayTasks recomputes the instants of a user's all-day dates after the
+// user changed timezone, and the offset reminders that depend on them
+func moveAllDayTasks(q querier, userID string) error {
+	_, err := q.Exec(`
+		UPDATE tasks t SET
+			due_date = COALESCE(t.due_on::timestamp AT TIME ZONE u.timezone, t.due_date),
+			start_date = COALESCE(t.start_on::timestamp AT TIME ZONE u.timezone, t.start_date)
+		FROM users u
+		WHERE u.id = t.user_id AND t.user_id = $1 AND t.all_day`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to move all-day tasks: %w", err)
+	}
+
+	return rescheduleRemindersWhere(q, "t.user_id = $1 AND t.all_day", userID)
+}
@@ -0,0 +1,88 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+)
+
+// ClaimDueDigests locks up to limit opted-in users whose weekly digest is due,
+// or who have no send time yet. Claimed users are leased so other replicas skip
+// them, and a send that never completes is retried once the lease runs out.
+func (db *DB) ClaimDueDigests(limit int, lease time.Duration) ([]models.DueDigest, error) {
+	query := `
+		WITH due AS (
+			SELECT id, digest_next_at FROM users
+			WHERE weekly_digest AND (digest_next_at IS NULL OR digest_next_at <= NOW())
+			ORDER BY digest_next_at NULLS FIRST
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE users u
+		SET digest_next_at = NOW() + $2::double precision * INTERVAL '1 second'
+		FROM due
+		WHERE u.id = due.id
+		RETURNING ` + userColumns + `, due.digest_next_at`
+
+	rows, err := db.Query(query, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim digests: %w", err)
+	}
+	defer rows.Close()
+
+	var digests []models.DueDigest
+	for rows.Next() {
+		var digest models.DueDigest
+		digest.User, err = scanUser(rows, &digest.ScheduledAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan digest user: %w", err)
+		}
+		digests = append(digests, digest)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate digest users: %w", err)
+	}
+
+	return digests, nil
+}
+
+// ScheduleDigest sets when a user's next weekly digest is due
+func (db *DB) ScheduleDigest(userID string, next time.Time) error {
+	_, err := db.Exec(`UPDATE users SET digest_next_at = $2 WHERE id = $1`, userID, next)
+	if err != nil {
+		return fmt.Errorf("failed to schedule digest: %w", err)
+	}
+	return nil
+}
+
+// GetDigestTasks loads the three task lists of a weekly digest: tasks completed
+// in [lastWeekStart, weekStart), open tasks overdue at now, and the other open
+// tasks due before weekEnd. All-day tasks due today count as upcoming.
+func (db *DB) GetDigestTasks(userID string, lastWeekStart, weekStart, now, weekEnd time.Time) (completed, overdue, upcoming []models.Task, err error) {
+	completed, err = queryTasks(db, `SELECT `+taskColumns+` FROM tasks t
+		WHERE t.user_id = $1 AND t.deleted_at IS NULL AND t.status = 'COMPLETED'
+			AND t.completed_at >= $2 AND t.completed_at < $3
+		ORDER BY t.completed_at`, userID, lastWeekStart, weekStart)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	overdue, err = queryTasks(db, `SELECT `+taskColumns+` FROM tasks t
+		WHERE t.user_id = $1 AND t.deleted_at IS NULL AND `+overdueSQL("$2::timestamptz")+`
+		ORDER BY t.due_date`, userID, now)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	upcoming, err = queryTasks(db, `SELECT `+taskColumns+` FROM tasks t
+		WHERE t.user_id = $1 AND t.deleted_at IS NULL AND t.status <> 'COMPLETED'
+			AND NOT `+overdueSQL("$2::timestamptz")+` AND t.due_date < $3
+		ORDER BY t.due_date`, userID, now, weekEnd)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return completed, overdue, upcoming, nil
+}
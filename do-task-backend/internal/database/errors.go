@@ -0,0 +1,25 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/apperr"
+	"github.com/lib/pq"
+)
+
+// ClientMessage returns the text of err that is safe to put in a result
+// payload, such as one failed item of a bulk change. Only application errors
+// keep their message and a malformed ID reads "invalid ID". Anything else may
+// come from the database, so ok is false and the caller should log err.
+func ClientMessage(err error) (message string, ok bool) {
+	var appErr *apperr.Error
+	if errors.As(err, &appErr) {
+		return appErr.Message, true
+	}
+	// A malformed ID only fails once Postgres casts it to a UUID
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "22P02" {
+		return "invalid ID", true
+	}
+	return "internal error", false
+}
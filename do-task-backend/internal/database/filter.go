@@ -0,0 +1,266 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/apperr"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+	"github.com/lib/pq"
+)
+
+// sqlArgs collects positional query arguments while a query is being built
+type sqlArgs struct {
+	values []interface{}
+}
+
+// add appends a value and returns its placeholder
+func (a *sqlArgs) add(v interface{}) string {
+	a.values = append(a.values, v)
+	return fmt.Sprintf("$%d", len(a.values))
+}
+
+// buildTaskFilter turns a task filter into a WHERE clause over tasks aliased
+// as t. The clause always restricts to the user's live (not trashed) tasks.
+func buildTaskFilter(filter *models.TaskFilter, userID string, args *sqlArgs) (string, error) {
+	userArg := args.add(userID)
+	conditions := []string{
+		"t.user_id = " + userArg,
+		"t.deleted_at IS NULL",
+	}
+
+	if filter == nil {
+		return strings.Join(conditions, " AND "), nil
+	}
+
+	if len(filter.Status) > 0 {
+		statuses := make([]string, len(filter.Status))
+		for i, status := range filter.Status {
+			statuses[i] = string(status)
+		}
+		conditions = append(conditions, "t.status = ANY("+args.add(pq.Array(statuses))+")")
+	}
+
+	if len(filter.Priority) > 0 {
+		priorities := make([]string, len(filter.Priority))
+		for i, priority := range filter.Priority {
+			priorities[i] = string(priority)
+		}
+		conditions = append(conditions, "t.priority = ANY("+args.add(pq.Array(priorities))+")")
+	}
+
+	if filter.CategoryID != nil && *filter.CategoryID != "" {
+		categoryArg := args.add(*filter.CategoryID)
+		if filter.IncludeSubcategories != nil && *filter.IncludeSubcategories {
+			conditions = append(conditions, fmt.Sprintf(`t.category_id IN (
+				WITH RECURSIVE filter_tree AS (
+					SELECT id, 0 AS depth FROM categories WHERE id = %[1]s AND user_id = %[2]s
+					UNION ALL
+					SELECT c.id, ft.depth + 1 FROM categories c
+					JOIN filter_tree ft ON c.parent_id = ft.id
+					WHERE ft.depth < 100
+				)
+				SELECT id FROM filter_tree)`, categoryArg, userArg))
+		} else {
+			conditions = append(conditions, "t.category_id = "+categoryArg)
+		}
+	}
+
+	for _, tag := range filter.Tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM task_tags tt JOIN tags tg ON tg.id = tt.tag_id
+			WHERE tt.task_id = t.id AND lower(tg.name) = lower(`+args.add(tag)+`))`)
+	}
+
+	if filter.DueAfter != nil && *filter.DueAfter != "" {
+		dueAfter, err := filterBound(*filter.DueAfter, args)
+		if err != nil {
+			return "", apperr.Invalid("dueAfter", "invalid dueAfter: use YYYY-MM-DD or an RFC 3339 timestamp")
+		}
+		conditions = append(conditions, "t.due_date >= "+dueAfter)
+	}
+
+	if filter.DueBefore != nil && *filter.DueBefore != "" {
+		dueBefore, err := filterBound(*filter.DueBefore, args)
+		if err != nil {
+			return "", apperr.Invalid("dueBefore", "invalid dueBefore: use YYYY-MM-DD or an RFC 3339 timestamp")
+		}
+		conditions = append(conditions, "t.due_date < "+dueBefore)
+	}
+
+	// Days are taken in the user's timezone, so a day is 23 or 25 hours long
+	// when the clocks change
+	if filter.DueOn != nil && *filter.DueOn != "" {
+		if !isDay(*filter.DueOn) {
+			return "", apperr.Invalid("dueOn", "invalid dueOn %q: use YYYY-MM-DD", *filter.DueOn)
+		}
+		conditions = append(conditions, dueDaySQL+" = "+args.add(*filter.DueOn)+"::date")
+	}
+
+	if filter.DueIn != nil {
+		first, last, err := dueRangeSQL(*filter.DueIn)
+		if err != nil {
+			return "", err
+		}
+		conditions = append(conditions, dueDaySQL+" BETWEEN "+first+" AND "+last)
+	}
+
+	if filter.HasDueDate != nil {
+		if *filter.HasDueDate {
+			conditions = append(conditions, "t.due_date IS NOT NULL")
+		} else {
+			conditions = append(conditions, "t.due_date IS NULL")
+		}
+	}
+
+	if filter.Overdue != nil {
+		if *filter.Overdue {
+			conditions = append(conditions, overdueSQL("NOW()"))
+		} else {
+			conditions = append(conditions, "NOT "+overdueSQL("NOW()"))
+		}
+	}
+
+	if filter.Search != nil && strings.TrimSpace(*filter.Search) != "" {
+		pattern := "%" + escapeLike(strings.TrimSpace(*filter.Search)) + "%"
+		arg := args.add(pattern)
+		conditions = append(conditions, fmt.Sprintf("(t.title ILIKE %[1]s OR t.description ILIKE %[1]s)", arg))
+	}
+
+	return strings.Join(conditions, " AND "), nil
+}
+
+// CheckTaskFilter returns the validation error buildTaskFilter would raise for
+// filter, for callers that must reject it before they start a response
+func CheckTaskFilter(filter *models.TaskFilter) error {
+	_, err := buildTaskFilter(filter, "", &sqlArgs{})
+	return err
+}
+
+// dueDaySQL is the day a task over tasks aliased t is due on in its owner's
+// timezone, NULL without a due date
+const dueDaySQL = `(CASE WHEN t.all_day THEN t.due_on
+	ELSE (t.due_date AT TIME ZONE ` + userTimezoneSQL + `)::date END)`
+
+// todaySQL is today in the timezone of the owner of tasks aliased t
+const todaySQL = `(NOW() AT TIME ZONE ` + userTimezoneSQL + `)::date`
+
+// weekStartSQL is the first day of the owner's current week, following their
+// start of week preference
+const weekStartSQL = `(` + todaySQL + ` - (EXTRACT(DOW FROM ` + todaySQL + `)::int + 8 - (
+	SELECT array_position(ARRAY['SUNDAY', 'MONDAY', 'TUESDAY', 'WEDNESDAY', 'THURSDAY', 'FRIDAY', 'SATURDAY'],
+		ws.start_of_week::text) FROM users ws WHERE ws.id = t.user_id)) % 7)`
+
+// dueRangeSQL returns the first and last day of a due range, both included
+func dueRangeSQL(dueIn models.DueRange) (string, string, error) {
+	switch dueIn {
+	case models.DueRangeToday:
+		return todaySQL, todaySQL, nil
+	case models.DueRangeTomorrow:
+		return todaySQL + " + 1", todaySQL + " + 1", nil
+	case models.DueRangeThisWeek:
+		return weekStartSQL, weekStartSQL + " + 6", nil
+	case models.DueRangeNext7Days:
+		return todaySQL, todaySQL + " + 6", nil
+	}
+	return "", "", apperr.Invalid("dueIn", "invalid dueIn %q", dueIn)
+}
+
+// filterBound turns a dueAfter or dueBefore value into a timestamptz
+// expression. A YYYY-MM-DD day means its midnight in the user's timezone.
+func filterBound(value string, args *sqlArgs) (string, error) {
+	if isDay(value) {
+		return fmt.Sprintf("(%s::date::timestamp AT TIME ZONE %s)", args.add(value), userTimezoneSQL), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return "", err
+	}
+	return args.add(t), nil
+}
+
+// escapeLike escapes the LIKE wildcards in s
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// taskOrderBy builds the ORDER BY list for sort, newest first without one.
+// Tasks without a due date sort last by due date in either direction, and
+// ties fall back to the newest task first.
+func taskOrderBy(sort *models.TaskSort) string {
+	if sort == nil {
+		return "t.created_at DESC"
+	}
+
+	direction := "ASC"
+	if sort.Direction != nil && *sort.Direction == models.SortDirectionDesc {
+		direction = "DESC"
+	}
+
+	var column string
+	switch sort.Field {
+	case models.TaskSortFieldDueDate:
+		column = "t.due_date " + direction + " NULLS LAST"
+	case models.TaskSortFieldPriority:
+		column = "CASE t.priority WHEN 'HIGH' THEN 3 WHEN 'MEDIUM' THEN 2 ELSE 1 END " + direction
+	case models.TaskSortFieldTitle:
+		column = "lower(t.title) " + direction
+	default:
+		return "t.created_at " + direction
+	}
+	return column + ", t.created_at DESC"
+}
+
+// GetTasks retrieves the tasks of a specific user matching filter in the
+// order given by sort, newest first without one
+func (db *DB) GetTasks(userID string, filter *models.TaskFilter, sort *models.TaskSort) ([]models.Task, error) {
+	args := &sqlArgs{}
+	where, err := buildTaskFilter(filter, userID, args)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + taskColumns + ` FROM tasks t WHERE ` + where + ` ORDER BY ` + taskOrderBy(sort)
+
+	return queryTasks(db, query, args.values...)
+}
+
+// StreamTasks calls fn for each task of a specific user matching filter
+// without holding the whole result set in memory. Iteration stops at the
+// first error returned by fn.
+func (db *DB) StreamTasks(userID string, filter *models.TaskFilter, fn func(models.Task) error) error {
+	args := &sqlArgs{}
+	where, err := buildTaskFilter(filter, userID, args)
+	if err != nil {
+		return err
+	}
+
+	query := `SELECT ` + taskColumns + ` FROM tasks t WHERE ` + where + ` ORDER BY t.created_at`
+
+	rows, err := db.Query(query, args.values...)
+	if err != nil {
+		return fmt.Errorf("failed to query tasks: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan task: %w", err)
+		}
+		if err := fn(task); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate tasks: %w", err)
+	}
+
+	return nil
+}
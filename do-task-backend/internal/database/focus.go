@@ -0,0 +1,136 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/apperr"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+)
+
+// focusSessionColumns is the select list used for all focus session queries
+const focusSessionColumns = `f.id, f.task_id, f.focus_minutes, f.break_minutes, f.started_at,
+	f.ends_at, f.break_ends_at, f.status, f.interrupted, f.ended_at`
+
+// scanFocusSession scans a row selected with focusSessionColumns
+func scanFocusSession(row rowScanner) (models.FocusSession, error) {
+	var session models.FocusSession
+	err := row.Scan(
+		&session.ID,
+		&session.TaskID,
+		&session.FocusMinutes,
+		&session.BreakMinutes,
+		&session.StartedAt,
+		&session.EndsAt,
+		&session.BreakEndsAt,
+		&session.Status,
+		&session.Interrupted,
+		&session.EndedAt,
+	)
+	return session, err
+}
+
+// finishExpiredFocusSessions completes a user's active session once its break
+// is over, for when nobody was there to complete it
+func finishExpiredFocusSessions(q querier, userID string) error {
+	_, err := q.Exec(`
+		UPDATE focus_sessions SET status = 'COMPLETED', ended_at = break_ends_at
+		WHERE user_id = $1 AND status = 'ACTIVE' AND break_ends_at <= NOW()`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to finish focus sessions: %w", err)
+	}
+	return nil
+}
+
+// GetCurrentFocusSession returns the user's active focus session, or nil when
+// there is none
+func (db *DB) GetCurrentFocusSession(userID string) (*models.FocusSession, error) {
+	if err := finishExpiredFocusSessions(db, userID); err != nil {
+		return nil, err
+	}
+
+	session, err := scanFocusSession(db.QueryRow(`SELECT `+focusSessionColumns+` FROM focus_sessions f
+		WHERE f.user_id = $1 AND f.status = 'ACTIVE'`, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get focus session: %w", err)
+	}
+	return &session, nil
+}
+
+// StartFocusSession starts a focus session on one of a user's tasks. A user
+// has one active session at a time.
+func (db *DB) StartFocusSession(input models.StartFocusSessionInput, userID string) (models.FocusSession, error) {
+	focusMinutes, breakMinutes := models.DefaultFocusMinutes, models.DefaultBreakMinutes
+	if input.FocusMinutes != nil {
+		focusMinutes = *input.FocusMinutes
+	}
+	if input.BreakMinutes != nil {
+		breakMinutes = *input.BreakMinutes
+	}
+	if focusMinutes < 1 || focusMinutes > models.MaxFocusMinutes {
+		return models.FocusSession{}, apperr.Invalid("focusMinutes", "focusMinutes must be between 1 and %d", models.MaxFocusMinutes)
+	}
+	if breakMinutes < 0 || breakMinutes > models.MaxBreakMinutes {
+		return models.FocusSession{}, apperr.Invalid("breakMinutes", "breakMinutes must be between 0 and %d", models.MaxBreakMinutes)
+	}
+
+	var session models.FocusSession
+	err := db.withTx(func(tx *sql.Tx) error {
+		if err := finishExpiredFocusSessions(tx, userID); err != nil {
+			return err
+		}
+
+		var err error
+		session, err = scanFocusSession(tx.QueryRow(`
+			INSERT INTO focus_sessions AS f (task_id, user_id, focus_minutes, break_minutes, started_at, ends_at, break_ends_at)
+			SELECT t.id, t.user_id, $3, $4, NOW(),
+				NOW() + $3::integer * INTERVAL '1 minute',
+				NOW() + ($3::integer + $4::integer) * INTERVAL '1 minute'
+			FROM tasks t
+			WHERE t.id = $1 AND t.user_id = $2 AND t.deleted_at IS NULL
+			RETURNING `+focusSessionColumns, input.TaskID, userID, focusMinutes, breakMinutes))
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return apperr.NotFound("task")
+			}
+			if isUniqueViolation(err) {
+				return apperr.Conflict("a focus session is already running")
+			}
+			return fmt.Errorf("failed to start focus session: %w", err)
+		}
+		return nil
+	})
+	return session, err
+}
+
+// endFocusSession moves one of a user's active sessions to status. Ending it
+// before the focus time is up marks it interrupted.
+func (db *DB) endFocusSession(id string, userID string, status models.FocusSessionStatus) (models.FocusSession, error) {
+	session, err := scanFocusSession(db.QueryRow(`
+		UPDATE focus_sessions f SET
+			status = $3,
+			ended_at = LEAST(NOW(), f.break_ends_at),
+			interrupted = NOW() < f.ends_at
+		WHERE f.id = $1 AND f.user_id = $2 AND f.status = 'ACTIVE'
+		RETURNING `+focusSessionColumns, id, userID, status))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.FocusSession{}, apperr.New(apperr.CodeNotFound, "no active focus session found")
+		}
+		return models.FocusSession{}, fmt.Errorf("failed to end focus session: %w", err)
+	}
+	return session, nil
+}
+
+// CompleteFocusSession completes one of a user's active focus sessions
+func (db *DB) CompleteFocusSession(id string, userID string) (models.FocusSession, error) {
+	return db.endFocusSession(id, userID, models.FocusSessionStatusCompleted)
+}
+
+// AbandonFocusSession gives up one of a user's active focus sessions
+func (db *DB) AbandonFocusSession(id string, userID string) (models.FocusSession, error) {
+	return db.endFocusSession(id, userID, models.FocusSessionStatusAbandoned)
+}
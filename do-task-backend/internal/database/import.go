@@ -0,0 +1,66 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+	"github.com/lib/pq"
+)
+
+// ErrDuplicateImport is returned when a task with the same import key already exists
+var ErrDuplicateImport = errors.New("task was already imported")
+
+// ImportTasks creates a batch of tasks in one transaction. The returned slice
+// holds one entry per input: nil on success, ErrDuplicateImport when the row
+// was imported before, or the error that rejected it.
+func (db *DB) ImportTasks(inputs []models.CreateTaskInput) ([]error, error) {
+	results := make([]error, len(inputs))
+
+	err := db.withTx(func(tx *sql.Tx) error {
+		for i, input := range inputs {
+			itemErr, err := withSavepoint(tx, func() error {
+				_, err := createTask(tx, input)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+			results[i] = itemErr
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// GetImportKeys returns which of keys have already been used by a specific user
+func (db *DB) GetImportKeys(userID string, keys []string) (map[string]bool, error) {
+	rows, err := db.Query(`
+		SELECT import_key FROM tasks WHERE user_id = $1 AND import_key = ANY($2)`,
+		userID, pq.Array(keys))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query import keys: %w", err)
+	}
+	defer rows.Close()
+
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("failed to scan import key: %w", err)
+		}
+		existing[key] = true
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate import keys: %w", err)
+	}
+
+	return existing, nil
+}
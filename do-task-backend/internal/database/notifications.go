@@ -0,0 +1,317 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/apperr"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+	"github.com/lib/pq"
+)
+
+// uuidPattern matches the textual form of a UUID
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// notificationColumns is the select list used for all notification queries
+const notificationColumns = `n.id, n.type, n.title, n.body, n.task_id, n.read_at, n.created_at`
+
+// scanNotification scans a row selected with notificationColumns
+func scanNotification(row rowScanner) (models.Notification, error) {
+	var notification models.Notification
+	err := row.Scan(
+		&notification.ID,
+		&notification.Type,
+		&notification.Title,
+		&notification.Body,
+		&notification.TaskID,
+		&notification.ReadAt,
+		&notification.CreatedAt,
+	)
+	return notification, err
+}
+
+// CreateNotification stores an in-app notification. It returns nil without
+// storing anything when the user switched the type off, or when OncePerTask
+// is set and an unread notification of the type exists for the task.
+func (db *DB) CreateNotification(input models.NewNotification) (*models.Notification, error) {
+	return createNotification(db, input)
+}
+
+// createNotification is CreateNotification using q
+func createNotification(q querier, input models.NewNotification) (*models.Notification, error) {
+	query := `
+		WITH n AS (
+			INSERT INTO notifications (user_id, type, title, body, task_id)
+			SELECT $1::uuid, $2::text, $3::text, $4::text, $5::uuid
+			WHERE NOT EXISTS (
+				SELECT 1 FROM notification_preferences
+				WHERE user_id = $1::uuid AND type = $2::text AND NOT enabled
+			) AND NOT ($6::boolean AND EXISTS (
+				SELECT 1 FROM notifications
+				WHERE user_id = $1::uuid AND type = $2::text AND task_id = $5::uuid AND read_at IS NULL
+			))
+			RETURNING *
+		)
+		SELECT ` + notificationColumns + ` FROM n`
+
+	notification, err := scanNotification(q.QueryRow(query,
+		input.UserID, input.Type, input.Title, input.Body, input.TaskID, input.OncePerTask))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to create notification: %w", err)
+	}
+
+	return &notification, nil
+}
+
+// GetNotifications returns a page of a user's notifications, newest first.
+// after is the endCursor of the previous page.
+func (db *DB) GetNotifications(userID string, first int, after *string, unreadOnly bool) (models.NotificationConnection, error) {
+	var args sqlArgs
+	conditions := []string{"n.user_id = " + args.add(userID)}
+	if unreadOnly {
+		conditions = append(conditions, "n.read_at IS NULL")
+	}
+	if after != nil && *after != "" {
+		createdAt, id, err := decodeNotificationCursor(*after)
+		if err != nil {
+			return models.NotificationConnection{}, err
+		}
+		conditions = append(conditions, fmt.Sprintf("(n.created_at, n.id) < (%s, %s::uuid)", args.add(createdAt), args.add(id)))
+	}
+
+	query := `SELECT ` + notificationColumns + ` FROM notifications n
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY n.created_at DESC, n.id DESC
+		LIMIT ` + args.add(first+1)
+
+	rows, err := db.Query(query, args.values...)
+	if err != nil {
+		return models.NotificationConnection{}, fmt.Errorf("failed to query notifications: %w", err)
+	}
+	defer rows.Close()
+
+	connection := models.NotificationConnection{Nodes: []*models.Notification{}}
+	for rows.Next() {
+		notification, err := scanNotification(rows)
+		if err != nil {
+			return models.NotificationConnection{}, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		connection.Nodes = append(connection.Nodes, &notification)
+	}
+
+	if err := rows.Err(); err != nil {
+		return models.NotificationConnection{}, fmt.Errorf("failed to iterate notifications: %w", err)
+	}
+
+	// The extra row only tells us whether another page exists
+	if len(connection.Nodes) > first {
+		connection.Nodes = connection.Nodes[:first]
+		connection.HasNextPage = true
+	}
+	if len(connection.Nodes) > 0 {
+		last := connection.Nodes[len(connection.Nodes)-1]
+		cursor := encodeNotificationCursor(last.CreatedAt, last.ID)
+		connection.EndCursor = &cursor
+	}
+
+	return connection, nil
+}
+
+func encodeNotificationCursor(createdAt time.Time, id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(createdAt.UTC().Format(time.RFC3339Nano) + "|" + id))
+}
+
+func decodeNotificationCursor(cursor string) (time.Time, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err == nil {
+		if createdAt, id, ok := strings.Cut(string(raw), "|"); ok && uuidPattern.MatchString(id) {
+			if t, err := time.Parse(time.RFC3339Nano, createdAt); err == nil {
+				return t, id, nil
+			}
+		}
+	}
+	return time.Time{}, "", apperr.Invalid("after", "invalid cursor")
+}
+
+// CountUnreadNotifications returns how many unread notifications a user has
+func (db *DB) CountUnreadNotifications(userID string) (int, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL`, userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count notifications: %w", err)
+	}
+	return count, nil
+}
+
+// MarkNotificationRead marks one notification of a specific user as read
+func (db *DB) MarkNotificationRead(id string, userID string) (models.Notification, error) {
+	query := `
+		UPDATE notifications n SET read_at = COALESCE(n.read_at, NOW())
+		WHERE n.id = $1 AND n.user_id = $2
+		RETURNING ` + notificationColumns
+
+	notification, err := scanNotification(db.QueryRow(query, id, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Notification{}, apperr.NotFound("notification")
+		}
+		return models.Notification{}, fmt.Errorf("failed to mark notification read: %w", err)
+	}
+
+	return notification, nil
+}
+
+// MarkAllNotificationsRead marks every unread notification of a user as read
+// and returns how many there were
+func (db *DB) MarkAllNotificationsRead(userID string) (int, error) {
+	result, err := db.Exec(`UPDATE notifications SET read_at = NOW() WHERE user_id = $1 AND read_at IS NULL`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications read: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return int(rowsAffected), nil
+}
+
+// MarkTaskNotificationsRead marks the unread notifications of the given types
+// about a task as read, e.g. its overdue alert once it is completed
+func (db *DB) MarkTaskNotificationsRead(taskID string, userID string, types ...models.NotificationType) error {
+	names := make([]string, len(types))
+	for i, notificationType := range types {
+		names[i] = string(notificationType)
+	}
+
+	_, err := db.Exec(`
+		UPDATE notifications SET read_at = NOW()
+		WHERE task_id = $1 AND user_id = $2 AND read_at IS NULL AND type = ANY($3)`,
+		taskID, userID, pq.Array(names))
+	if err != nil {
+		return fmt.Errorf("failed to mark notifications read: %w", err)
+	}
+	return nil
+}
+
+// GetNotificationPreferences returns a user's setting for every notification type
+func (db *DB) GetNotificationPreferences(userID string) ([]models.NotificationPreference, error) {
+	rows, err := db.Query(`SELECT type, enabled FROM notification_preferences WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notification preferences: %w", err)
+	}
+	defer rows.Close()
+
+	disabled := make(map[models.NotificationType]bool)
+	for rows.Next() {
+		var notificationType models.NotificationType
+		var enabled bool
+		if err := rows.Scan(&notificationType, &enabled); err != nil {
+			return nil, fmt.Errorf("failed to scan notification preference: %w", err)
+		}
+		disabled[notificationType] = !enabled
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate notification preferences: %w", err)
+	}
+
+	preferences := make([]models.NotificationPreference, len(models.NotificationTypes))
+	for i, notificationType := range models.NotificationTypes {
+		preferences[i] = models.NotificationPreference{Type: notificationType, Enabled: !disabled[notificationType]}
+	}
+	return preferences, nil
+}
+
+// SetNotificationPreference switches a type of notification on or off for a user
+func (db *DB) SetNotificationPreference(userID string, notificationType models.NotificationType, enabled bool) (models.NotificationPreference, error) {
+	_, err := db.Exec(`
+		INSERT INTO notification_preferences (user_id, type, enabled)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, type) DO UPDATE SET enabled = EXCLUDED.enabled, updated_at = NOW()`,
+		userID, notificationType, enabled)
+	if err != nil {
+		return models.NotificationPreference{}, fmt.Errorf("failed to save notification preference: %w", err)
+	}
+
+	return models.NotificationPreference{Type: notificationType, Enabled: enabled}, nil
+}
+
+// NotifyOverdueTasks raises a TASK_OVERDUE notification for up to limit open
+// tasks whose due date has passed, once per due date. Tasks locked by another
+// replica are skipped. It returns how many tasks were claimed.
+func (db *DB) NotifyOverdueTasks(limit int) (int, error) {
+	var claimed int
+	err := db.withTx(func(tx *sql.Tx) error {
+		rows, err := tx.Query(`
+			WITH due AS (
+				SELECT t.id, t.due_date FROM tasks t
+				LEFT JOIN overdue_notices o ON o.task_id = t.id
+				WHERE t.deleted_at IS NULL AND `+overdueSQL("NOW()")+`
+					AND (o.task_id IS NULL OR o.due_date <> t.due_date)
+				ORDER BY t.due_date
+				LIMIT $1
+				FOR UPDATE OF t SKIP LOCKED
+			)
+			INSERT INTO overdue_notices (task_id, due_date)
+			SELECT id, due_date FROM due
+			ON CONFLICT (task_id) DO UPDATE SET due_date = EXCLUDED.due_date, created_at = NOW()
+			RETURNING task_id`, limit)
+		if err != nil {
+			return fmt.Errorf("failed to claim overdue tasks: %w", err)
+		}
+		var ids []string
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan overdue task: %w", err)
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to iterate overdue tasks: %w", err)
+		}
+
+		tasks, err := queryTasks(tx, `SELECT `+taskColumns+` FROM tasks t WHERE t.id = ANY($1)`, pq.Array(ids))
+		if err != nil {
+			return err
+		}
+
+		users := map[string]models.User{}
+		for _, task := range tasks {
+			user, ok := users[task.UserID]
+			if !ok {
+				user, err = scanUser(tx.QueryRow(`SELECT `+userColumns+` FROM users u WHERE u.id = $1`, task.UserID))
+				if err != nil {
+					return fmt.Errorf("failed to get task owner: %w", err)
+				}
+				users[task.UserID] = user
+			}
+
+			_, err := createNotification(tx, models.NewNotification{
+				UserID:      task.UserID,
+				Type:        models.NotificationTypeTaskOverdue,
+				Title:       task.Title,
+				Body:        "Overdue since " + user.Preferences.FormatDue(task),
+				TaskID:      &task.ID,
+				OncePerTask: true,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		claimed = len(ids)
+		return nil
+	})
+	return claimed, err
+}
@@ -0,0 +1,98 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/apperr"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+)
+
+// UpdateUserPreferences changes the preferences set in input and returns the
+// updated user. Values are expected to be validated by the caller.
+func (db *DB) UpdateUserPreferences(id string, input models.UpdatePreferencesInput) (models.User, error) {
+	var args sqlArgs
+	setParts := []string{"updated_at = NOW()"}
+	set := func(column string, value interface{}) {
+		setParts = append(setParts, column+" = "+args.add(value))
+	}
+
+	if input.Theme != nil {
+		set("theme", *input.Theme)
+	}
+	if input.Language != nil {
+		set("language", *input.Language)
+	}
+	if input.Timezone != nil {
+		set("timezone", *input.Timezone)
+	}
+	if input.DateFormat != nil {
+		set("date_format", *input.DateFormat)
+	}
+	if input.StartOfWeek != nil {
+		set("start_of_week", *input.StartOfWeek)
+	}
+	if input.SessionTimeoutMinutes != nil {
+		set("session_timeout_minutes", *input.SessionTimeoutMinutes)
+	}
+
+	weeklyDigestChanged := false
+	if n := input.Notifications; n != nil {
+		if n.Email != nil {
+			set("email_notifications", *n.Email)
+		}
+		if n.Push != nil {
+			set("push_notifications", *n.Push)
+		}
+		if n.TaskReminders != nil {
+			set("task_reminders", *n.TaskReminders)
+		}
+		if n.WeeklyDigest != nil {
+			set("weekly_digest", *n.WeeklyDigest)
+			weeklyDigestChanged = true
+		}
+		if n.MarketingEmails != nil {
+			set("marketing_emails", *n.MarketingEmails)
+		}
+	}
+
+	// The digest job works out the next send time again in the new setting
+	if weeklyDigestChanged || input.Timezone != nil || input.StartOfWeek != nil {
+		setParts = append(setParts, "digest_next_at = NULL")
+	}
+
+	query := fmt.Sprintf(`
+		UPDATE users u SET %s
+		WHERE u.id = %s
+		RETURNING %s`,
+		strings.Join(setParts, ", "), args.add(id), userColumns)
+
+	var user models.User
+	err := db.withTx(func(tx *sql.Tx) error {
+		var err error
+		user, err = scanUser(tx.QueryRow(query, args.values...))
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return apperr.NotFound("user")
+			}
+			return fmt.Errorf("failed to update preferences: %w", err)
+		}
+
+		if input.Timezone != nil {
+			return moveAllDayTasks(tx, id)
+		}
+		return nil
+	})
+	return user, err
+}
+
+// GetUserPreferences returns a user's preferences
+func (db *DB) GetUserPreferences(id string) (models.UserPreferences, error) {
+	user, err := db.GetUserByID(id)
+	if err != nil {
+		return models.UserPreferences{}, err
+	}
+	return user.Preferences, nil
+}
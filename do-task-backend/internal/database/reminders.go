@@ -0,0 +1,318 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/apperr"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+	"github.com/lib/pq"
+)
+
+// maxReminderOffset is the earliest a reminder can fire before the due date, in minutes
+const maxReminderOffset = 366 * 24 * 60
+
+// reminderColumns is the select list used for all reminder queries
+const reminderColumns = `r.id, r.task_id, r.remind_at, r.offset_minutes, r.fire_at, r.channels,
+	r.status, r.sent_at, r.last_error, r.created_at`
+
+// scanReminder scans a row selected with reminderColumns
+func scanReminder(row rowScanner) (models.Reminder, error) {
+	var reminder models.Reminder
+	var channels []string
+	err := row.Scan(
+		&reminder.ID,
+		&reminder.TaskID,
+		&reminder.RemindAt,
+		&reminder.OffsetMinutes,
+		&reminder.FireAt,
+		pq.Array(&channels),
+		&reminder.Status,
+		&reminder.SentAt,
+		&reminder.LastError,
+		&reminder.CreatedAt,
+	)
+	reminder.Channels = toReminderChannels(channels)
+	return reminder, err
+}
+
+func toReminderChannels(channels []string) []models.ReminderChannel {
+	result := make([]models.ReminderChannel, len(channels))
+	for i, channel := range channels {
+		result[i] = models.ReminderChannel(channel)
+	}
+	return result
+}
+
+// normalizeReminderChannels validates and de-duplicates channels, defaulting to IN_APP
+func normalizeReminderChannels(channels []models.ReminderChannel) ([]string, error) {
+	if len(channels) == 0 {
+		return []string{string(models.ReminderChannelInApp)}, nil
+	}
+
+	seen := make(map[models.ReminderChannel]bool)
+	var result []string
+	for _, channel := range channels {
+		switch channel {
+		case models.ReminderChannelEmail, models.ReminderChannelWebhook, models.ReminderChannelInApp:
+		default:
+			return nil, apperr.Invalid("channels", "unknown reminder channel %q", channel)
+		}
+		if !seen[channel] {
+			seen[channel] = true
+			result = append(result, string(channel))
+		}
+	}
+	return result, nil
+}
+
+// GetReminders retrieves a user's reminders, optionally only those of one task
+func (db *DB) GetReminders(userID string, taskID *string) ([]models.Reminder, error) {
+	query := `SELECT ` + reminderColumns + ` FROM reminders r
+		WHERE r.user_id = $1 AND ($2::uuid IS NULL OR r.task_id = $2::uuid)
+		ORDER BY r.fire_at, r.created_at`
+
+	rows, err := db.Query(query, userID, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reminders: %w", err)
+	}
+	defer rows.Close()
+
+	var reminders []models.Reminder
+	for rows.Next() {
+		reminder, err := scanReminder(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan reminder: %w", err)
+		}
+		reminders = append(reminders, reminder)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate reminders: %w", err)
+	}
+
+	return reminders, nil
+}
+
+// CreateReminder adds a reminder to a task of a specific user
+func (db *DB) CreateReminder(input models.CreateReminderInput, userID string) (models.Reminder, error) {
+	if (input.RemindAt == nil) == (input.OffsetMinutes == nil) {
+		return models.Reminder{}, apperr.Invalid("", "a reminder needs either remindAt or offsetMinutes")
+	}
+
+	var remindAt *time.Time
+	if input.RemindAt != nil {
+		t, err := time.Parse(time.RFC3339, *input.RemindAt)
+		if err != nil {
+			return models.Reminder{}, apperr.Invalid("remindAt", "remindAt must be an RFC 3339 timestamp")
+		}
+		remindAt = &t
+	}
+	if input.OffsetMinutes != nil && (*input.OffsetMinutes < 0 || *input.OffsetMinutes > maxReminderOffset) {
+		return models.Reminder{}, apperr.Invalid("offsetMinutes", "offsetMinutes must be between 0 and %d", maxReminderOffset)
+	}
+
+	channels, err := normalizeReminderChannels(input.Channels)
+	if err != nil {
+		return models.Reminder{}, err
+	}
+
+	// Offsets count back from the due date, so the task needs one
+	if input.OffsetMinutes != nil {
+		var hasDueDate bool
+		err := db.QueryRow(`
+			SELECT due_date IS NOT NULL FROM tasks
+			WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`, input.TaskID, userID).Scan(&hasDueDate)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return models.Reminder{}, apperr.NotFound("task")
+			}
+			return models.Reminder{}, fmt.Errorf("failed to get task: %w", err)
+		}
+		if !hasDueDate {
+			return models.Reminder{}, apperr.Invalid("offsetMinutes", "offset reminders need a task with a due date")
+		}
+	}
+
+	// fire_at is derived from the task so offsets follow its due date
+	query := `
+		WITH t AS (
+			SELECT id, user_id,
+				COALESCE($3::timestamptz, due_date - $4::integer * INTERVAL '1 minute') AS fire_at
+			FROM tasks
+			WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+		), r AS (
+			INSERT INTO reminders (task_id, user_id, remind_at, offset_minutes, fire_at, next_attempt_at, channels)
+			SELECT t.id, t.user_id, $3, $4, t.fire_at, t.fire_at, $5::text[] FROM t
+			RETURNING *
+		)
+		SELECT ` + reminderColumns + ` FROM r`
+
+	reminder, err := scanReminder(db.QueryRow(query, input.TaskID, userID, remindAt, input.OffsetMinutes, pq.Array(channels)))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Reminder{}, apperr.NotFound("task")
+		}
+		return models.Reminder{}, fmt.Errorf("failed to create reminder: %w", err)
+	}
+
+	return reminder, nil
+}
+
+// DeleteReminder removes a reminder of a specific user
+func (db *DB) DeleteReminder(id string, userID string) error {
+	result, err := db.Exec(`DELETE FROM reminders WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete reminder: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return apperr.NotFound("reminder")
+	}
+
+	return nil
+}
+
+// rescheduleReminders moves a task's offset reminders after its due date
+// changed. Reminders that now lie in the future are armed again even if they
+// already fired for the old date.
+func rescheduleReminders(q querier, taskID string) error {
+	return rescheduleRemindersWhere(q, "r.task_id = $1", taskID)
+}
+
+// rescheduleRemindersWhere moves the offset reminders matching condition, an
+// expression over reminders r and their tasks t. Offset reminders of tasks
+// that no longer have a due date are removed.
+func rescheduleRemindersWhere(q querier, condition string, args ...interface{}) error {
+	_, err := q.Exec(`
+		DELETE FROM reminders r USING tasks t
+		WHERE t.id = r.task_id AND r.offset_minutes IS NOT NULL AND t.due_date IS NULL AND `+condition, args...)
+	if err != nil {
+		return fmt.Errorf("failed to remove reminders: %w", err)
+	}
+
+	_, err = q.Exec(`
+		UPDATE reminders r SET
+			fire_at = t.due_date - r.offset_minutes * INTERVAL '1 minute',
+			next_attempt_at = t.due_date - r.offset_minutes * INTERVAL '1 minute',
+			status = CASE WHEN t.due_date - r.offset_minutes * INTERVAL '1 minute' > NOW()
+				THEN 'PENDING' ELSE r.status END,
+			delivered_channels = CASE WHEN t.due_date - r.offset_minutes * INTERVAL '1 minute' > NOW()
+				THEN '{}' ELSE r.delivered_channels END,
+			attempts = CASE WHEN t.due_date - r.offset_minutes * INTERVAL '1 minute' > NOW()
+				THEN 0 ELSE r.attempts END
+		FROM tasks t
+		WHERE t.id = r.task_id AND r.offset_minutes IS NOT NULL AND `+condition, args...)
+	if err != nil {
+		return fmt.Errorf("failed to reschedule reminders: %w", err)
+	}
+	return nil
+}
+
+// ClaimDueReminders locks up to limit due reminders for sending. Rows locked
+// by another replica are skipped, and claimed rows are leased so nobody else
+// picks them up while they are being sent.
+func (db *DB) ClaimDueReminders(limit int, lease time.Duration) ([]models.DueReminder, error) {
+	query := `
+		UPDATE reminders r
+		SET attempts = r.attempts + 1,
+			next_attempt_at = NOW() + $2::double precision * INTERVAL '1 second'
+		FROM tasks t, users u
+		WHERE t.id = r.task_id AND u.id = r.user_id AND r.id IN (
+			SELECT id FROM reminders
+			WHERE status = 'PENDING' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + userColumns + `,
+			r.id, r.attempts, r.fire_at, r.channels, r.delivered_channels, r.task_id`
+
+	rows, err := db.Query(query, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim reminders: %w", err)
+	}
+	defer rows.Close()
+
+	var reminders []models.DueReminder
+	for rows.Next() {
+		var reminder models.DueReminder
+		var channels, delivered []string
+		user, err := scanUser(rows,
+			&reminder.ReminderID,
+			&reminder.Attempt,
+			&reminder.FireAt,
+			pq.Array(&channels),
+			pq.Array(&delivered),
+			&reminder.TaskID,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan reminder: %w", err)
+		}
+		reminder.User = user
+		reminder.Channels = toReminderChannels(channels)
+		reminder.Delivered = toReminderChannels(delivered)
+		reminders = append(reminders, reminder)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate reminders: %w", err)
+	}
+
+	// Tasks in the trash are left nil
+	for i := range reminders {
+		task, err := scanTask(db.QueryRow(`SELECT `+taskColumns+` FROM tasks t
+			WHERE t.id = $1 AND t.deleted_at IS NULL`, reminders[i].TaskID))
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get task: %w", err)
+		}
+		reminders[i].Task = &task
+	}
+
+	return reminders, nil
+}
+
+// RecordReminderAttempt stores the outcome of sending a claimed reminder.
+// A PENDING status with retryAt schedules another attempt.
+func (db *DB) RecordReminderAttempt(id string, status models.ReminderStatus, delivered []models.ReminderChannel, sendErr error, retryAt *time.Time) error {
+	var lastError *string
+	if sendErr != nil {
+		message := truncateError(sendErr)
+		lastError = &message
+	}
+
+	deliveredNames := make([]string, len(delivered))
+	for i, channel := range delivered {
+		deliveredNames[i] = string(channel)
+	}
+
+	_, err := db.Exec(`
+		UPDATE reminders SET
+			status = $2,
+			delivered_channels = $3,
+			last_error = $4,
+			next_attempt_at = COALESCE($5, next_attempt_at),
+			sent_at = CASE WHEN $2 = 'SENT' THEN NOW() ELSE sent_at END
+		WHERE id = $1`, id, status, pq.Array(deliveredNames), lastError, retryAt)
+	if err != nil {
+		return fmt.Errorf("failed to record reminder attempt: %w", err)
+	}
+	return nil
+}
+
+// EmitReminderEvent queues a reminder.due event for the user's webhooks
+func (db *DB) EmitReminderEvent(reminder models.DueReminder) error {
+	return emitEvent(db, reminder.User.ID, models.EventReminderDue, map[string]interface{}{
+		"reminderId": reminder.ReminderID,
+		"fireAt":     reminder.FireAt,
+		"task":       reminder.Task,
+	})
+}
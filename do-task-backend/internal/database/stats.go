@@ -0,0 +1,238 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+)
+
+// Bits of GROUPING(t.status, t.priority, c.id) for each grouping set of the
+// task counts; a set bit marks a column that is not grouped
+const (
+	groupedByStatus   = 3 // (t.status)
+	groupedByPriority = 5 // (t.priority)
+	groupedByCategory = 6 // (c.id, c.name)
+	groupedByNothing  = 7 // ()
+)
+
+// GetTaskStats aggregates a user's tasks. from and to are the first and last
+// day of the range, in the user's timezone; completions are counted per day or
+// per week starting on the user's first day of the week.
+func (db *DB) GetTaskStats(userID string, from, to time.Time, groupBy models.StatsGroupBy, prefs models.UserPreferences) (models.TaskStats, error) {
+	stats := models.TaskStats{
+		From:            from.Format(dayLayout),
+		To:              to.Format(dayLayout),
+		ByStatus:        []models.StatusCount{},
+		ByPriority:      []models.PriorityCount{},
+		ByCategory:      []models.CategoryCount{},
+		CompletedSeries: []models.StatsPoint{},
+		FocusSeries:     []models.FocusPoint{},
+	}
+	timezone := prefs.Location().String()
+
+	if err := db.taskCounts(userID, &stats); err != nil {
+		return models.TaskStats{}, err
+	}
+	if err := db.completionStats(userID, timezone, groupBy, prefs.StartOfWeek, &stats); err != nil {
+		return models.TaskStats{}, err
+	}
+	if err := db.completionStreaks(userID, timezone, &stats); err != nil {
+		return models.TaskStats{}, err
+	}
+	if err := db.focusStats(userID, timezone, groupBy, prefs.StartOfWeek, &stats); err != nil {
+		return models.TaskStats{}, err
+	}
+
+	return stats, nil
+}
+
+// taskCounts fills in the totals and the counts by status, priority and
+// category in one pass over the user's tasks
+func (db *DB) taskCounts(userID string, stats *models.TaskStats) error {
+	rows, err := db.Query(`
+		SELECT GROUPING(t.status, t.priority, c.id), t.status, t.priority, c.id, c.name,
+			COUNT(*), COUNT(*) FILTER (WHERE `+overdueSQL("NOW()")+`)
+		FROM tasks t
+		LEFT JOIN categories c ON c.id = t.category_id
+		WHERE t.user_id = $1 AND t.deleted_at IS NULL
+		GROUP BY GROUPING SETS ((), (t.status), (t.priority), (c.id, c.name))
+		ORDER BY 1, 6 DESC, 5`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to count tasks: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var grouping, count, overdue int
+		var status, priority, categoryID, categoryName sql.NullString
+		if err := rows.Scan(&grouping, &status, &priority, &categoryID, &categoryName, &count, &overdue); err != nil {
+			return fmt.Errorf("failed to scan task count: %w", err)
+		}
+
+		switch grouping {
+		case groupedByNothing:
+			stats.Total, stats.Overdue = count, overdue
+		case groupedByStatus:
+			stats.ByStatus = append(stats.ByStatus, models.StatusCount{
+				Status: models.TaskStatus(status.String), Count: count, Overdue: overdue,
+			})
+		case groupedByPriority:
+			stats.ByPriority = append(stats.ByPriority, models.PriorityCount{
+				Priority: models.TaskPriority(priority.String), Count: count, Overdue: overdue,
+			})
+		case groupedByCategory:
+			if categoryID.Valid {
+				stats.ByCategory = append(stats.ByCategory, models.CategoryCount{
+					CategoryID: categoryID.String, Name: categoryName.String, Count: count, Overdue: overdue,
+				})
+			}
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate task counts: %w", err)
+	}
+	return nil
+}
+
+// completionStats fills in the completions within the range, their series and
+// the average cycle time
+func (db *DB) completionStats(userID, timezone string, groupBy models.StatsGroupBy, startOfWeek models.Weekday, stats *models.TaskStats) error {
+	args := &sqlArgs{}
+	user, from, to, tz := args.add(userID), args.add(stats.From), args.add(stats.To), args.add(timezone)
+	inRange := fmt.Sprintf("t.user_id = %s AND t.deleted_at IS NULL AND %s",
+		user, localDaysSQL("t.completed_at", from, to, tz))
+
+	err := db.QueryRow(`
+		SELECT COUNT(*), AVG(EXTRACT(EPOCH FROM t.completed_at - t.created_at)) / 3600
+		FROM tasks t
+		WHERE `+inRange, args.values...).Scan(&stats.Completed, &stats.AverageCycleTimeHours)
+	if err != nil {
+		return fmt.Errorf("failed to get completion stats: %w", err)
+	}
+
+	period := statsPeriodSQL(groupBy, startOfWeek, args)
+	rows, err := db.Query(`
+		WITH days AS (`+statsDaysSQL(from, to)+`), completions AS (
+			SELECT (t.completed_at AT TIME ZONE `+tz+`::text)::date AS day, COUNT(*) AS completed
+			FROM tasks t
+			WHERE `+inRange+`
+			GROUP BY 1
+		)
+		SELECT to_char(`+period+`, 'YYYY-MM-DD'), COALESCE(SUM(c.completed), 0)
+		FROM days d
+		LEFT JOIN completions c ON c.day = d.day
+		GROUP BY 1
+		ORDER BY 1`, args.values...)
+	if err != nil {
+		return fmt.Errorf("failed to get completion series: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var point models.StatsPoint
+		if err := rows.Scan(&point.Date, &point.Count); err != nil {
+			return fmt.Errorf("failed to scan completion series: %w", err)
+		}
+		stats.CompletedSeries = append(stats.CompletedSeries, point)
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate completion series: %w", err)
+	}
+	return nil
+}
+
+// focusStats fills in the focus sessions started within the range, per period.
+// Focus time stops at the end of the focus phase or when the session ended.
+func (db *DB) focusStats(userID, timezone string, groupBy models.StatsGroupBy, startOfWeek models.Weekday, stats *models.TaskStats) error {
+	if err := finishExpiredFocusSessions(db, userID); err != nil {
+		return err
+	}
+
+	args := &sqlArgs{}
+	user, from, to, tz := args.add(userID), args.add(stats.From), args.add(stats.To), args.add(timezone)
+	period := statsPeriodSQL(groupBy, startOfWeek, args)
+
+	rows, err := db.Query(`
+		WITH days AS (`+statsDaysSQL(from, to)+`), sessions AS (
+			SELECT (f.started_at AT TIME ZONE `+tz+`::text)::date AS day,
+				COUNT(*) AS sessions,
+				COUNT(*) FILTER (WHERE f.interrupted) AS interrupted,
+				SUM(EXTRACT(EPOCH FROM LEAST(f.ended_at, f.ends_at) - f.started_at)) / 60 AS minutes
+			FROM focus_sessions f
+			WHERE f.user_id = `+user+` AND f.status <> 'ACTIVE'
+				AND `+localDaysSQL("f.started_at", from, to, tz)+`
+			GROUP BY 1
+		)
+		SELECT to_char(`+period+`, 'YYYY-MM-DD'),
+			COALESCE(SUM(s.sessions), 0), COALESCE(SUM(s.interrupted), 0), COALESCE(ROUND(SUM(s.minutes)), 0)
+		FROM days d
+		LEFT JOIN sessions s ON s.day = d.day
+		GROUP BY 1
+		ORDER BY 1`, args.values...)
+	if err != nil {
+		return fmt.Errorf("failed to get focus series: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var point models.FocusPoint
+		if err := rows.Scan(&point.Date, &point.Sessions, &point.Interrupted, &point.FocusMinutes); err != nil {
+			return fmt.Errorf("failed to scan focus series: %w", err)
+		}
+		stats.FocusSeries = append(stats.FocusSeries, point)
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate focus series: %w", err)
+	}
+	return nil
+}
+
+// localDaysSQL is true when the timestamptz column falls between the from and
+// to days, both included, in timezone tz. The arguments are placeholders.
+func localDaysSQL(column, from, to, tz string) string {
+	return fmt.Sprintf(`%[1]s >= (%[2]s::date::timestamp AT TIME ZONE %[4]s::text)
+		AND %[1]s < ((%[3]s::date + 1)::timestamp AT TIME ZONE %[4]s::text)`, column, from, to, tz)
+}
+
+// statsDaysSQL selects every day from the from to the to placeholder as days d.
+// Every period of a series gets a point, including empty ones.
+func statsDaysSQL(from, to string) string {
+	return `SELECT d::date AS day FROM generate_series(` + from + `::date, ` + to + `::date, INTERVAL '1 day') d`
+}
+
+// statsPeriodSQL is the first day of the period containing d.day. Weeks start
+// on startOfWeek, so the first one may begin before the range.
+func statsPeriodSQL(groupBy models.StatsGroupBy, startOfWeek models.Weekday, args *sqlArgs) string {
+	if groupBy != models.StatsGroupByWeek {
+		return "d.day"
+	}
+	weekday := args.add(int(startOfWeek.TimeWeekday()))
+	return fmt.Sprintf("(d.day - (EXTRACT(DOW FROM d.day)::int - %s::int + 7) %% 7)", weekday)
+}
+
+// completionStreaks fills in the current and longest runs of consecutive days
+// with at least one completion. Days in a run minus their position in the
+// sorted list of days are the same date, which groups each run.
+func (db *DB) completionStreaks(userID, timezone string, stats *models.TaskStats) error {
+	err := db.QueryRow(`
+		WITH days AS (
+			SELECT DISTINCT (t.completed_at AT TIME ZONE $2::text)::date AS day
+			FROM tasks t
+			WHERE t.user_id = $1 AND t.deleted_at IS NULL AND t.completed_at IS NOT NULL
+		), runs AS (
+			SELECT MAX(day) AS last_day, COUNT(*) AS length
+			FROM (SELECT day, day - (ROW_NUMBER() OVER (ORDER BY day))::int AS run FROM days) numbered
+			GROUP BY run
+		)
+		SELECT COALESCE(MAX(length) FILTER (WHERE last_day >= $3::date - 1), 0), COALESCE(MAX(length), 0)
+		FROM runs`, userID, timezone, stats.To).Scan(&stats.CurrentStreak, &stats.LongestStreak)
+	if err != nil {
+		return fmt.Errorf("failed to get completion streaks: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,425 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/apperr"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+	"github.com/lib/pq"
+)
+
+// maxTagNameLength mirrors the size of the tags.name column
+const maxTagNameLength = 100
+
+var tagColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// tagColumns is the select list used for tag queries; usage_count counts the
+// live (not trashed) tasks currently carrying the tag
+const tagColumns = `tg.id, tg.name, tg.color, tg.description, tg.created_at, tg.updated_at,
+		(SELECT COUNT(*) FROM task_tags tt JOIN tasks t ON t.id = tt.task_id
+			WHERE tt.tag_id = tg.id AND t.deleted_at IS NULL)`
+
+// scanTag scans a row selected with tagColumns
+func scanTag(row rowScanner) (models.Tag, error) {
+	var tag models.Tag
+	err := row.Scan(
+		&tag.ID,
+		&tag.Name,
+		&tag.Color,
+		&tag.Description,
+		&tag.CreatedAt,
+		&tag.UpdatedAt,
+		&tag.UsageCount,
+	)
+	return tag, err
+}
+
+// normalizeTagName trims a tag name and checks it is usable
+func normalizeTagName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", apperr.Invalid("name", "tag name cannot be empty")
+	}
+	if utf8.RuneCountInString(name) > maxTagNameLength {
+		return "", apperr.Invalid("name", "tag name cannot be longer than %d characters", maxTagNameLength)
+	}
+	return name, nil
+}
+
+// normalizeTagColor validates a #RRGGBB colour; an empty string clears it
+func normalizeTagColor(color *string) (*string, error) {
+	if color == nil {
+		return nil, nil
+	}
+	c := strings.TrimSpace(*color)
+	if c == "" {
+		return nil, nil
+	}
+	if !tagColorPattern.MatchString(c) {
+		return nil, apperr.Invalid("color", "tag color must be a hex value like #1a2b3c")
+	}
+	c = strings.ToLower(c)
+	return &c, nil
+}
+
+// isUniqueViolation reports whether err is a Postgres unique violation
+func isUniqueViolation(err error) bool {
+	pqErr, ok := err.(*pq.Error)
+	return ok && pqErr.Code == "23505"
+}
+
+// setTaskTags replaces the tags of a task, creating any tag names the user
+// does not have yet. Names are matched case-insensitively so "Work" and
+// "work" resolve to the same tag. A blank or overlong name fails the whole
+// change.
+func setTaskTags(q querier, taskID string, userID string, names []string) error {
+	if _, err := q.Exec(`DELETE FROM task_tags WHERE task_id = $1`, taskID); err != nil {
+		return fmt.Errorf("failed to clear task tags: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	position := 0
+	for _, raw := range names {
+		name, err := normalizeTagName(raw)
+		if err != nil {
+			return apperr.Invalid("tags", "%v", err)
+		}
+		key := strings.ToLower(name)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		tagID, err := ensureTag(q, userID, name)
+		if err != nil {
+			return err
+		}
+
+		_, err = q.Exec(`
+			INSERT INTO task_tags (task_id, tag_id, position)
+			VALUES ($1, $2, $3)
+			ON CONFLICT DO NOTHING`, taskID, tagID, position)
+		if err != nil {
+			return fmt.Errorf("failed to tag task: %w", err)
+		}
+		position++
+	}
+
+	return nil
+}
+
+// ensureTag returns the ID of the user's tag with the given name, creating it if needed
+func ensureTag(q querier, userID string, name string) (string, error) {
+	var id string
+	err := q.QueryRow(`
+		INSERT INTO tags (user_id, name)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id, (lower(name))) DO UPDATE SET name = tags.name
+		RETURNING id`, userID, name).Scan(&id)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve tag %q: %w", name, err)
+	}
+	return id, nil
+}
+
+// touchTasksWithTags bumps updated_at on every task carrying one of the tags so
+// clients notice the change, and returns the IDs of those tasks
+func touchTasksWithTags(q querier, tagIDs []string) ([]string, error) {
+	rows, err := q.Query(`
+		UPDATE tasks SET updated_at = NOW()
+		WHERE id IN (SELECT task_id FROM task_tags WHERE tag_id = ANY($1))
+		RETURNING id`, pq.Array(tagIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update tagged tasks: %w", err)
+	}
+	defer rows.Close()
+
+	var taskIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan tagged task: %w", err)
+		}
+		taskIDs = append(taskIDs, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate tagged tasks: %w", err)
+	}
+	return taskIDs, nil
+}
+
+// emitTasksUpdated queues a task.updated event for each of the tasks as they
+// are after a change to their tags
+func emitTasksUpdated(q querier, taskIDs []string) error {
+	if len(taskIDs) == 0 {
+		return nil
+	}
+	tasks, err := queryTasks(q, `SELECT `+taskColumns+` FROM tasks t WHERE t.id = ANY($1)`, pq.Array(taskIDs))
+	if err != nil {
+		return err
+	}
+	for _, task := range tasks {
+		if err := emitTaskEvent(q, models.EventTaskUpdated, task); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getTag loads a single tag owned by userID
+func getTag(q querier, id string, userID string) (models.Tag, error) {
+	query := `SELECT ` + tagColumns + ` FROM tags tg WHERE tg.id = $1 AND tg.user_id = $2`
+
+	tag, err := scanTag(q.QueryRow(query, id, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Tag{}, apperr.NotFound("tag")
+		}
+		return models.Tag{}, fmt.Errorf("failed to get tag: %w", err)
+	}
+
+	return tag, nil
+}
+
+// GetTag retrieves a tag by ID for a specific user
+func (db *DB) GetTag(id string, userID string) (models.Tag, error) {
+	return getTag(db, id, userID)
+}
+
+// GetAllTags retrieves all tags for a specific user with their usage counts
+func (db *DB) GetAllTags(userID string) ([]models.Tag, error) {
+	query := `SELECT ` + tagColumns + ` FROM tags tg WHERE tg.user_id = $1 ORDER BY lower(tg.name)`
+
+	return queryTags(db, query, userID)
+}
+
+// GetTagsForTask retrieves the tags attached to a task in display order
+func (db *DB) GetTagsForTask(taskID string, userID string) ([]models.Tag, error) {
+	query := `SELECT ` + tagColumns + `
+		FROM tags tg JOIN task_tags tt ON tt.tag_id = tg.id
+		WHERE tt.task_id = $1 AND tg.user_id = $2
+		ORDER BY tt.position, tg.name`
+
+	return queryTags(db, query, taskID, userID)
+}
+
+func queryTags(q querier, query string, args ...interface{}) ([]models.Tag, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tags: %w", err)
+	}
+	defer rows.Close()
+
+	var tags []models.Tag
+	for rows.Next() {
+		tag, err := scanTag(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate tags: %w", err)
+	}
+
+	return tags, nil
+}
+
+// CreateTag creates a new tag for a specific user
+func (db *DB) CreateTag(input models.CreateTagInput, userID string) (models.Tag, error) {
+	name, err := normalizeTagName(input.Name)
+	if err != nil {
+		return models.Tag{}, err
+	}
+	color, err := normalizeTagColor(input.Color)
+	if err != nil {
+		return models.Tag{}, err
+	}
+
+	var id string
+	err = db.QueryRow(`
+		INSERT INTO tags (user_id, name, color, description)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id`, userID, name, color, input.Description).Scan(&id)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return models.Tag{}, apperr.Conflict("tag with this name already exists")
+		}
+		return models.Tag{}, fmt.Errorf("failed to create tag: %w", err)
+	}
+
+	return db.GetTag(id, userID)
+}
+
+// UpdateTag updates the colour and description of a tag for a specific user
+func (db *DB) UpdateTag(id string, input models.UpdateTagInput, userID string) (models.Tag, error) {
+	setParts := []string{"updated_at = NOW()"}
+	args := []interface{}{}
+	argIndex := 1
+
+	if input.Color != nil {
+		color, err := normalizeTagColor(input.Color)
+		if err != nil {
+			return models.Tag{}, err
+		}
+		setParts = append(setParts, fmt.Sprintf("color = $%d", argIndex))
+		args = append(args, color)
+		argIndex++
+	}
+	if input.Description != nil {
+		setParts = append(setParts, fmt.Sprintf("description = $%d", argIndex))
+		args = append(args, *input.Description)
+		argIndex++
+	}
+
+	args = append(args, id, userID)
+	query := fmt.Sprintf(`UPDATE tags SET %s WHERE id = $%d AND user_id = $%d`,
+		strings.Join(setParts, ", "), argIndex, argIndex+1)
+
+	result, err := db.Exec(query, args...)
+	if err != nil {
+		return models.Tag{}, fmt.Errorf("failed to update tag: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Tag{}, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return models.Tag{}, apperr.NotFound("tag")
+	}
+
+	return db.GetTag(id, userID)
+}
+
+// RenameTag renames a tag; every task carrying it picks up the new name
+func (db *DB) RenameTag(id string, name string, userID string) (models.Tag, error) {
+	name, err := normalizeTagName(name)
+	if err != nil {
+		return models.Tag{}, err
+	}
+
+	var tag models.Tag
+	err = db.withTx(func(tx *sql.Tx) error {
+		result, err := tx.Exec(`
+			UPDATE tags SET name = $1, updated_at = NOW()
+			WHERE id = $2 AND user_id = $3`, name, id, userID)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return apperr.Conflict("tag with this name already exists, merge the tags instead")
+			}
+			return fmt.Errorf("failed to rename tag: %w", err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		if rowsAffected == 0 {
+			return apperr.NotFound("tag")
+		}
+
+		taskIDs, err := touchTasksWithTags(tx, []string{id})
+		if err != nil {
+			return err
+		}
+		if err := emitTasksUpdated(tx, taskIDs); err != nil {
+			return err
+		}
+
+		tag, err = getTag(tx, id, userID)
+		return err
+	})
+
+	return tag, err
+}
+
+// MergeTags moves every task tagged with one of sourceIDs onto targetID and
+// deletes the source tags
+func (db *DB) MergeTags(sourceIDs []string, targetID string, userID string) (models.Tag, error) {
+	var sources []string
+	for _, id := range uniqueIDs(sourceIDs) {
+		if id != targetID {
+			sources = append(sources, id)
+		}
+	}
+	if len(sources) == 0 {
+		return models.Tag{}, apperr.Invalid("sourceIds", "at least one source tag different from the target is required")
+	}
+
+	var tag models.Tag
+	err := db.withTx(func(tx *sql.Tx) error {
+		if _, err := getTag(tx, targetID, userID); err != nil {
+			return err
+		}
+
+		var owned int
+		err := tx.QueryRow(`SELECT COUNT(*) FROM tags WHERE id = ANY($1) AND user_id = $2`,
+			pq.Array(sources), userID).Scan(&owned)
+		if err != nil {
+			return fmt.Errorf("failed to check source tags: %w", err)
+		}
+		if owned != len(sources) {
+			return apperr.NotFound("tag")
+		}
+
+		taskIDs, err := touchTasksWithTags(tx, sources)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO task_tags (task_id, tag_id, position)
+			SELECT task_id, $1, MIN(position) FROM task_tags
+			WHERE tag_id = ANY($2)
+			GROUP BY task_id
+			ON CONFLICT DO NOTHING`, targetID, pq.Array(sources))
+		if err != nil {
+			return fmt.Errorf("failed to merge tags: %w", err)
+		}
+
+		_, err = tx.Exec(`DELETE FROM tags WHERE id = ANY($1) AND user_id = $2`, pq.Array(sources), userID)
+		if err != nil {
+			return fmt.Errorf("failed to delete merged tags: %w", err)
+		}
+		if err := emitTasksUpdated(tx, taskIDs); err != nil {
+			return err
+		}
+
+		tag, err = getTag(tx, targetID, userID)
+		return err
+	})
+
+	return tag, err
+}
+
+// DeleteTag deletes a tag and removes it from every task
+func (db *DB) DeleteTag(id string, userID string) error {
+	return db.withTx(func(tx *sql.Tx) error {
+		taskIDs, err := touchTasksWithTags(tx, []string{id})
+		if err != nil {
+			return err
+		}
+
+		result, err := tx.Exec(`DELETE FROM tags WHERE id = $1 AND user_id = $2`, id, userID)
+		if err != nil {
+			return fmt.Errorf("failed to delete tag: %w", err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		if rowsAffected == 0 {
+			return apperr.NotFound("tag")
+		}
+
+		return emitTasksUpdated(tx, taskIDs)
+	})
+}
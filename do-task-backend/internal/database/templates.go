@@ -0,0 +1,502 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/apperr"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+)
+
+// Template limits
+const (
+	maxTemplateNameLength = 100
+	maxTemplateItems      = 50
+	maxTemplateOffsetDays = 3650
+	// maxEmailLength mirrors the size of the task_template_shares.email column
+	maxEmailLength = 255
+	// maxTaskTitleLength mirrors the size of the tasks.title column
+	maxTaskTitleLength = 500
+)
+
+// templateTimeLayout is the HH:MM time of day of timed template items
+const templateTimeLayout = "15:04"
+
+// templateColumns is the select list used for template queries; the owner's
+// name is only filled in for templates shared with user, a placeholder
+func templateColumns(user string) string {
+	return `tt.id, tt.user_id, tt.name, tt.items,
+		CASE WHEN tt.user_id <> ` + user + ` THEN (SELECT o.name FROM users o WHERE o.id = tt.user_id) END,
+		tt.created_at, tt.updated_at`
+}
+
+// templateVisibleSQL is true for templates user owns or that were shared with
+// their verified email
+func templateVisibleSQL(user string) string {
+	return `(tt.user_id = ` + user + ` OR EXISTS (
+		SELECT 1 FROM task_template_shares s JOIN users su ON lower(su.email) = s.email
+		WHERE s.template_id = tt.id AND su.id = ` + user + ` AND su.email_verified_at IS NOT NULL))`
+}
+
+// scanTaskTemplate scans a row selected with templateColumns
+func scanTaskTemplate(row rowScanner) (models.TaskTemplate, error) {
+	var template models.TaskTemplate
+	var items []byte
+	err := row.Scan(
+		&template.ID,
+		&template.UserID,
+		&template.Name,
+		&items,
+		&template.SharedBy,
+		&template.CreatedAt,
+		&template.UpdatedAt,
+	)
+	if err != nil {
+		return template, err
+	}
+
+	if err := json.Unmarshal(items, &template.Items); err != nil {
+		return template, fmt.Errorf("failed to decode template items: %w", err)
+	}
+	return template, nil
+}
+
+// ownsCategory reports whether categoryID is one of the user's categories
+func ownsCategory(q querier, categoryID string, userID string) (bool, error) {
+	var exists bool
+	err := q.QueryRow(`SELECT EXISTS (SELECT 1 FROM categories WHERE id = $1 AND user_id = $2)`,
+		categoryID, userID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check category: %w", err)
+	}
+	return exists, nil
+}
+
+// itemField names a field of the i-th template item for validation errors
+func itemField(i int, field string) string {
+	return fmt.Sprintf("items.%d.%s", i, field)
+}
+
+// checkTaskTemplate validates a template's name and items before they are
+// stored, filling in the default status and priority of the items
+func checkTaskTemplate(q querier, name string, items []models.TaskTemplateItem, userID string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", apperr.Invalid("name", "template name cannot be empty")
+	}
+	if utf8.RuneCountInString(name) > maxTemplateNameLength {
+		return "", apperr.Invalid("name", "template name cannot be longer than %d characters", maxTemplateNameLength)
+	}
+	if len(items) == 0 {
+		return "", apperr.Invalid("items", "a template needs at least one task")
+	}
+	if len(items) > maxTemplateItems {
+		return "", apperr.Invalid("items", "a template can have at most %d tasks", maxTemplateItems)
+	}
+
+	for i := range items {
+		item := &items[i]
+		item.Title = strings.TrimSpace(item.Title)
+		if item.Title == "" {
+			return "", apperr.Invalid(itemField(i, "title"), "task %d: title cannot be empty", i+1)
+		}
+		if utf8.RuneCountInString(item.Title) > maxTaskTitleLength {
+			return "", apperr.Invalid(itemField(i, "title"), "task %d: title cannot be longer than %d characters", i+1, maxTaskTitleLength)
+		}
+		if item.Status == "" {
+			item.Status = models.TaskStatusTodo
+		}
+		if item.Priority == "" {
+			item.Priority = models.TaskPriorityMedium
+		}
+		if item.EstimateMinutes != nil && *item.EstimateMinutes <= 0 {
+			return "", apperr.Invalid(itemField(i, "estimateMinutes"), "task %d: estimateMinutes must be positive", i+1)
+		}
+
+		for _, offset := range []struct {
+			field string
+			days  *int
+		}{
+			{"dueOffsetDays", item.DueOffsetDays},
+			{"startOffsetDays", item.StartOffsetDays},
+		} {
+			if offset.days != nil && (*offset.days < -maxTemplateOffsetDays || *offset.days > maxTemplateOffsetDays) {
+				return "", apperr.Invalid(itemField(i, offset.field), "task %d: %s must be between -%d and %d days", i+1, offset.field, maxTemplateOffsetDays, maxTemplateOffsetDays)
+			}
+		}
+		if item.DueOffsetDays != nil && item.StartOffsetDays != nil && *item.StartOffsetDays > *item.DueOffsetDays {
+			return "", apperr.Invalid(itemField(i, "startOffsetDays"), "task %d: start date must not be after the due date", i+1)
+		}
+		if item.Time != nil {
+			if _, err := time.Parse(templateTimeLayout, *item.Time); err != nil {
+				return "", apperr.Invalid(itemField(i, "time"), "task %d: invalid time %q: use HH:MM", i+1, *item.Time)
+			}
+		}
+
+		if item.CategoryID != nil && *item.CategoryID == "" {
+			item.CategoryID = nil
+		}
+		if item.CategoryID != nil {
+			owned, err := ownsCategory(q, *item.CategoryID, userID)
+			if err != nil {
+				return "", err
+			}
+			if !owned {
+				return "", apperr.Invalid(itemField(i, "categoryId"), "task %d: category not found", i+1)
+			}
+		}
+	}
+
+	return name, nil
+}
+
+// GetTaskTemplates retrieves the templates a user owns or that were shared
+// with them, by name
+func (db *DB) GetTaskTemplates(userID string) ([]models.TaskTemplate, error) {
+	rows, err := db.Query(`SELECT `+templateColumns("$1")+` FROM task_templates tt
+		WHERE `+templateVisibleSQL("$1")+` ORDER BY lower(tt.name), tt.created_at`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task templates: %w", err)
+	}
+	defer rows.Close()
+
+	templates := []models.TaskTemplate{}
+	for rows.Next() {
+		template, err := scanTaskTemplate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan task template: %w", err)
+		}
+		templates = append(templates, template)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate task templates: %w", err)
+	}
+	return templates, nil
+}
+
+// GetTaskTemplate retrieves a template the user owns or that was shared with them
+func (db *DB) GetTaskTemplate(id string, userID string) (models.TaskTemplate, error) {
+	template, err := scanTaskTemplate(db.QueryRow(`SELECT `+templateColumns("$2")+` FROM task_templates tt
+		WHERE tt.id = $1 AND `+templateVisibleSQL("$2"), id, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.TaskTemplate{}, apperr.NotFound("task template")
+		}
+		return models.TaskTemplate{}, fmt.Errorf("failed to get task template: %w", err)
+	}
+	return template, nil
+}
+
+// CreateTaskTemplate saves a template under a name unique to the user
+func (db *DB) CreateTaskTemplate(input models.TaskTemplateInput, userID string) (models.TaskTemplate, error) {
+	name, err := checkTaskTemplate(db, input.Name, input.Items, userID)
+	if err != nil {
+		return models.TaskTemplate{}, err
+	}
+	items, err := json.Marshal(input.Items)
+	if err != nil {
+		return models.TaskTemplate{}, fmt.Errorf("failed to encode template items: %w", err)
+	}
+
+	template, err := scanTaskTemplate(db.QueryRow(`
+		INSERT INTO task_templates AS tt (user_id, name, items)
+		VALUES ($1, $2, $3)
+		RETURNING `+templateColumns("$1"), userID, name, items))
+	if err != nil {
+		if isUniqueViolation(err) {
+			return models.TaskTemplate{}, apperr.Conflict("template with this name already exists")
+		}
+		return models.TaskTemplate{}, fmt.Errorf("failed to create task template: %w", err)
+	}
+	return template, nil
+}
+
+// UpdateTaskTemplate changes one of the user's own templates
+func (db *DB) UpdateTaskTemplate(id string, input models.UpdateTaskTemplateInput, userID string) (models.TaskTemplate, error) {
+	template, err := db.GetTaskTemplate(id, userID)
+	if err != nil {
+		return models.TaskTemplate{}, err
+	}
+	if template.UserID != userID {
+		return models.TaskTemplate{}, apperr.Forbidden("only the owner can change a shared template")
+	}
+
+	if input.Name != nil {
+		template.Name = *input.Name
+	}
+	if input.Items != nil {
+		template.Items = input.Items
+	}
+
+	name, err := checkTaskTemplate(db, template.Name, template.Items, userID)
+	if err != nil {
+		return models.TaskTemplate{}, err
+	}
+	items, err := json.Marshal(template.Items)
+	if err != nil {
+		return models.TaskTemplate{}, fmt.Errorf("failed to encode template items: %w", err)
+	}
+
+	template, err = scanTaskTemplate(db.QueryRow(`
+		UPDATE task_templates tt SET name = $3, items = $4, updated_at = NOW()
+		WHERE tt.id = $1 AND tt.user_id = $2
+		RETURNING `+templateColumns("$2"), id, userID, name, items))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.TaskTemplate{}, apperr.NotFound("task template")
+		}
+		if isUniqueViolation(err) {
+			return models.TaskTemplate{}, apperr.Conflict("template with this name already exists")
+		}
+		return models.TaskTemplate{}, fmt.Errorf("failed to update task template: %w", err)
+	}
+	return template, nil
+}
+
+// DeleteTaskTemplate removes one of the user's own templates, and so its shares
+func (db *DB) DeleteTaskTemplate(id string, userID string) error {
+	result, err := db.Exec(`DELETE FROM task_templates WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete task template: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return apperr.NotFound("task template")
+	}
+	return nil
+}
+
+// ShareTaskTemplate lets whoever verifies the given email create tasks from one
+// of the user's templates. The share is kept by address, so it works the same
+// whether or not the address has an account yet.
+func (db *DB) ShareTaskTemplate(id string, email string, userID string) error {
+	template, err := db.GetTaskTemplate(id, userID)
+	if err != nil {
+		return err
+	}
+	if template.UserID != userID {
+		return apperr.Forbidden("only the owner can share a template")
+	}
+
+	email = NormalizeEmail(email)
+	if utf8.RuneCountInString(email) > maxEmailLength {
+		return apperr.Invalid("email", "email cannot be longer than %d characters", maxEmailLength)
+	}
+
+	var ownEmail string
+	if err := db.QueryRow(`SELECT lower(email) FROM users WHERE id = $1`, userID).Scan(&ownEmail); err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if email == ownEmail {
+		return apperr.Invalid("email", "cannot share a template with yourself")
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO task_template_shares (template_id, email)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING`, id, email)
+	if err != nil {
+		return fmt.Errorf("failed to share task template: %w", err)
+	}
+	return nil
+}
+
+// UnshareTaskTemplate takes back access to one of the user's templates
+func (db *DB) UnshareTaskTemplate(id string, email string, userID string) error {
+	result, err := db.Exec(`
+		DELETE FROM task_template_shares s
+		USING task_templates tt
+		WHERE s.template_id = tt.id
+			AND tt.id = $1 AND tt.user_id = $2 AND s.email = $3::text`, id, userID, NormalizeEmail(email))
+	if err != nil {
+		return fmt.Errorf("failed to unshare task template: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return apperr.NotFound("template share")
+	}
+	return nil
+}
+
+// GetTaskTemplateShares lists the emails one of the user's templates is shared
+// with; it is empty for templates owned by someone else
+func (db *DB) GetTaskTemplateShares(id string, userID string) ([]string, error) {
+	rows, err := db.Query(`
+		SELECT s.email
+		FROM task_template_shares s
+		JOIN task_templates tt ON tt.id = s.template_id
+		WHERE tt.id = $1 AND tt.user_id = $2
+		ORDER BY s.created_at`, id, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template shares: %w", err)
+	}
+	defer rows.Close()
+
+	emails := []string{}
+	for rows.Next() {
+		var email string
+		if err := rows.Scan(&email); err != nil {
+			return nil, fmt.Errorf("failed to scan template share: %w", err)
+		}
+		emails = append(emails, email)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate template shares: %w", err)
+	}
+	return emails, nil
+}
+
+// CreateTasksFromTemplate creates every task of a template the user can see,
+// all or none. Item categories the user does not own, as in shared
+// templates, fall back to the user's default category.
+func (db *DB) CreateTasksFromTemplate(id string, overrides *models.TemplateOverrides, userID string) ([]models.Task, error) {
+	if overrides == nil {
+		overrides = &models.TemplateOverrides{}
+	}
+
+	template, err := db.GetTaskTemplate(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if overrides.Title != nil {
+		if len(template.Items) != 1 {
+			return nil, apperr.Invalid("overrides.title", "title can only be overridden for templates with a single task")
+		}
+		title := strings.TrimSpace(*overrides.Title)
+		if title == "" {
+			return nil, apperr.Invalid("overrides.title", "title cannot be empty")
+		}
+		if utf8.RuneCountInString(title) > maxTaskTitleLength {
+			return nil, apperr.Invalid("overrides.title", "title cannot be longer than %d characters", maxTaskTitleLength)
+		}
+		overrides.Title = &title
+	}
+
+	loc, err := userLocation(db, userID)
+	if err != nil {
+		return nil, err
+	}
+	anchor := time.Now().In(loc)
+	if overrides.Anchor != nil && *overrides.Anchor != "" {
+		if anchor, err = time.ParseInLocation(dayLayout, *overrides.Anchor, loc); err != nil {
+			return nil, apperr.Invalid("overrides.anchor", "invalid anchor %q: use YYYY-MM-DD", *overrides.Anchor)
+		}
+	}
+
+	if overrides.CategoryID != nil && *overrides.CategoryID != "" {
+		owned, err := ownsCategory(db, *overrides.CategoryID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if !owned {
+			return nil, apperr.NotFound("category")
+		}
+	}
+
+	inputs := make([]models.CreateTaskInput, len(template.Items))
+	for i, item := range template.Items {
+		input, err := templateTaskInput(item, anchor, loc)
+		if err != nil {
+			return nil, err
+		}
+		input.UserID = userID
+		input.Tags = append(input.Tags, overrides.Tags...)
+		if overrides.Title != nil {
+			input.Title = *overrides.Title
+		}
+		if overrides.Status != nil {
+			input.Status = *overrides.Status
+		}
+		if overrides.Priority != nil {
+			input.Priority = *overrides.Priority
+		}
+
+		switch {
+		case overrides.CategoryID != nil && *overrides.CategoryID != "":
+			input.CategoryID = *overrides.CategoryID
+		case item.CategoryID != nil:
+			owned, err := ownsCategory(db, *item.CategoryID, userID)
+			if err != nil {
+				return nil, err
+			}
+			if owned {
+				input.CategoryID = *item.CategoryID
+			}
+		}
+		if err := db.ApplyDefaultCategory(&input); err != nil {
+			return nil, err
+		}
+		inputs[i] = input
+	}
+
+	tasks := make([]models.Task, 0, len(inputs))
+	err = db.withTx(func(tx *sql.Tx) error {
+		for _, input := range inputs {
+			task, err := createTask(tx, input)
+			if err != nil {
+				return err
+			}
+			tasks = append(tasks, task)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// templateTaskInput turns a template item into a task input, counting its
+// offsets from the anchor day in loc
+func templateTaskInput(item models.TaskTemplateItem, anchor time.Time, loc *time.Location) (models.CreateTaskInput, error) {
+	input := models.CreateTaskInput{
+		Title:           item.Title,
+		Description:     item.Description,
+		Status:          item.Status,
+		Priority:        item.Priority,
+		Tags:            append([]string{}, item.Tags...),
+		EstimateMinutes: item.EstimateMinutes,
+	}
+
+	var clock time.Time
+	if item.Time != nil {
+		var err error
+		if clock, err = time.Parse(templateTimeLayout, *item.Time); err != nil {
+			return models.CreateTaskInput{}, apperr.Invalid("time", "invalid time %q: use HH:MM", *item.Time)
+		}
+	}
+	allDay := item.Time == nil
+	input.AllDay = &allDay
+
+	date := func(offset int) *string {
+		day := anchor.AddDate(0, 0, offset)
+		var value string
+		if allDay {
+			value = day.Format(dayLayout)
+		} else {
+			value = time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), 0, 0, loc).Format(time.RFC3339)
+		}
+		return &value
+	}
+	if item.DueOffsetDays != nil {
+		input.DueDate = date(*item.DueOffsetDays)
+	}
+	if item.StartOffsetDays != nil {
+		input.StartDate = date(*item.StartOffsetDays)
+	}
+	return input, nil
+}
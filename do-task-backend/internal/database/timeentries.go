@@ -0,0 +1,311 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/apperr"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+	"github.com/lib/pq"
+)
+
+var (
+	errInvalidEstimate   = apperr.Invalid("estimateMinutes", "estimateMinutes must be positive")
+	errEntryEndsTooEarly = apperr.Invalid("endedAt", "endedAt must not be before startedAt")
+)
+
+// timeEntryColumns is the select list used for all time entry queries
+const timeEntryColumns = `e.id, e.task_id, e.started_at, e.ended_at, e.note, e.created_at`
+
+// scanTimeEntry scans a row selected with timeEntryColumns
+func scanTimeEntry(row rowScanner) (models.TimeEntry, error) {
+	var entry models.TimeEntry
+	err := row.Scan(&entry.ID, &entry.TaskID, &entry.StartedAt, &entry.EndedAt, &entry.Note, &entry.CreatedAt)
+	return entry, err
+}
+
+// isCheckViolation reports whether err is a Postgres check constraint violation
+func isCheckViolation(err error) bool {
+	pqErr, ok := err.(*pq.Error)
+	return ok && pqErr.Code == "23514"
+}
+
+// GetTimeEntries retrieves the time entries of one of a user's tasks, newest first
+func (db *DB) GetTimeEntries(taskID string, userID string) ([]models.TimeEntry, error) {
+	rows, err := db.Query(`SELECT `+timeEntryColumns+` FROM time_entries e
+		WHERE e.task_id = $1 AND e.user_id = $2
+		ORDER BY e.started_at DESC`, taskID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query time entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []models.TimeEntry
+	for rows.Next() {
+		entry, err := scanTimeEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan time entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate time entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+// GetTimeSpent returns the seconds spent on one of a user's tasks, counting a
+// running timer up to now
+func (db *DB) GetTimeSpent(taskID string, userID string) (int, error) {
+	var seconds int
+	err := db.QueryRow(`
+		SELECT COALESCE(SUM(EXTRACT(EPOCH FROM COALESCE(e.ended_at, NOW()) - e.started_at)), 0)::bigint
+		FROM time_entries e
+		WHERE e.task_id = $1 AND e.user_id = $2`, taskID, userID).Scan(&seconds)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get time spent: %w", err)
+	}
+	return seconds, nil
+}
+
+// GetRunningTimer returns the user's running timer, or nil when none is running
+func (db *DB) GetRunningTimer(userID string) (*models.TimeEntry, error) {
+	entry, err := scanTimeEntry(db.QueryRow(`SELECT `+timeEntryColumns+` FROM time_entries e
+		WHERE e.user_id = $1 AND e.ended_at IS NULL`, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get running timer: %w", err)
+	}
+	return &entry, nil
+}
+
+// StartTimer starts timing one of a user's tasks. A timer already running for
+// the user is stopped first, so there is never more than one.
+func (db *DB) StartTimer(taskID string, note string, userID string) (models.TimeEntry, error) {
+	var entry models.TimeEntry
+	err := db.withTx(func(tx *sql.Tx) error {
+		_, err := tx.Exec(`UPDATE time_entries SET ended_at = NOW()
+			WHERE user_id = $1 AND ended_at IS NULL`, userID)
+		if err != nil {
+			return fmt.Errorf("failed to stop running timer: %w", err)
+		}
+
+		entry, err = scanTimeEntry(tx.QueryRow(`
+			INSERT INTO time_entries AS e (task_id, user_id, started_at, note)
+			SELECT t.id, t.user_id, NOW(), $3 FROM tasks t
+			WHERE t.id = $1 AND t.user_id = $2 AND t.deleted_at IS NULL
+			RETURNING `+timeEntryColumns, taskID, userID, note))
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return apperr.NotFound("task")
+			}
+			// Another request started a timer at the same moment
+			if isUniqueViolation(err) {
+				return apperr.Conflict("a timer is already running")
+			}
+			return fmt.Errorf("failed to start timer: %w", err)
+		}
+		return nil
+	})
+	return entry, err
+}
+
+// StopTimer stops the user's running timer
+func (db *DB) StopTimer(userID string) (models.TimeEntry, error) {
+	entry, err := scanTimeEntry(db.QueryRow(`
+		UPDATE time_entries e SET ended_at = NOW()
+		WHERE e.user_id = $1 AND e.ended_at IS NULL
+		RETURNING `+timeEntryColumns, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.TimeEntry{}, apperr.New(apperr.CodeNotFound, "no timer is running")
+		}
+		return models.TimeEntry{}, fmt.Errorf("failed to stop timer: %w", err)
+	}
+	return entry, nil
+}
+
+// parseEntryTime reads a time entry timestamp
+func parseEntryTime(field, value string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, apperr.Invalid(field, "%s must be an RFC 3339 timestamp", field)
+	}
+	return t, nil
+}
+
+// CreateTimeEntry records time spent on one of a user's tasks by hand
+func (db *DB) CreateTimeEntry(input models.CreateTimeEntryInput, userID string) (models.TimeEntry, error) {
+	startedAt, err := parseEntryTime("startedAt", input.StartedAt)
+	if err != nil {
+		return models.TimeEntry{}, err
+	}
+	endedAt, err := parseEntryTime("endedAt", input.EndedAt)
+	if err != nil {
+		return models.TimeEntry{}, err
+	}
+	if endedAt.Before(startedAt) {
+		return models.TimeEntry{}, errEntryEndsTooEarly
+	}
+	note := ""
+	if input.Note != nil {
+		note = *input.Note
+	}
+
+	entry, err := scanTimeEntry(db.QueryRow(`
+		INSERT INTO time_entries AS e (task_id, user_id, started_at, ended_at, note)
+		SELECT t.id, t.user_id, $3, $4, $5 FROM tasks t
+		WHERE t.id = $1 AND t.user_id = $2 AND t.deleted_at IS NULL
+		RETURNING `+timeEntryColumns, input.TaskID, userID, startedAt, endedAt, note))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.TimeEntry{}, apperr.NotFound("task")
+		}
+		return models.TimeEntry{}, fmt.Errorf("failed to create time entry: %w", err)
+	}
+	return entry, nil
+}
+
+// UpdateTimeEntry changes a user's time entry. Setting endedAt on a running
+// timer stops it.
+func (db *DB) UpdateTimeEntry(id string, input models.UpdateTimeEntryInput, userID string) (models.TimeEntry, error) {
+	var startedAt, endedAt *time.Time
+	if input.StartedAt != nil {
+		t, err := parseEntryTime("startedAt", *input.StartedAt)
+		if err != nil {
+			return models.TimeEntry{}, err
+		}
+		startedAt = &t
+	}
+	if input.EndedAt != nil {
+		t, err := parseEntryTime("endedAt", *input.EndedAt)
+		if err != nil {
+			return models.TimeEntry{}, err
+		}
+		endedAt = &t
+	}
+
+	entry, err := scanTimeEntry(db.QueryRow(`
+		UPDATE time_entries e SET
+			started_at = COALESCE($3, e.started_at),
+			ended_at = COALESCE($4, e.ended_at),
+			note = COALESCE($5, e.note)
+		WHERE e.id = $1 AND e.user_id = $2
+		RETURNING `+timeEntryColumns, id, userID, startedAt, endedAt, input.Note))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.TimeEntry{}, apperr.NotFound("time entry")
+		}
+		if isCheckViolation(err) {
+			return models.TimeEntry{}, errEntryEndsTooEarly
+		}
+		return models.TimeEntry{}, fmt.Errorf("failed to update time entry: %w", err)
+	}
+	return entry, nil
+}
+
+// DeleteTimeEntry removes a user's time entry
+func (db *DB) DeleteTimeEntry(id string, userID string) error {
+	result, err := db.Exec(`DELETE FROM time_entries WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete time entry: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return apperr.NotFound("time entry")
+	}
+
+	return nil
+}
+
+// GetTimeReport adds up a user's time from the first to the last day given
+// (YYYY-MM-DD, both included) in the user's timezone
+func (db *DB) GetTimeReport(userID string, from, to string, groupBy models.TimeReportGroupBy) (models.TimeReport, error) {
+	if !isDay(from) || !isDay(to) {
+		return models.TimeReport{}, apperr.Invalid("", "from and to must be YYYY-MM-DD days")
+	}
+	if to < from {
+		return models.TimeReport{}, apperr.Invalid("to", "to must not be before from")
+	}
+
+	loc, err := userLocation(db, userID)
+	if err != nil {
+		return models.TimeReport{}, err
+	}
+
+	// Entries are cut to the range; the start of what is left decides the day
+	entries := `
+		WITH bounds AS (
+			SELECT $2::date::timestamp AT TIME ZONE $4::text AS start_at,
+				($3::date + 1)::timestamp AT TIME ZONE $4::text AS end_at
+		), entries AS (
+			SELECT e.task_id, GREATEST(e.started_at, b.start_at) AS started_at,
+				EXTRACT(EPOCH FROM LEAST(COALESCE(e.ended_at, NOW()), b.end_at)
+					- GREATEST(e.started_at, b.start_at)) AS seconds
+			FROM time_entries e, bounds b
+			WHERE e.user_id = $1 AND e.started_at < b.end_at AND COALESCE(e.ended_at, NOW()) > b.start_at
+		)`
+	args := []interface{}{userID, from, to, loc.String()}
+
+	var grouped, order string
+	switch groupBy {
+	case models.TimeReportGroupByCategory:
+		grouped = `SELECT c.id::text AS key, COALESCE(c.name, '') AS label, x.seconds
+			FROM entries x
+			JOIN tasks t ON t.id = x.task_id
+			LEFT JOIN categories c ON c.id = t.category_id`
+		order = "3 DESC, 2"
+	case models.TimeReportGroupByTag:
+		grouped = `SELECT tg.id::text AS key, COALESCE(tg.name, '') AS label, x.seconds
+			FROM entries x
+			LEFT JOIN task_tags tt ON tt.task_id = x.task_id
+			LEFT JOIN tags tg ON tg.id = tt.tag_id`
+		order = "3 DESC, 2"
+	case models.TimeReportGroupByDay:
+		grouped = `SELECT to_char((x.started_at AT TIME ZONE $4::text)::date, 'YYYY-MM-DD') AS key,
+				to_char((x.started_at AT TIME ZONE $4::text)::date, 'YYYY-MM-DD') AS label, x.seconds
+			FROM entries x`
+		order = "1"
+	default:
+		return models.TimeReport{}, apperr.Invalid("groupBy", "unknown time report grouping %q", groupBy)
+	}
+
+	report := models.TimeReport{From: from, To: to, GroupBy: groupBy, Rows: []models.TimeReportRow{}}
+	err = db.QueryRow(entries+` SELECT COALESCE(SUM(seconds), 0)::bigint FROM entries`, args...).Scan(&report.TotalSeconds)
+	if err != nil {
+		return models.TimeReport{}, fmt.Errorf("failed to get time report total: %w", err)
+	}
+
+	rows, err := db.Query(entries+`
+		SELECT key, label, SUM(seconds)::bigint, COUNT(*)
+		FROM (`+grouped+`) g
+		GROUP BY key, label
+		ORDER BY `+order, args...)
+	if err != nil {
+		return models.TimeReport{}, fmt.Errorf("failed to get time report: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row models.TimeReportRow
+		if err := rows.Scan(&row.Key, &row.Label, &row.Seconds, &row.Entries); err != nil {
+			return models.TimeReport{}, fmt.Errorf("failed to scan time report row: %w", err)
+		}
+		report.Rows = append(report.Rows, row)
+	}
+
+	if err := rows.Err(); err != nil {
+		return models.TimeReport{}, fmt.Errorf("failed to iterate time report: %w", err)
+	}
+
+	return report, nil
+}
@@ -0,0 +1,84 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/apperr"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+)
+
+// GetTrashedTasks retrieves the trashed tasks of a specific user, most recently trashed first
+func (db *DB) GetTrashedTasks(userID string) ([]models.Task, error) {
+	query := `SELECT ` + taskColumns + `
+		FROM tasks t WHERE t.user_id = $1 AND t.deleted_at IS NOT NULL
+		ORDER BY t.deleted_at DESC`
+
+	tasks, err := queryTasks(db, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query trashed tasks: %w", err)
+	}
+
+	return tasks, nil
+}
+
+// RestoreTask moves a trashed task back into the task list. Tasks whose
+// category was deleted need a categoryID to land in.
+func (db *DB) RestoreTask(id string, categoryID *string, userID string) (models.Task, error) {
+	var task models.Task
+	err := db.withTx(func(tx *sql.Tx) error {
+		var currentCategory sql.NullString
+		err := tx.QueryRow(`
+			SELECT category_id FROM tasks
+			WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL
+			FOR UPDATE`, id, userID).Scan(&currentCategory)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return apperr.NotFound("trashed task")
+			}
+			return fmt.Errorf("failed to get trashed task: %w", err)
+		}
+
+		target := currentCategory.String
+		if categoryID != nil && *categoryID != "" {
+			if _, err := getCategory(tx, *categoryID, userID); err != nil {
+				return err
+			}
+			target = *categoryID
+		}
+		if target == "" {
+			return apperr.Invalid("categoryId", "the task's category no longer exists, choose a category to restore it into")
+		}
+
+		_, err = tx.Exec(`
+			UPDATE tasks SET deleted_at = NULL, category_id = $1, updated_at = NOW()
+			WHERE id = $2 AND user_id = $3`, target, id, userID)
+		if err != nil {
+			return fmt.Errorf("failed to restore task: %w", err)
+		}
+
+		task, err = getTask(tx, id, userID)
+		if err != nil {
+			return err
+		}
+
+		return emitTaskEvent(tx, models.EventTaskUpdated, task)
+	})
+
+	return task, err
+}
+
+// EmptyTrash permanently deletes every trashed task of a specific user
+func (db *DB) EmptyTrash(userID string) (int, error) {
+	result, err := db.Exec(`DELETE FROM tasks WHERE user_id = $1 AND deleted_at IS NOT NULL`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to empty trash: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return int(rowsAffected), nil
+}
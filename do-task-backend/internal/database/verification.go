@@ -0,0 +1,101 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/apperr"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+)
+
+// verificationResendInterval is how long to wait before mailing another
+// verification link for the same address
+const verificationResendInterval = time.Minute
+
+// checkEmailAvailable reports a conflict when email belongs to another user
+func checkEmailAvailable(q querier, email string, userID string) error {
+	var taken bool
+	err := q.QueryRow(`SELECT EXISTS (SELECT 1 FROM users WHERE lower(email) = $1 AND id <> $2)`,
+		NormalizeEmail(email), userID).Scan(&taken)
+	if err != nil {
+		return fmt.Errorf("failed to check email: %w", err)
+	}
+	if taken {
+		return apperr.Conflict("email already in use")
+	}
+	return nil
+}
+
+// CreateEmailVerification stores the hash of a link token that verifies email
+// for a user. Only the newest link of a user works.
+func (db *DB) CreateEmailVerification(userID string, email string, tokenHash string, expiresAt time.Time) error {
+	return db.withTx(func(tx *sql.Tx) error {
+		// Serialise requests per user so the resend check holds
+		if _, err := tx.Exec(`SELECT 1 FROM users WHERE id = $1 FOR UPDATE`, userID); err != nil {
+			return fmt.Errorf("failed to lock user: %w", err)
+		}
+
+		var recent bool
+		err := tx.QueryRow(`
+			SELECT EXISTS (SELECT 1 FROM email_verifications
+				WHERE user_id = $1 AND email = $2 AND created_at > $3)`,
+			userID, email, time.Now().Add(-verificationResendInterval)).Scan(&recent)
+		if err != nil {
+			return fmt.Errorf("failed to check email verifications: %w", err)
+		}
+		if recent {
+			return apperr.RateLimited("a verification email was sent a moment ago; wait a minute before asking for another")
+		}
+
+		if _, err := tx.Exec(`DELETE FROM email_verifications WHERE user_id = $1`, userID); err != nil {
+			return fmt.Errorf("failed to delete email verifications: %w", err)
+		}
+		_, err = tx.Exec(`
+			INSERT INTO email_verifications (user_id, email, token_hash, expires_at)
+			VALUES ($1, $2, $3, $4)`, userID, email, tokenHash, expiresAt)
+		if err != nil {
+			return fmt.Errorf("failed to create email verification: %w", err)
+		}
+		return nil
+	})
+}
+
+// VerifyEmail uses up a verification link. The address it was sent to is
+// marked verified, replacing the user's email when it was the pending one.
+func (db *DB) VerifyEmail(tokenHash string) (models.User, error) {
+	invalid := apperr.Invalid("token", "verification link is invalid or has expired")
+
+	var user models.User
+	err := db.withTx(func(tx *sql.Tx) error {
+		var userID, email string
+		err := tx.QueryRow(`
+			DELETE FROM email_verifications WHERE token_hash = $1 AND expires_at > NOW()
+			RETURNING user_id, email`, tokenHash).Scan(&userID, &email)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return invalid
+			}
+			return fmt.Errorf("failed to get email verification: %w", err)
+		}
+
+		user, err = scanUser(tx.QueryRow(`
+			UPDATE users u SET email = $2,
+				pending_email = CASE WHEN u.pending_email = $2 THEN NULL ELSE u.pending_email END,
+				email_verified_at = NOW(), updated_at = NOW()
+			WHERE u.id = $1 AND (u.email = $2 OR u.pending_email = $2)
+			RETURNING `+userColumns, userID, email))
+		if err != nil {
+			// The address was replaced by another change in the meantime
+			if err == sql.ErrNoRows {
+				return invalid
+			}
+			if isUniqueViolation(err) {
+				return apperr.Conflict("email already in use")
+			}
+			return fmt.Errorf("failed to verify email: %w", err)
+		}
+		return nil
+	})
+	return user, err
+}
@@ -0,0 +1,245 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/apperr"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+)
+
+// maxViewNameLength mirrors the size of the saved_views.name column
+const maxViewNameLength = 100
+
+// savedViewColumns is the select list used for saved view queries
+const savedViewColumns = `v.id, v.name, v.filter, v.sort, v.display_mode, v.created_at, v.updated_at`
+
+// scanSavedView scans a row selected with savedViewColumns
+func scanSavedView(row rowScanner) (models.SavedView, error) {
+	var view models.SavedView
+	var filter []byte
+	var sort []byte
+	err := row.Scan(
+		&view.ID,
+		&view.Name,
+		&filter,
+		&sort,
+		&view.DisplayMode,
+		&view.CreatedAt,
+		&view.UpdatedAt,
+	)
+	if err != nil {
+		return view, err
+	}
+
+	if err := json.Unmarshal(filter, &view.Filter); err != nil {
+		return view, fmt.Errorf("failed to decode view filter: %w", err)
+	}
+	if sort != nil {
+		view.Sort = &models.TaskSort{}
+		if err := json.Unmarshal(sort, view.Sort); err != nil {
+			return view, fmt.Errorf("failed to decode view sort: %w", err)
+		}
+	}
+	return view, nil
+}
+
+// checkSavedView validates a view before it is stored: the filter has to
+// compile and point at one of the user's categories
+func checkSavedView(q querier, view *models.SavedView, userID string) error {
+	view.Name = strings.TrimSpace(view.Name)
+	if view.Name == "" {
+		return apperr.Invalid("name", "view name cannot be empty")
+	}
+	if utf8.RuneCountInString(view.Name) > maxViewNameLength {
+		return apperr.Invalid("name", "view name cannot be longer than %d characters", maxViewNameLength)
+	}
+
+	switch view.DisplayMode {
+	case models.ViewDisplayModeList, models.ViewDisplayModeBoard, models.ViewDisplayModeCalendar:
+	default:
+		return apperr.Invalid("displayMode", "invalid display mode %q", view.DisplayMode)
+	}
+
+	if _, err := buildTaskFilter(&view.Filter, userID, &sqlArgs{}); err != nil {
+		return err
+	}
+
+	if view.Filter.CategoryID != nil && *view.Filter.CategoryID != "" {
+		var exists bool
+		err := q.QueryRow(`SELECT EXISTS (SELECT 1 FROM categories WHERE id = $1 AND user_id = $2)`,
+			*view.Filter.CategoryID, userID).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("failed to check view category: %w", err)
+		}
+		if !exists {
+			return apperr.Invalid("filter.categoryId", "category not found")
+		}
+	}
+
+	return nil
+}
+
+// encodeSavedView returns the JSON columns of a view
+func encodeSavedView(view models.SavedView) ([]byte, []byte, error) {
+	filter, err := json.Marshal(view.Filter)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode view filter: %w", err)
+	}
+	if view.Sort == nil {
+		return filter, nil, nil
+	}
+	sort, err := json.Marshal(view.Sort)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode view sort: %w", err)
+	}
+	return filter, sort, nil
+}
+
+// GetSavedViews retrieves all saved views of a user by name
+func (db *DB) GetSavedViews(userID string) ([]models.SavedView, error) {
+	rows, err := db.Query(`SELECT `+savedViewColumns+` FROM saved_views v
+		WHERE v.user_id = $1 ORDER BY lower(v.name)`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get saved views: %w", err)
+	}
+	defer rows.Close()
+
+	views := []models.SavedView{}
+	for rows.Next() {
+		view, err := scanSavedView(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan saved view: %w", err)
+		}
+		views = append(views, view)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate saved views: %w", err)
+	}
+	return views, nil
+}
+
+// GetSavedView retrieves one of a user's saved views
+func (db *DB) GetSavedView(id string, userID string) (models.SavedView, error) {
+	view, err := scanSavedView(db.QueryRow(`SELECT `+savedViewColumns+` FROM saved_views v
+		WHERE v.id = $1 AND v.user_id = $2`, id, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.SavedView{}, apperr.NotFound("saved view")
+		}
+		return models.SavedView{}, fmt.Errorf("failed to get saved view: %w", err)
+	}
+	return view, nil
+}
+
+// CreateSavedView saves a task filter and sort under a name unique to the user
+func (db *DB) CreateSavedView(input models.CreateSavedViewInput, userID string) (models.SavedView, error) {
+	view := models.SavedView{
+		Name:        input.Name,
+		Sort:        input.Sort,
+		DisplayMode: models.ViewDisplayModeList,
+	}
+	if input.Filter != nil {
+		view.Filter = *input.Filter
+	}
+	if input.DisplayMode != nil {
+		view.DisplayMode = *input.DisplayMode
+	}
+
+	if err := checkSavedView(db, &view, userID); err != nil {
+		return models.SavedView{}, err
+	}
+	filter, sort, err := encodeSavedView(view)
+	if err != nil {
+		return models.SavedView{}, err
+	}
+
+	view, err = scanSavedView(db.QueryRow(`
+		INSERT INTO saved_views AS v (user_id, name, filter, sort, display_mode)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+savedViewColumns, userID, view.Name, filter, sort, view.DisplayMode))
+	if err != nil {
+		if isUniqueViolation(err) {
+			return models.SavedView{}, apperr.Conflict("view with this name already exists")
+		}
+		return models.SavedView{}, fmt.Errorf("failed to create saved view: %w", err)
+	}
+	return view, nil
+}
+
+// UpdateSavedView changes one of a user's saved views
+func (db *DB) UpdateSavedView(id string, input models.UpdateSavedViewInput, userID string) (models.SavedView, error) {
+	view, err := db.GetSavedView(id, userID)
+	if err != nil {
+		return models.SavedView{}, err
+	}
+
+	if input.Name != nil {
+		view.Name = *input.Name
+	}
+	if input.Filter != nil {
+		view.Filter = *input.Filter
+	}
+	if input.Sort != nil {
+		view.Sort = input.Sort
+	} else if input.ClearSort != nil && *input.ClearSort {
+		view.Sort = nil
+	}
+	if input.DisplayMode != nil {
+		view.DisplayMode = *input.DisplayMode
+	}
+
+	if err := checkSavedView(db, &view, userID); err != nil {
+		return models.SavedView{}, err
+	}
+	filter, sort, err := encodeSavedView(view)
+	if err != nil {
+		return models.SavedView{}, err
+	}
+
+	view, err = scanSavedView(db.QueryRow(`
+		UPDATE saved_views v SET name = $3, filter = $4, sort = $5, display_mode = $6, updated_at = NOW()
+		WHERE v.id = $1 AND v.user_id = $2
+		RETURNING `+savedViewColumns, id, userID, view.Name, filter, sort, view.DisplayMode))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.SavedView{}, apperr.NotFound("saved view")
+		}
+		if isUniqueViolation(err) {
+			return models.SavedView{}, apperr.Conflict("view with this name already exists")
+		}
+		return models.SavedView{}, fmt.Errorf("failed to update saved view: %w", err)
+	}
+	return view, nil
+}
+
+// DeleteSavedView removes one of a user's saved views
+func (db *DB) DeleteSavedView(id string, userID string) error {
+	result, err := db.Exec(`DELETE FROM saved_views WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete saved view: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return apperr.NotFound("saved view")
+	}
+	return nil
+}
+
+// GetViewTasks compiles one of a user's saved views into a task query and
+// runs it. Relative filters such as dueIn are evaluated against today.
+func (db *DB) GetViewTasks(id string, userID string) ([]models.Task, error) {
+	view, err := db.GetSavedView(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	return db.GetTasks(userID, &view.Filter, view.Sort)
+}
@@ -0,0 +1,446 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/apperr"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+	"github.com/lib/pq"
+)
+
+// webhookColumns is the select list used for webhook queries
+const webhookColumns = `w.id, w.url, w.secret, w.event_types, w.active, w.created_at, w.updated_at`
+
+// webhookDeliveryColumns is the select list used for delivery queries
+const webhookDeliveryColumns = `d.id, d.webhook_id, d.event_type, d.payload::text, d.status, d.attempts,
+		d.response_status, d.last_error, d.next_attempt_at, d.last_attempt_at, d.delivered_at, d.created_at`
+
+// maxDeliveryError caps how much of a failure message is kept
+const maxDeliveryError = 1000
+
+// scanWebhook scans a row selected with webhookColumns
+func scanWebhook(row rowScanner) (models.Webhook, error) {
+	var webhook models.Webhook
+	err := row.Scan(
+		&webhook.ID,
+		&webhook.URL,
+		&webhook.Secret,
+		pq.Array(&webhook.EventTypes),
+		&webhook.Active,
+		&webhook.CreatedAt,
+		&webhook.UpdatedAt,
+	)
+	return webhook, err
+}
+
+// scanWebhookDelivery scans a row selected with webhookDeliveryColumns
+func scanWebhookDelivery(row rowScanner) (models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	var responseStatus sql.NullInt64
+	err := row.Scan(
+		&delivery.ID,
+		&delivery.WebhookID,
+		&delivery.EventType,
+		&delivery.Payload,
+		&delivery.Status,
+		&delivery.Attempts,
+		&responseStatus,
+		&delivery.Error,
+		&delivery.NextAttemptAt,
+		&delivery.LastAttemptAt,
+		&delivery.DeliveredAt,
+		&delivery.CreatedAt,
+	)
+	if responseStatus.Valid {
+		status := int(responseStatus.Int64)
+		delivery.ResponseStatus = &status
+	}
+	return delivery, err
+}
+
+// webhookEvent is the JSON body sent to webhooks
+type webhookEvent struct {
+	Type      string      `json:"type"`
+	CreatedAt string      `json:"createdAt"`
+	Data      interface{} `json:"data"`
+}
+
+func encodeEvent(eventType string, data interface{}) (string, error) {
+	payload, err := json.Marshal(webhookEvent{
+		Type:      eventType,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+		Data:      data,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+	return string(payload), nil
+}
+
+// emitEvent queues an event for every active webhook of userID subscribed to
+// it. Callers run it in the transaction that makes the change, so an event is
+// recorded if and only if the change is committed.
+func emitEvent(q querier, userID string, eventType string, data interface{}) error {
+	payload, err := encodeEvent(eventType, data)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.Exec(`
+		INSERT INTO webhook_deliveries (webhook_id, event_type, payload)
+		SELECT w.id, $2::text, $3::jsonb FROM webhooks w
+		WHERE w.user_id = $1 AND w.active AND EXISTS (
+			SELECT 1 FROM unnest(w.event_types) AS e(pattern)
+			WHERE e.pattern = $2::text OR e.pattern = '*'
+				OR (e.pattern LIKE '%.*' AND $2::text LIKE left(e.pattern, -1) || '%')
+		)`, userID, eventType, payload)
+	if err != nil {
+		return fmt.Errorf("failed to queue %s event: %w", eventType, err)
+	}
+	return nil
+}
+
+// emitTaskEvent queues a task event whose data is {"task": task}
+func emitTaskEvent(q querier, eventType string, task models.Task) error {
+	return emitEvent(q, task.UserID, eventType, map[string]interface{}{"task": task})
+}
+
+// emitCategoryEvent queues a category event whose data is {"category": category}
+func emitCategoryEvent(q querier, userID string, eventType string, category models.Category) error {
+	return emitEvent(q, userID, eventType, map[string]interface{}{"category": category})
+}
+
+// normalizeWebhookEventTypes validates and de-duplicates event subscriptions
+func normalizeWebhookEventTypes(eventTypes []string) ([]string, error) {
+	known := map[string]bool{"*": true}
+	for _, eventType := range models.WebhookEventTypes {
+		known[eventType] = true
+		known[eventType[:strings.Index(eventType, ".")]+".*"] = true
+	}
+
+	seen := make(map[string]bool)
+	var result []string
+	for _, eventType := range eventTypes {
+		eventType = strings.TrimSpace(eventType)
+		if !known[eventType] {
+			return nil, apperr.Invalid("events", "unknown event type %q", eventType)
+		}
+		if !seen[eventType] {
+			seen[eventType] = true
+			result = append(result, eventType)
+		}
+	}
+	if len(result) == 0 {
+		return nil, apperr.Invalid("events", "at least one event type is required")
+	}
+	return result, nil
+}
+
+// getWebhook loads a single webhook owned by userID
+func getWebhook(q querier, id string, userID string) (models.Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks w WHERE w.id = $1 AND w.user_id = $2`
+
+	webhook, err := scanWebhook(q.QueryRow(query, id, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Webhook{}, apperr.NotFound("webhook")
+		}
+		return models.Webhook{}, fmt.Errorf("failed to get webhook: %w", err)
+	}
+
+	return webhook, nil
+}
+
+// GetWebhook retrieves a webhook by ID for a specific user
+func (db *DB) GetWebhook(id string, userID string) (models.Webhook, error) {
+	return getWebhook(db, id, userID)
+}
+
+// GetWebhooks retrieves all webhooks for a specific user
+func (db *DB) GetWebhooks(userID string) ([]models.Webhook, error) {
+	rows, err := db.Query(`SELECT `+webhookColumns+` FROM webhooks w WHERE w.user_id = $1 ORDER BY w.created_at`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	var webhooks []models.Webhook
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate webhooks: %w", err)
+	}
+
+	return webhooks, nil
+}
+
+// CreateWebhook creates a webhook for a specific user. The URL and secret are
+// expected to be validated by the caller.
+func (db *DB) CreateWebhook(url string, secret string, eventTypes []string, active bool, userID string) (models.Webhook, error) {
+	eventTypes, err := normalizeWebhookEventTypes(eventTypes)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	query := `
+		INSERT INTO webhooks (user_id, url, secret, event_types, active)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, url, secret, event_types, active, created_at, updated_at`
+
+	webhook, err := scanWebhook(db.QueryRow(query, userID, url, secret, pq.Array(eventTypes), active))
+	if err != nil {
+		return models.Webhook{}, fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	return webhook, nil
+}
+
+// UpdateWebhook applies the non-nil fields of input to a webhook
+func (db *DB) UpdateWebhook(id string, input models.UpdateWebhookInput, userID string) (models.Webhook, error) {
+	setParts := []string{"updated_at = NOW()"}
+	args := &sqlArgs{}
+
+	if input.URL != nil {
+		setParts = append(setParts, "url = "+args.add(*input.URL))
+	}
+	if input.Secret != nil {
+		setParts = append(setParts, "secret = "+args.add(*input.Secret))
+	}
+	if input.EventTypes != nil {
+		eventTypes, err := normalizeWebhookEventTypes(input.EventTypes)
+		if err != nil {
+			return models.Webhook{}, err
+		}
+		setParts = append(setParts, "event_types = "+args.add(pq.Array(eventTypes)))
+	}
+	if input.Active != nil {
+		setParts = append(setParts, "active = "+args.add(*input.Active))
+	}
+
+	query := fmt.Sprintf(`
+		UPDATE webhooks SET %s
+		WHERE id = %s AND user_id = %s
+		RETURNING id, url, secret, event_types, active, created_at, updated_at`,
+		strings.Join(setParts, ", "), args.add(id), args.add(userID))
+
+	webhook, err := scanWebhook(db.QueryRow(query, args.values...))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Webhook{}, apperr.NotFound("webhook")
+		}
+		return models.Webhook{}, fmt.Errorf("failed to update webhook: %w", err)
+	}
+
+	return webhook, nil
+}
+
+// DeleteWebhook deletes a webhook and its delivery log
+func (db *DB) DeleteWebhook(id string, userID string) error {
+	result, err := db.Exec(`DELETE FROM webhooks WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return apperr.NotFound("webhook")
+	}
+
+	return nil
+}
+
+// GetWebhookDeliveries retrieves the most recent deliveries of a webhook
+func (db *DB) GetWebhookDeliveries(webhookID string, limit int, userID string) ([]models.WebhookDelivery, error) {
+	if _, err := db.GetWebhook(webhookID, userID); err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries d
+		WHERE d.webhook_id = $1
+		ORDER BY d.created_at DESC
+		LIMIT $2`
+
+	rows, err := db.Query(query, webhookID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// GetWebhookDelivery retrieves a single delivery
+func (db *DB) GetWebhookDelivery(id string) (models.WebhookDelivery, error) {
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries d WHERE d.id = $1`
+
+	delivery, err := scanWebhookDelivery(db.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.WebhookDelivery{}, apperr.NotFound("webhook delivery")
+		}
+		return models.WebhookDelivery{}, fmt.Errorf("failed to get webhook delivery: %w", err)
+	}
+
+	return delivery, nil
+}
+
+// CreateTestWebhookDelivery queues a webhook.test event for a webhook,
+// whether or not it is active or subscribed to anything
+func (db *DB) CreateTestWebhookDelivery(webhookID string, userID string) (models.WebhookDelivery, error) {
+	webhook, err := db.GetWebhook(webhookID, userID)
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+
+	payload, err := encodeEvent(models.EventWebhookTest, map[string]interface{}{
+		"webhookId": webhook.ID,
+		"message":   "This is a test event from DoTask",
+	})
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+
+	var id string
+	err = db.QueryRow(`
+		INSERT INTO webhook_deliveries (webhook_id, event_type, payload)
+		VALUES ($1, $2, $3::jsonb)
+		RETURNING id`, webhook.ID, models.EventWebhookTest, payload).Scan(&id)
+	if err != nil {
+		return models.WebhookDelivery{}, fmt.Errorf("failed to queue test event: %w", err)
+	}
+
+	return db.GetWebhookDelivery(id)
+}
+
+// ClaimWebhookDeliveries locks up to limit due deliveries for sending. Claimed
+// rows are pushed lease into the future and their attempt counted, so other
+// dispatchers skip them and a crashed dispatcher's work is retried once the
+// lease runs out. With deliveryID set only that delivery is claimed.
+func (db *DB) ClaimWebhookDeliveries(limit int, lease time.Duration, deliveryID string) ([]models.WebhookDispatch, error) {
+	query := `
+		UPDATE webhook_deliveries d
+		SET attempts = d.attempts + 1, last_attempt_at = NOW(),
+			next_attempt_at = NOW() + $2::double precision * INTERVAL '1 second'
+		FROM webhooks w
+		WHERE w.id = d.webhook_id AND d.id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'PENDING' AND ($3 = '' OR id::text = $3)
+				AND ($3 <> '' OR next_attempt_at <= NOW())
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING d.id, d.event_type, d.payload::text, d.attempts, w.url, w.secret`
+
+	rows, err := db.Query(query, limit, lease.Seconds(), deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var dispatches []models.WebhookDispatch
+	for rows.Next() {
+		var dispatch models.WebhookDispatch
+		err := rows.Scan(
+			&dispatch.DeliveryID,
+			&dispatch.EventType,
+			&dispatch.Payload,
+			&dispatch.Attempt,
+			&dispatch.URL,
+			&dispatch.Secret,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		dispatches = append(dispatches, dispatch)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate webhook deliveries: %w", err)
+	}
+
+	return dispatches, nil
+}
+
+// RecordWebhookAttempt stores the outcome of sending a delivery. A failed
+// attempt is retried at retryAt, or marked FAILED when retryAt is nil.
+func (db *DB) RecordWebhookAttempt(deliveryID string, responseStatus int, attemptErr error, retryAt *time.Time) error {
+	var status *int
+	if responseStatus != 0 {
+		status = &responseStatus
+	}
+
+	var err error
+	switch {
+	case attemptErr == nil:
+		_, err = db.Exec(`
+			UPDATE webhook_deliveries
+			SET status = 'SUCCEEDED', response_status = $1, last_error = NULL, delivered_at = NOW()
+			WHERE id = $2`, status, deliveryID)
+	case retryAt != nil:
+		_, err = db.Exec(`
+			UPDATE webhook_deliveries
+			SET response_status = $1, last_error = $2, next_attempt_at = $3
+			WHERE id = $4`, status, truncateError(attemptErr), *retryAt, deliveryID)
+	default:
+		_, err = db.Exec(`
+			UPDATE webhook_deliveries
+			SET status = 'FAILED', response_status = $1, last_error = $2
+			WHERE id = $3`, status, truncateError(attemptErr), deliveryID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record webhook attempt: %w", err)
+	}
+	return nil
+}
+
+// PruneWebhookDeliveries deletes finished deliveries created before cutoff
+func (db *DB) PruneWebhookDeliveries(cutoff time.Time) (int, error) {
+	result, err := db.Exec(`
+		DELETE FROM webhook_deliveries
+		WHERE status <> 'PENDING' AND created_at < $1`, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune webhook deliveries: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return int(rowsAffected), nil
+}
+
+func truncateError(err error) string {
+	message := err.Error()
+	if len(message) > maxDeliveryError {
+		message = strings.ToValidUTF8(message[:maxDeliveryError], "")
+	}
+	return message
+}
@@ -0,0 +1,163 @@
+// Package digest compiles and sends the weekly digest email: the tasks a user
+// completed last week, the ones that are overdue and the ones due in the week
+// ahead, shown in the user's timezone and date format. Digests go out on the
+// morning of the first day of the user's week.
+package digest
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/database"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/mailer"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+)
+
+// SendHour is the local hour at which digests go out
+const SendHour = 8
+
+const (
+	// lease keeps a claimed digest away from other replicas; a send that
+	// fails is retried when it runs out
+	lease       = time.Hour
+	sendTimeout = 30 * time.Second
+)
+
+// Job sends weekly digests to the users who opted in
+type Job struct {
+	DB           *database.DB
+	Mailer       mailer.Mailer
+	PollInterval time.Duration
+	BatchSize    int
+}
+
+// NewJob returns a digest job that sends through m
+func NewJob(db *database.DB, m mailer.Mailer) *Job {
+	return &Job{
+		DB:           db,
+		Mailer:       m,
+		PollInterval: 5 * time.Minute,
+		BatchSize:    50,
+	}
+}
+
+// Run sends due digests until ctx is cancelled
+func (j *Job) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := j.ProcessDue(ctx); err != nil {
+			log.Printf("digest: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessDue handles one batch of due digests and returns how many were claimed
+func (j *Job) ProcessDue(ctx context.Context) (int, error) {
+	due, err := j.DB.ClaimDueDigests(j.BatchSize, lease)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, digest := range due {
+		if err := j.send(ctx, digest); err != nil {
+			return 0, err
+		}
+	}
+	return len(due), nil
+}
+
+// send mails a claimed digest and schedules the next one. Users without a
+// send time yet are only scheduled, so opting in midweek doesn't send a
+// digest straight away.
+func (j *Job) send(ctx context.Context, due models.DueDigest) error {
+	now := time.Now()
+	prefs := due.User.Preferences
+	next := NextSend(now, prefs.Location(), prefs.StartOfWeek.TimeWeekday())
+	if due.ScheduledAt == nil {
+		return j.DB.ScheduleDigest(due.User.ID, next)
+	}
+
+	digest, err := Compose(j.DB, due.User, now)
+	if err != nil {
+		return err
+	}
+
+	// Nothing to report is not worth an email
+	if len(digest.Completed)+len(digest.Overdue)+len(digest.Upcoming) > 0 {
+		sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+		err := j.Mailer.Send(sendCtx, mailer.Message{
+			To:      due.User.Email,
+			Subject: digest.Subject,
+			Text:    digest.Text,
+			HTML:    digest.HTML,
+		})
+		cancel()
+		if err != nil {
+			// Left leased; it is claimed again when the lease runs out
+			log.Printf("digest: sending to user %s: %v", due.User.ID, err)
+			return nil
+		}
+	}
+
+	return j.DB.ScheduleDigest(due.User.ID, next)
+}
+
+// Compose builds and renders the digest user would receive at now
+func Compose(db *database.DB, user models.User, now time.Time) (models.WeeklyDigest, error) {
+	loc := user.Preferences.Location()
+	weekStart := WeekStart(now, loc, user.Preferences.StartOfWeek.TimeWeekday())
+	lastWeekStart := weekStart.AddDate(0, 0, -7)
+	weekEnd := weekStart.AddDate(0, 0, 7)
+
+	completed, overdue, upcoming, err := db.GetDigestTasks(user.ID, lastWeekStart, weekStart, now, weekEnd)
+	if err != nil {
+		return models.WeeklyDigest{}, err
+	}
+
+	digest := models.WeeklyDigest{
+		Timezone:  loc.String(),
+		WeekStart: weekStart,
+		Completed: taskPointers(completed),
+		Overdue:   taskPointers(overdue),
+		Upcoming:  taskPointers(upcoming),
+	}
+	if err := Render(&digest, user); err != nil {
+		return models.WeeklyDigest{}, err
+	}
+	return digest, nil
+}
+
+// WeekStart returns midnight, in loc, on the first day of the week that
+// contains t, for weeks starting on firstDay
+func WeekStart(t time.Time, loc *time.Location, firstDay time.Weekday) time.Time {
+	local := t.In(loc)
+	daysIntoWeek := (int(local.Weekday()) - int(firstDay) + 7) % 7
+	return time.Date(local.Year(), local.Month(), local.Day()-daysIntoWeek, 0, 0, 0, 0, loc)
+}
+
+// NextSend returns the first SendHour on a firstDay in loc that is after now
+func NextSend(now time.Time, loc *time.Location, firstDay time.Weekday) time.Time {
+	start := WeekStart(now, loc, firstDay)
+	send := time.Date(start.Year(), start.Month(), start.Day(), SendHour, 0, 0, 0, loc)
+	if !send.After(now) {
+		send = time.Date(start.Year(), start.Month(), start.Day()+7, SendHour, 0, 0, 0, loc)
+	}
+	return send
+}
+
+func taskPointers(tasks []models.Task) []*models.Task {
+	result := make([]*models.Task, len(tasks))
+	for i := range tasks {
+		result[i] = &tasks[i]
+	}
+	return result
+}
@@ -0,0 +1,137 @@
+package digest
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"text/template"
+	"time"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+)
+
+// view is what the digest templates render; times are already formatted in
+// the user's timezone
+type view struct {
+	Name      string
+	WeekOf    string
+	Timezone  string
+	Completed []taskView
+	Overdue   []taskView
+	Upcoming  []taskView
+}
+
+type taskView struct {
+	Title    string
+	When     string
+	Priority string // set for high priority tasks only
+}
+
+var textTemplate = template.Must(template.New("digest.txt").Parse(`Hi {{.Name}},
+
+Here is your DoTask summary for the week of {{.WeekOf}}.
+{{- if .Overdue}}
+
+Overdue ({{len .Overdue}})
+{{- range .Overdue}}
+  - {{.Title}} (due {{.When}}){{if .Priority}} [{{.Priority}}]{{end}}
+{{- end}}
+{{- end}}
+{{- if .Upcoming}}
+
+Due this week ({{len .Upcoming}})
+{{- range .Upcoming}}
+  - {{.Title}} (due {{.When}}){{if .Priority}} [{{.Priority}}]{{end}}
+{{- end}}
+{{- end}}
+{{- if .Completed}}
+
+Completed last week ({{len .Completed}})
+{{- range .Completed}}
+  - {{.Title}} ({{.When}})
+{{- end}}
+{{- end}}
+
+Times are shown in {{.Timezone}}. You can turn this email off under Settings.
+`))
+
+var htmlTemplate = htmltemplate.Must(htmltemplate.New("digest.html").Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif; color: #111827; max-width: 600px; margin: 0 auto;">
+<p>Hi {{.Name}},</p>
+<p>Here is your DoTask summary for the week of <strong>{{.WeekOf}}</strong>.</p>
+{{- define "tasks"}}
+<ul style="padding-left: 20px;">
+{{- range .}}
+<li>{{.Title}} <span style="color: #6b7280;">{{.When}}</span>{{if .Priority}} <strong style="color: #dc2626;">{{.Priority}}</strong>{{end}}</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- if .Overdue}}
+<h3 style="color: #dc2626;">Overdue ({{len .Overdue}})</h3>
+{{template "tasks" .Overdue}}
+{{- end}}
+{{- if .Upcoming}}
+<h3>Due this week ({{len .Upcoming}})</h3>
+{{template "tasks" .Upcoming}}
+{{- end}}
+{{- if .Completed}}
+<h3 style="color: #16a34a;">Completed last week ({{len .Completed}})</h3>
+{{template "tasks" .Completed}}
+{{- end}}
+<p style="color: #6b7280; font-size: 12px;">Times are shown in {{.Timezone}}. You can turn this email off under Settings.</p>
+</body>
+</html>
+`))
+
+// Render fills in the subject, text and HTML of digest for user, with times
+// shown in the user's timezone and date format
+func Render(digest *models.WeeklyDigest, user models.User) error {
+	loc := user.Preferences.Location()
+	layout := "Mon " + user.Preferences.DateFormat.Layout()
+	v := view{
+		Name:      user.Name,
+		WeekOf:    digest.WeekStart.In(loc).Format(user.Preferences.DateFormat.Layout()),
+		Timezone:  loc.String(),
+		Completed: taskViews(digest.Completed, loc, layout, true),
+		Overdue:   taskViews(digest.Overdue, loc, layout, false),
+		Upcoming:  taskViews(digest.Upcoming, loc, layout, false),
+	}
+
+	var text, html bytes.Buffer
+	if err := textTemplate.Execute(&text, v); err != nil {
+		return fmt.Errorf("failed to render digest text: %w", err)
+	}
+	if err := htmlTemplate.Execute(&html, v); err != nil {
+		return fmt.Errorf("failed to render digest HTML: %w", err)
+	}
+
+	digest.Subject = fmt.Sprintf("Your week in DoTask: %d due, %d overdue, %d completed",
+		len(digest.Upcoming), len(digest.Overdue), len(digest.Completed))
+	digest.Text = text.String()
+	digest.HTML = html.String()
+	return nil
+}
+
+// taskViews formats tasks for the templates, by completion time when completed
+// is set. layout writes the date; due times are added for timed tasks.
+func taskViews(tasks []*models.Task, loc *time.Location, layout string, completed bool) []taskView {
+	views := make([]taskView, len(tasks))
+	for i, task := range tasks {
+		when, whenLayout := task.DueDate, layout
+		if completed && task.CompletedAt != nil {
+			when = task.CompletedAt
+		}
+		if completed || !task.AllDay {
+			whenLayout += " 15:04"
+		}
+		views[i] = taskView{Title: task.Title}
+		if when != nil {
+			views[i].When = when.In(loc).Format(whenLayout)
+		}
+		if task.Priority == models.TaskPriorityHigh {
+			views[i].Priority = "High priority"
+		}
+	}
+	return views
+}
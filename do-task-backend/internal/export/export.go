@@ -0,0 +1,441 @@
+// Package export provides HTTP handlers that stream a user's data out of DoTask
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/auth"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/database"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// FormatVersion is bumped whenever the JSON export layout changes incompatibly
+const FormatVersion = 1
+
+// flushEvery controls how many rows are written between flushes to the client
+const flushEvery = 100
+
+// CSVHeader lists the columns of the task CSV export
+var CSVHeader = []string{"id", "title", "description", "status", "priority", "due_date", "category", "tags", "created_at", "updated_at", "completed_at",
+	"all_day", "start_date", "due_on"}
+
+// Task is the exported representation of a task
+type Task struct {
+	ID          string   `json:"id"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Status      string   `json:"status"`
+	Priority    string   `json:"priority"`
+	DueDate     *string  `json:"dueDate"`
+	AllDay      bool     `json:"allDay"`
+	StartDate   *string  `json:"startDate"`
+	CategoryID  string   `json:"categoryId"`
+	Category    string   `json:"category"`
+	Tags        []string `json:"tags"`
+	CreatedAt   string   `json:"createdAt"`
+	UpdatedAt   string   `json:"updatedAt"`
+	CompletedAt *string  `json:"completedAt"`
+}
+
+// Category is the exported representation of a category
+type Category struct {
+	ID       string  `json:"id"`
+	Name     string  `json:"name"`
+	ParentID *string `json:"parentId"`
+	Path     string  `json:"path"`
+}
+
+// Profile is the exported representation of the account owner
+type Profile struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Email     string `json:"email"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// Handler serves the export endpoints
+type Handler struct {
+	DB *database.DB
+}
+
+// Register mounts the export routes on r. All of them require authentication.
+func (h *Handler) Register(r gin.IRouter) {
+	group := r.Group("/export", auth.RequireAuth())
+	group.GET("/tasks.csv", h.TasksCSV)
+	group.GET("/tasks.json", h.TasksJSON)
+	group.GET("/account.json", h.AccountJSON)
+	group.GET("/time.csv", h.TimeCSV)
+}
+
+// TasksCSV streams the authenticated user's tasks as CSV
+func (h *Handler) TasksCSV(c *gin.Context) {
+	userID, _, _, _ := auth.GetUserFromContext(c)
+
+	filter, err := ParseTaskFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	paths, err := h.categoryPaths(userID)
+	if err != nil {
+		log.Printf("export: failed to load categories: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export tasks"})
+		return
+	}
+
+	setDownloadHeaders(c, "text/csv; charset=utf-8", "tasks.csv")
+
+	w := csv.NewWriter(c.Writer)
+	if err := w.Write(CSVHeader); err != nil {
+		log.Printf("export: failed to write CSV header: %v", err)
+		return
+	}
+
+	count := 0
+	err = h.DB.StreamTasks(userID, filter, func(task models.Task) error {
+		t := NewTask(task, paths)
+		dueDate, completedAt, startDate, dueOn := "", "", "", ""
+		if t.DueDate != nil {
+			dueDate = *t.DueDate
+			dueOn = task.DueDate.In(task.Location()).Format("2006-01-02")
+		}
+		if t.CompletedAt != nil {
+			completedAt = *t.CompletedAt
+		}
+		if t.StartDate != nil {
+			startDate = *t.StartDate
+		}
+		record := []string{t.ID, t.Title, t.Description, t.Status, t.Priority, dueDate,
+			t.Category, strings.Join(t.Tags, ","), t.CreatedAt, t.UpdatedAt, completedAt,
+			strconv.FormatBool(t.AllDay), startDate, dueOn}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+
+		count++
+		if count%flushEvery == 0 {
+			w.Flush()
+			c.Writer.Flush()
+		}
+		return w.Error()
+	})
+	if err != nil {
+		log.Printf("export: CSV export aborted: %v", err)
+	}
+
+	w.Flush()
+	c.Writer.Flush()
+}
+
+// TasksJSON streams the authenticated user's tasks as a JSON document
+func (h *Handler) TasksJSON(c *gin.Context) {
+	userID, _, _, _ := auth.GetUserFromContext(c)
+
+	filter, err := ParseTaskFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	paths, err := h.categoryPaths(userID)
+	if err != nil {
+		log.Printf("export: failed to load categories: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export tasks"})
+		return
+	}
+
+	setDownloadHeaders(c, "application/json; charset=utf-8", "tasks.json")
+
+	fmt.Fprintf(c.Writer, `{"version":%d,"exportedAt":%q,"tasks":`, FormatVersion, time.Now().UTC().Format(time.RFC3339))
+	if err := h.streamTasksJSON(c, userID, filter, paths); err != nil {
+		log.Printf("export: JSON export aborted: %v", err)
+	}
+	fmt.Fprint(c.Writer, "}\n")
+	c.Writer.Flush()
+}
+
+// AccountJSON streams everything DoTask stores about the authenticated user,
+// for data portability requests
+func (h *Handler) AccountJSON(c *gin.Context) {
+	userID, _, _, _ := auth.GetUserFromContext(c)
+
+	user, err := h.DB.GetUserByID(userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	categories, err := h.DB.GetAllCategories(userID)
+	if err != nil {
+		log.Printf("export: failed to load categories: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export account"})
+		return
+	}
+	paths := CategoryPaths(categories)
+
+	tags, err := h.DB.GetAllTags(userID)
+	if err != nil {
+		log.Printf("export: failed to load tags: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export account"})
+		return
+	}
+
+	exportCategories := make([]Category, len(categories))
+	for i, category := range categories {
+		exportCategories[i] = Category{
+			ID:       category.ID,
+			Name:     category.Name,
+			ParentID: category.ParentID,
+			Path:     paths[category.ID],
+		}
+	}
+
+	header := struct {
+		Version    int          `json:"version"`
+		ExportedAt string       `json:"exportedAt"`
+		Profile    Profile      `json:"profile"`
+		Categories []Category   `json:"categories"`
+		Tags       []models.Tag `json:"tags"`
+	}{
+		Version:    FormatVersion,
+		ExportedAt: time.Now().UTC().Format(time.RFC3339),
+		Profile: Profile{
+			ID:        user.ID,
+			Name:      user.Name,
+			Email:     user.Email,
+			CreatedAt: user.CreatedAt.UTC().Format(time.RFC3339),
+		},
+		Categories: exportCategories,
+		Tags:       tags,
+	}
+
+	encoded, err := json.Marshal(header)
+	if err != nil {
+		log.Printf("export: failed to encode account: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export account"})
+		return
+	}
+
+	setDownloadHeaders(c, "application/json; charset=utf-8", "dotask-account.json")
+
+	// Splice the streamed task array into the header object
+	c.Writer.Write(encoded[:len(encoded)-1])
+	fmt.Fprint(c.Writer, `,"tasks":`)
+	if err := h.streamTasksJSON(c, userID, nil, paths); err != nil {
+		log.Printf("export: account export aborted: %v", err)
+	}
+	fmt.Fprint(c.Writer, "}\n")
+	c.Writer.Flush()
+}
+
+// streamTasksJSON writes the matching tasks as a JSON array
+func (h *Handler) streamTasksJSON(c *gin.Context, userID string, filter *models.TaskFilter, paths map[string]string) error {
+	fmt.Fprint(c.Writer, "[")
+	defer fmt.Fprint(c.Writer, "]")
+
+	count := 0
+	return h.DB.StreamTasks(userID, filter, func(task models.Task) error {
+		encoded, err := json.Marshal(NewTask(task, paths))
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			fmt.Fprint(c.Writer, ",")
+		}
+		if _, err := c.Writer.Write(encoded); err != nil {
+			return err
+		}
+
+		count++
+		if count%flushEvery == 0 {
+			c.Writer.Flush()
+		}
+		return nil
+	})
+}
+
+func (h *Handler) categoryPaths(userID string) (map[string]string, error) {
+	categories, err := h.DB.GetAllCategories(userID)
+	if err != nil {
+		return nil, err
+	}
+	return CategoryPaths(categories), nil
+}
+
+// CategoryPaths maps each category ID to its full path, e.g. "Work / Clients"
+func CategoryPaths(categories []models.Category) map[string]string {
+	byID := make(map[string]models.Category, len(categories))
+	for _, category := range categories {
+		byID[category.ID] = category
+	}
+
+	paths := make(map[string]string, len(categories))
+	for _, category := range categories {
+		names := []string{category.Name}
+		current := category
+		// The depth guard protects against corrupt cycles
+		for depth := 0; current.ParentID != nil && depth < 100; depth++ {
+			parent, ok := byID[*current.ParentID]
+			if !ok {
+				break
+			}
+			names = append([]string{parent.Name}, names...)
+			current = parent
+		}
+		paths[category.ID] = strings.Join(names, models.CategoryPathSeparator)
+	}
+
+	return paths
+}
+
+// NewTask returns the exported representation of a task; paths maps category
+// IDs to their full paths
+func NewTask(task models.Task, paths map[string]string) Task {
+	t := Task{
+		ID:          task.ID,
+		Title:       task.Title,
+		Description: task.Description,
+		Status:      string(task.Status),
+		Priority:    string(task.Priority),
+		CategoryID:  task.CategoryID,
+		Category:    paths[task.CategoryID],
+		AllDay:      task.AllDay,
+		Tags:        task.Tags,
+		CreatedAt:   task.CreatedAt.UTC().Format(time.RFC3339),
+		UpdatedAt:   task.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+	if t.Tags == nil {
+		t.Tags = []string{}
+	}
+	if task.DueDate != nil {
+		dueDate := exportDate(task, *task.DueDate)
+		t.DueDate = &dueDate
+	}
+	if task.StartDate != nil {
+		startDate := exportDate(task, *task.StartDate)
+		t.StartDate = &startDate
+	}
+	if task.CompletedAt != nil {
+		completedAt := task.CompletedAt.UTC().Format(time.RFC3339)
+		t.CompletedAt = &completedAt
+	}
+	return t
+}
+
+// exportDate formats one of a task's dates, as a calendar day for all-day tasks
+func exportDate(task models.Task, date time.Time) string {
+	if task.AllDay {
+		return date.In(task.Location()).Format("2006-01-02")
+	}
+	return date.UTC().Format(time.RFC3339)
+}
+
+func setDownloadHeaders(c *gin.Context, contentType string, filename string) {
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+}
+
+// ParseTaskFilter reads the task list filters from the query string. List
+// parameters accept repeated values or comma separated values. The values are
+// checked the same way as the task list's filter.
+func ParseTaskFilter(c *gin.Context) (*models.TaskFilter, error) {
+	filter := &models.TaskFilter{}
+
+	for _, value := range queryList(c, "status") {
+		status := models.TaskStatus(strings.ToUpper(value))
+		switch status {
+		case models.TaskStatusTodo, models.TaskStatusInProgress, models.TaskStatusCompleted:
+			filter.Status = append(filter.Status, status)
+		default:
+			return nil, fmt.Errorf("invalid status %q", value)
+		}
+	}
+
+	for _, value := range queryList(c, "priority") {
+		priority := models.TaskPriority(strings.ToUpper(value))
+		switch priority {
+		case models.TaskPriorityLow, models.TaskPriorityMedium, models.TaskPriorityHigh:
+			filter.Priority = append(filter.Priority, priority)
+		default:
+			return nil, fmt.Errorf("invalid priority %q", value)
+		}
+	}
+
+	if categoryID := c.Query("categoryId"); categoryID != "" {
+		filter.CategoryID = &categoryID
+	}
+
+	var err error
+	if filter.IncludeSubcategories, err = queryBool(c, "includeSubcategories"); err != nil {
+		return nil, err
+	}
+
+	filter.Tags = queryList(c, "tag")
+
+	if dueAfter := c.Query("dueAfter"); dueAfter != "" {
+		filter.DueAfter = &dueAfter
+	}
+	if dueBefore := c.Query("dueBefore"); dueBefore != "" {
+		filter.DueBefore = &dueBefore
+	}
+
+	if search := c.Query("search"); search != "" {
+		filter.Search = &search
+	}
+
+	if filter.Overdue, err = queryBool(c, "overdue"); err != nil {
+		return nil, err
+	}
+	if filter.HasDueDate, err = queryBool(c, "hasDueDate"); err != nil {
+		return nil, err
+	}
+	if dueOn := c.Query("dueOn"); dueOn != "" {
+		filter.DueOn = &dueOn
+	}
+	if dueIn := c.Query("dueIn"); dueIn != "" {
+		dueRange := models.DueRange(strings.ToUpper(dueIn))
+		filter.DueIn = &dueRange
+	}
+
+	if err := database.CheckTaskFilter(filter); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+// queryBool returns the value of a boolean query parameter, nil when absent
+func queryBool(c *gin.Context, name string) (*bool, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q", name, value)
+	}
+	return &b, nil
+}
+
+// queryList returns the values of a repeated or comma separated query parameter
+func queryList(c *gin.Context, name string) []string {
+	var values []string
+	for _, raw := range c.QueryArray(name) {
+		for _, value := range strings.Split(raw, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
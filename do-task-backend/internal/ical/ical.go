@@ -0,0 +1,223 @@
+// Package ical encodes tasks as RFC 5545 iCalendar components
+package ical
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+)
+
+// ProductID identifies DoTask in generated calendars
+const ProductID = "-//DoTask//DoTask Calendar//EN"
+
+// maxLineOctets is the RFC 5545 limit for a content line, excluding CRLF
+const maxLineOctets = 75
+
+// dateTimeLayout is the UTC DATE-TIME form, e.g. 20240131T170000Z
+const dateTimeLayout = "20060102T150405Z"
+
+// dateLayout is the DATE form, e.g. 20240131
+const dateLayout = "20060102"
+
+// Writer emits iCalendar content lines with escaping and line folding
+type Writer struct {
+	w   io.Writer
+	err error
+}
+
+// NewWriter returns a Writer that writes to w
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Err returns the first error encountered while writing
+func (w *Writer) Err() error {
+	return w.err
+}
+
+// Line writes a raw property line, folding it at 75 octets
+func (w *Writer) Line(name string, value string) {
+	if w.err != nil {
+		return
+	}
+	_, w.err = io.WriteString(w.w, fold(name+":"+value))
+}
+
+// Text writes a TEXT property, escaping the value
+func (w *Writer) Text(name string, value string) {
+	w.Line(name, EscapeText(value))
+}
+
+// DateTime writes a DATE-TIME property in UTC
+func (w *Writer) DateTime(name string, t time.Time) {
+	w.Line(name, t.UTC().Format(dateTimeLayout))
+}
+
+// Date writes a DATE property holding the calendar day of t in its location
+func (w *Writer) Date(name string, t time.Time) {
+	w.Line(name+";VALUE=DATE", t.Format(dateLayout))
+}
+
+// taskDate writes one of a task's dates, as a DATE for all-day tasks
+func (w *Writer) taskDate(name string, task models.Task, t time.Time) {
+	if task.AllDay {
+		w.Date(name, t.In(task.Location()))
+		return
+	}
+	w.DateTime(name, t)
+}
+
+// Begin opens a component
+func (w *Writer) Begin(component string) {
+	w.Line("BEGIN", component)
+}
+
+// End closes a component
+func (w *Writer) End(component string) {
+	w.Line("END", component)
+}
+
+// BeginCalendar writes the VCALENDAR header
+func (w *Writer) BeginCalendar(name string) {
+	w.Begin("VCALENDAR")
+	w.Line("VERSION", "2.0")
+	w.Line("PRODID", ProductID)
+	w.Line("CALSCALE", "GREGORIAN")
+	if name != "" {
+		w.Text("X-WR-CALNAME", name)
+	}
+}
+
+// EndCalendar closes the VCALENDAR
+func (w *Writer) EndCalendar() {
+	w.End("VCALENDAR")
+}
+
+// TaskUID returns the iCalendar UID of a task, keeping the one a CalDAV client
+// assigned so the client recognises its own task
+func TaskUID(task models.Task) string {
+	if task.ICalUID != "" {
+		return task.ICalUID
+	}
+	return task.ID + "@dotask"
+}
+
+// Todo writes a task as a VTODO component
+func (w *Writer) Todo(task models.Task) {
+	w.Begin("VTODO")
+	w.writeCommon(task)
+	if task.StartDate != nil {
+		w.taskDate("DTSTART", task, *task.StartDate)
+	}
+	if task.DueDate != nil {
+		w.taskDate("DUE", task, *task.DueDate)
+	}
+	w.Line("STATUS", TodoStatus(task.Status))
+	if task.Status == models.TaskStatusCompleted {
+		if task.CompletedAt != nil {
+			w.DateTime("COMPLETED", *task.CompletedAt)
+		}
+		w.Line("PERCENT-COMPLETE", "100")
+	}
+	w.End("VTODO")
+}
+
+// Event writes a task as a VEVENT component starting at its due date, lasting
+// the whole day for all-day tasks. Calendar apps that ignore VTODO (most
+// subscription clients) still show these. Tasks without a due date have no
+// event and are skipped.
+func (w *Writer) Event(task models.Task) {
+	if task.DueDate == nil {
+		return
+	}
+	w.Begin("VEVENT")
+	w.writeCommon(task)
+	w.taskDate("DTSTART", task, *task.DueDate)
+	if task.AllDay {
+		w.Line("DURATION", "P1D")
+	} else {
+		w.Line("DURATION", "PT30M")
+	}
+	w.Line("TRANSP", "TRANSPARENT")
+	w.End("VEVENT")
+}
+
+func (w *Writer) writeCommon(task models.Task) {
+	w.Text("UID", TaskUID(task))
+	w.DateTime("DTSTAMP", task.UpdatedAt)
+	w.DateTime("CREATED", task.CreatedAt)
+	w.DateTime("LAST-MODIFIED", task.UpdatedAt)
+	w.Text("SUMMARY", task.Title)
+	if task.Description != "" {
+		w.Text("DESCRIPTION", task.Description)
+	}
+	w.Line("PRIORITY", fmt.Sprint(Priority(task.Priority)))
+	if len(task.Tags) > 0 {
+		escaped := make([]string, len(task.Tags))
+		for i, tag := range task.Tags {
+			escaped[i] = EscapeText(tag)
+		}
+		w.Line("CATEGORIES", strings.Join(escaped, ","))
+	}
+}
+
+// TodoStatus maps a task status to a VTODO STATUS value
+func TodoStatus(status models.TaskStatus) string {
+	switch status {
+	case models.TaskStatusInProgress:
+		return "IN-PROCESS"
+	case models.TaskStatusCompleted:
+		return "COMPLETED"
+	default:
+		return "NEEDS-ACTION"
+	}
+}
+
+// Priority maps a task priority to the RFC 5545 1 (highest) .. 9 (lowest) scale
+func Priority(priority models.TaskPriority) int {
+	switch priority {
+	case models.TaskPriorityHigh:
+		return 1
+	case models.TaskPriorityLow:
+		return 9
+	default:
+		return 5
+	}
+}
+
+// EscapeText escapes a TEXT value as described in RFC 5545 section 3.3.11
+func EscapeText(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", `\n`,
+	).Replace(s)
+}
+
+// fold splits a content line into 75-octet chunks joined by CRLF + space,
+// never breaking a UTF-8 sequence
+func fold(line string) string {
+	var b strings.Builder
+	limit := maxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// Continuation lines lose one octet to the leading space
+		limit = maxLineOctets - 1
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+	return b.String()
+}
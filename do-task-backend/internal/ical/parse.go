@@ -0,0 +1,349 @@
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Property is a single content line of an iCalendar object
+type Property struct {
+	Name   string
+	Params map[string]string
+	Value  string
+}
+
+// Component is a BEGIN/END block together with its properties and children
+type Component struct {
+	Name       string
+	Properties []Property
+	Children   []*Component
+}
+
+// Get returns the first property called name, or nil
+func (c *Component) Get(name string) *Property {
+	for i := range c.Properties {
+		if c.Properties[i].Name == name {
+			return &c.Properties[i]
+		}
+	}
+	return nil
+}
+
+// Text returns the unescaped value of the first TEXT property called name
+func (c *Component) Text(name string) string {
+	if p := c.Get(name); p != nil {
+		return UnescapeText(p.Value)
+	}
+	return ""
+}
+
+// Parse reads a single iCalendar object, normally a VCALENDAR
+func Parse(r io.Reader) (*Component, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var root *Component
+	var stack []*Component
+	for n, line := range lines {
+		prop, err := parseLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n+1, err)
+		}
+
+		switch prop.Name {
+		case "BEGIN":
+			component := &Component{Name: strings.ToUpper(prop.Value)}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, component)
+			} else if root == nil {
+				root = component
+			} else {
+				return nil, errors.New("more than one top-level component")
+			}
+			stack = append(stack, component)
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1].Name != strings.ToUpper(prop.Value) {
+				return nil, fmt.Errorf("line %d: unexpected END:%s", n+1, prop.Value)
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("line %d: property outside of a component", n+1)
+			}
+			current := stack[len(stack)-1]
+			current.Properties = append(current.Properties, prop)
+		}
+	}
+
+	if root == nil {
+		return nil, errors.New("no iCalendar component found")
+	}
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("component %s is not closed", stack[len(stack)-1].Name)
+	}
+	return root, nil
+}
+
+// unfold joins folded content lines
+func unfold(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		if (line[0] == ' ' || line[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read calendar data: %w", err)
+	}
+	return lines, nil
+}
+
+// parseLine splits "NAME;PARAM=value:VALUE", honouring quoted parameter values
+func parseLine(line string) (Property, error) {
+	prop := Property{Params: map[string]string{}}
+
+	inQuotes := false
+	nameEnd := -1
+	valueStart := -1
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '"':
+			inQuotes = !inQuotes
+		case ';':
+			if !inQuotes && nameEnd < 0 {
+				nameEnd = i
+			}
+		case ':':
+			if !inQuotes {
+				valueStart = i
+			}
+		}
+		if valueStart >= 0 {
+			break
+		}
+	}
+	if valueStart < 0 {
+		return prop, fmt.Errorf("missing ':' in %q", line)
+	}
+	if nameEnd < 0 {
+		nameEnd = valueStart
+	}
+
+	prop.Name = strings.ToUpper(line[:nameEnd])
+	prop.Value = line[valueStart+1:]
+	if nameEnd < valueStart {
+		for _, param := range splitParams(line[nameEnd+1 : valueStart]) {
+			key, value, _ := strings.Cut(param, "=")
+			prop.Params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+		}
+	}
+
+	return prop, nil
+}
+
+func splitParams(s string) []string {
+	var params []string
+	inQuotes := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			inQuotes = !inQuotes
+		case ';':
+			if !inQuotes {
+				params = append(params, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(params, s[start:])
+}
+
+// UnescapeText reverses EscapeText
+func UnescapeText(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+			switch s[i] {
+			case 'n', 'N':
+				b.WriteByte('\n')
+			default:
+				b.WriteByte(s[i])
+			}
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// SplitList splits a comma separated TEXT list such as CATEGORIES
+func SplitList(s string) []string {
+	var items []string
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s):
+			b.WriteByte('\\')
+			b.WriteByte(s[i+1])
+			i++
+		case s[i] == ',':
+			items = append(items, UnescapeText(b.String()))
+			b.Reset()
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return append(items, UnescapeText(b.String()))
+}
+
+// Time parses a DATE or DATE-TIME property. Floating times and DATE values are
+// interpreted in UTC; TZID parameters are resolved through the Go time zone
+// database. allDay reports a DATE value.
+func (p *Property) Time() (t time.Time, allDay bool, err error) {
+	value := strings.TrimSpace(p.Value)
+
+	if p.Params["VALUE"] == "DATE" || len(value) == 8 {
+		t, err = time.Parse(dateLayout, value)
+		return t, true, err
+	}
+
+	loc := time.UTC
+	if tzid := p.Params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		t, err = time.Parse(dateTimeLayout, value)
+		return t, false, err
+	}
+	t, err = time.ParseInLocation("20060102T150405", value, loc)
+	return t, false, err
+}
+
+// Todo is the subset of a VTODO that DoTask understands
+type Todo struct {
+	UID         string
+	Summary     string
+	Description string
+	Status      string
+	Priority    int
+	Due         *time.Time
+	DueAllDay   bool
+	Start       *time.Time
+	StartAllDay bool
+	Completed   *time.Time
+	Categories  []string
+}
+
+// ParseTodo reads a VCALENDAR holding exactly one VTODO
+func ParseTodo(r io.Reader) (Todo, error) {
+	root, err := Parse(r)
+	if err != nil {
+		return Todo{}, err
+	}
+	if root.Name != "VCALENDAR" {
+		return Todo{}, errors.New("expected a VCALENDAR object")
+	}
+
+	var vtodo *Component
+	for _, child := range root.Children {
+		switch child.Name {
+		case "VTODO":
+			if vtodo != nil {
+				return Todo{}, errors.New("only one VTODO per resource is supported")
+			}
+			vtodo = child
+		case "VTIMEZONE":
+		default:
+			return Todo{}, fmt.Errorf("unsupported component %s, only VTODO is stored", child.Name)
+		}
+	}
+	if vtodo == nil {
+		return Todo{}, errors.New("no VTODO found")
+	}
+
+	todo := Todo{
+		UID:         strings.TrimSpace(vtodo.Text("UID")),
+		Summary:     strings.TrimSpace(vtodo.Text("SUMMARY")),
+		Description: vtodo.Text("DESCRIPTION"),
+		Status:      strings.ToUpper(strings.TrimSpace(vtodo.Get("STATUS").valueOrEmpty())),
+	}
+
+	if p := vtodo.Get("PRIORITY"); p != nil {
+		priority, err := strconv.Atoi(strings.TrimSpace(p.Value))
+		if err != nil || priority < 0 || priority > 9 {
+			return Todo{}, fmt.Errorf("invalid PRIORITY %q", p.Value)
+		}
+		todo.Priority = priority
+	}
+
+	if p := vtodo.Get("DUE"); p != nil {
+		due, allDay, err := p.Time()
+		if err != nil {
+			return Todo{}, fmt.Errorf("invalid DUE %q", p.Value)
+		}
+		todo.Due = &due
+		todo.DueAllDay = allDay
+	}
+
+	if p := vtodo.Get("DTSTART"); p != nil {
+		start, allDay, err := p.Time()
+		if err != nil {
+			return Todo{}, fmt.Errorf("invalid DTSTART %q", p.Value)
+		}
+		todo.Start = &start
+		todo.StartAllDay = allDay
+	}
+
+	if p := vtodo.Get("COMPLETED"); p != nil {
+		completed, _, err := p.Time()
+		if err != nil {
+			return Todo{}, fmt.Errorf("invalid COMPLETED %q", p.Value)
+		}
+		todo.Completed = &completed
+	}
+
+	for _, p := range vtodo.Properties {
+		if p.Name != "CATEGORIES" {
+			continue
+		}
+		for _, category := range SplitList(p.Value) {
+			if category = strings.TrimSpace(category); category != "" {
+				todo.Categories = append(todo.Categories, category)
+			}
+		}
+	}
+
+	return todo, nil
+}
+
+func (p *Property) valueOrEmpty() string {
+	if p == nil {
+		return ""
+	}
+	return p.Value
+}
@@ -0,0 +1,495 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/export"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+)
+
+// Row is a task read from an import file, normalised to DoTask's model
+type Row struct {
+	Line        int // 1-based row number reported back in errors
+	ExternalID  string
+	Title       string
+	Description string
+	Status      models.TaskStatus
+	Priority    models.TaskPriority
+	DueDate     *time.Time
+	StartDate   *time.Time
+	AllDay      bool // the dates are calendar days rather than instants
+	CompletedAt *time.Time
+	Category    string // category path, e.g. "Work / Clients"
+	Tags        []string
+	Err         error // set when the row could not be parsed
+}
+
+// Parse reads every row of an import file in the given format
+func Parse(format models.ImportFormat, r io.Reader) ([]Row, error) {
+	switch format {
+	case models.ImportFormatCSV:
+		return parseCSV(r)
+	case models.ImportFormatJSON:
+		return parseJSON(r)
+	case models.ImportFormatTodoist:
+		return parseTodoist(r)
+	case models.ImportFormatTrello:
+		return parseTrello(r)
+	default:
+		return nil, fmt.Errorf("unsupported import format %q", format)
+	}
+}
+
+// parseCSV reads DoTask's own CSV export, or any CSV with a compatible header
+func parseCSV(r io.Reader) ([]Row, error) {
+	reader := csv.NewReader(skipBOM(r))
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		name = strings.NewReplacer("_", "", " ", "").Replace(name)
+		columns[name] = i
+	}
+	if _, ok := columns["title"]; !ok {
+		return nil, errors.New("CSV header must contain a title column")
+	}
+
+	get := func(record []string, names ...string) string {
+		for _, name := range names {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+		}
+		return ""
+	}
+
+	var rows []Row
+	line := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			rows = append(rows, Row{Line: line, Err: err})
+			continue
+		}
+
+		row := Row{
+			Line:        line,
+			ExternalID:  get(record, "id"),
+			Title:       get(record, "title"),
+			Description: get(record, "description"),
+			Category:    get(record, "category"),
+			Tags:        splitTags(get(record, "tags")),
+		}
+		row.Err = fillCommon(&row, get(record, "status"), get(record, "priority"), get(record, "duedate", "due"))
+		if row.Err == nil {
+			row.Err = fillCompletedAt(&row, get(record, "completedat"))
+		}
+		if row.Err == nil {
+			row.Err = fillSchedule(&row, get(record, "allday"), get(record, "startdate"))
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// parseJSON reads DoTask's JSON task or account export, or a bare array of tasks
+func parseJSON(r io.Reader) ([]Row, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JSON: %w", err)
+	}
+	data = bytes.TrimSpace(data)
+
+	var tasks []export.Task
+	if len(data) > 0 && data[0] == '[' {
+		err = json.Unmarshal(data, &tasks)
+	} else {
+		var document struct {
+			Tasks []export.Task `json:"tasks"`
+		}
+		err = json.Unmarshal(data, &document)
+		tasks = document.Tasks
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON export: %w", err)
+	}
+
+	rows := make([]Row, len(tasks))
+	for i, task := range tasks {
+		row := Row{
+			Line:        i + 1,
+			ExternalID:  task.ID,
+			Title:       strings.TrimSpace(task.Title),
+			Description: task.Description,
+			Category:    task.Category,
+			Tags:        normalizeTags(task.Tags),
+			AllDay:      task.AllDay,
+		}
+		dueDate := ""
+		if task.DueDate != nil {
+			dueDate = *task.DueDate
+		}
+		row.Err = fillCommon(&row, task.Status, task.Priority, dueDate)
+		if row.Err == nil && task.StartDate != nil && *task.StartDate != "" {
+			if row.StartDate, err = parseDate(*task.StartDate); err != nil {
+				row.Err = fmt.Errorf("could not understand start date %q", *task.StartDate)
+			}
+		}
+		if row.Err == nil && task.CompletedAt != nil {
+			row.Err = fillCompletedAt(&row, *task.CompletedAt)
+		}
+		rows[i] = row
+	}
+
+	return rows, nil
+}
+
+// parseTodoist reads a Todoist project CSV export. Sections become
+// subcategories, @labels in the content become tags and notes are appended
+// to the description of the task they belong to.
+func parseTodoist(r io.Reader) ([]Row, error) {
+	reader := csv.NewReader(skipBOM(r))
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Todoist header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToUpper(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"TYPE", "CONTENT"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("Todoist export must contain a %s column", required)
+		}
+	}
+
+	get := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var rows []Row
+	section := ""
+	line := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			rows = append(rows, Row{Line: line, Err: err})
+			continue
+		}
+
+		content := get(record, "CONTENT")
+		switch strings.ToLower(get(record, "TYPE")) {
+		case "section":
+			section = content
+		case "note":
+			if len(rows) > 0 && content != "" {
+				last := &rows[len(rows)-1]
+				last.Description = strings.TrimSpace(last.Description + "\n\n" + content)
+			}
+		case "task":
+			title, labels := extractLabels(content)
+			row := Row{
+				Line:        line,
+				Title:       title,
+				Description: get(record, "DESCRIPTION"),
+				Status:      models.TaskStatusTodo,
+				Priority:    todoistPriority(get(record, "PRIORITY")),
+				Category:    section,
+				Tags:        labels,
+			}
+			if date := get(record, "DATE"); date != "" {
+				dueDate, err := parseDate(date)
+				if err != nil {
+					row.Err = fmt.Errorf("could not understand due date %q", date)
+				}
+				row.DueDate = dueDate
+			}
+			row.ExternalID = contentKey(section, content, get(record, "DATE"))
+			rows = append(rows, row)
+		}
+	}
+
+	return rows, nil
+}
+
+// todoistPriority maps the PRIORITY column of a Todoist CSV export, which
+// uses the app's numbering: 1 is p1 (urgent) and plain tasks are 4
+func todoistPriority(value string) models.TaskPriority {
+	switch value {
+	case "1":
+		return models.TaskPriorityHigh
+	case "2":
+		return models.TaskPriorityMedium
+	default:
+		return models.TaskPriorityLow
+	}
+}
+
+// extractLabels removes @label tokens from Todoist content and returns them as tags
+func extractLabels(content string) (string, []string) {
+	var words, labels []string
+	for _, word := range strings.Fields(content) {
+		if len(word) > 1 && strings.HasPrefix(word, "@") {
+			labels = append(labels, strings.TrimPrefix(word, "@"))
+			continue
+		}
+		words = append(words, word)
+	}
+	return strings.Join(words, " "), normalizeTags(labels)
+}
+
+// trelloBoard is the subset of a Trello board JSON export we read
+type trelloBoard struct {
+	Name  string `json:"name"`
+	Lists []struct {
+		ID     string `json:"id"`
+		Name   string `json:"name"`
+		Closed bool   `json:"closed"`
+	} `json:"lists"`
+	Cards []struct {
+		ID          string  `json:"id"`
+		Name        string  `json:"name"`
+		Desc        string  `json:"desc"`
+		IDList      string  `json:"idList"`
+		Due         *string `json:"due"`
+		DueComplete bool    `json:"dueComplete"`
+		Closed      bool    `json:"closed"`
+		Labels      []struct {
+			Name  string `json:"name"`
+			Color string `json:"color"`
+		} `json:"labels"`
+	} `json:"cards"`
+}
+
+// parseTrello reads a Trello board JSON export. Each list becomes a
+// subcategory of a category named after the board; archived cards and lists
+// are ignored.
+func parseTrello(r io.Reader) ([]Row, error) {
+	var board trelloBoard
+	if err := json.NewDecoder(r).Decode(&board); err != nil {
+		return nil, fmt.Errorf("invalid Trello board export: %w", err)
+	}
+
+	lists := make(map[string]string, len(board.Lists))
+	closedLists := make(map[string]bool)
+	for _, list := range board.Lists {
+		lists[list.ID] = strings.TrimSpace(list.Name)
+		if list.Closed {
+			closedLists[list.ID] = true
+		}
+	}
+
+	boardName := strings.TrimSpace(board.Name)
+	var rows []Row
+	for i, card := range board.Cards {
+		if card.Closed || closedLists[card.IDList] {
+			continue
+		}
+
+		var tags []string
+		for _, label := range card.Labels {
+			if label.Name != "" {
+				tags = append(tags, label.Name)
+			} else if label.Color != "" {
+				tags = append(tags, label.Color)
+			}
+		}
+
+		category := boardName
+		if list := lists[card.IDList]; list != "" {
+			if category != "" {
+				category += models.CategoryPathSeparator
+			}
+			category += list
+		}
+
+		row := Row{
+			Line:        i + 1,
+			ExternalID:  card.ID,
+			Title:       strings.TrimSpace(card.Name),
+			Description: card.Desc,
+			Status:      models.TaskStatusTodo,
+			Priority:    models.TaskPriorityMedium,
+			Category:    category,
+			Tags:        normalizeTags(tags),
+		}
+		if card.DueComplete {
+			row.Status = models.TaskStatusCompleted
+		}
+		if card.Due != nil && *card.Due != "" {
+			dueDate, err := parseDate(*card.Due)
+			if err != nil {
+				row.Err = fmt.Errorf("could not understand due date %q", *card.Due)
+			}
+			row.DueDate = dueDate
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// fillCommon parses the status, priority and due date columns shared by the
+// DoTask formats
+func fillCommon(row *Row, status, priority, dueDate string) error {
+	var err error
+	if row.Status, err = parseStatus(status); err != nil {
+		return err
+	}
+	if row.Priority, err = parsePriority(priority); err != nil {
+		return err
+	}
+	if dueDate != "" {
+		if row.DueDate, err = parseDate(dueDate); err != nil {
+			return fmt.Errorf("could not understand due date %q", dueDate)
+		}
+	}
+	return nil
+}
+
+// fillSchedule parses the all-day flag and start date columns of DoTask's CSV
+// export
+func fillSchedule(row *Row, allDay, startDate string) error {
+	if allDay != "" {
+		var err error
+		if row.AllDay, err = strconv.ParseBool(allDay); err != nil {
+			return fmt.Errorf("could not understand all_day %q", allDay)
+		}
+	}
+	if startDate != "" {
+		var err error
+		if row.StartDate, err = parseDate(startDate); err != nil {
+			return fmt.Errorf("could not understand start date %q", startDate)
+		}
+	}
+	return nil
+}
+
+// fillCompletedAt parses when a completed row was finished; other rows ignore it
+func fillCompletedAt(row *Row, completedAt string) error {
+	if completedAt == "" || row.Status != models.TaskStatusCompleted {
+		return nil
+	}
+	var err error
+	if row.CompletedAt, err = parseDate(completedAt); err != nil {
+		return fmt.Errorf("could not understand completion date %q", completedAt)
+	}
+	return nil
+}
+
+func parseStatus(value string) (models.TaskStatus, error) {
+	switch strings.ToUpper(strings.NewReplacer(" ", "_", "-", "_").Replace(strings.TrimSpace(value))) {
+	case "", "TODO", "TO_DO", "OPEN":
+		return models.TaskStatusTodo, nil
+	case "IN_PROGRESS", "DOING", "STARTED":
+		return models.TaskStatusInProgress, nil
+	case "COMPLETED", "DONE", "COMPLETE", "CLOSED":
+		return models.TaskStatusCompleted, nil
+	default:
+		return "", fmt.Errorf("unknown status %q", value)
+	}
+}
+
+func parsePriority(value string) (models.TaskPriority, error) {
+	switch strings.ToUpper(strings.TrimSpace(value)) {
+	case "LOW":
+		return models.TaskPriorityLow, nil
+	case "", "MEDIUM", "NORMAL":
+		return models.TaskPriorityMedium, nil
+	case "HIGH", "URGENT":
+		return models.TaskPriorityHigh, nil
+	default:
+		return "", fmt.Errorf("unknown priority %q", value)
+	}
+}
+
+// dateLayouts are the due date formats accepted by the importers
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05.000Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// parseDate accepts RFC 3339 timestamps and plain dates; dates without a
+// zone are read as UTC
+func parseDate(value string) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			t = t.UTC()
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("unrecognised date %q", value)
+}
+
+// splitTags splits a comma or semicolon separated tag list
+func splitTags(value string) []string {
+	return normalizeTags(strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ';'
+	}))
+}
+
+// normalizeTags trims tags, strips a leading '#' and drops empty and
+// case-insensitive duplicate entries
+func normalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	var result []string
+	for _, tag := range tags {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "#")
+		key := strings.ToLower(tag)
+		if tag == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, tag)
+	}
+	return result
+}
+
+// contentKey builds a stable identifier for rows whose source has no IDs
+func contentKey(parts ...string) string {
+	return strings.Join(parts, "\x1f")
+}
+
+// skipBOM drops a UTF-8 byte order mark, which spreadsheet tools like to add
+func skipBOM(r io.Reader) io.Reader {
+	br := bufio.NewReader(r)
+	if bom, err := br.Peek(3); err == nil && bytes.Equal(bom, []byte{0xEF, 0xBB, 0xBF}) {
+		br.Discard(3)
+	}
+	return br
+}
@@ -0,0 +1,147 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/export"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+)
+
+func TestTodoistPriority(t *testing.T) {
+	export := `TYPE,CONTENT,DESCRIPTION,PRIORITY,INDENT,AUTHOR,RESPONSIBLE,DATE,DATE_LANG,TIMEZONE,DURATION,DURATION_UNIT
+task,Renew passport,,1,1,Sam (12345),,,en,Europe/London,,
+task,Book flights,,2,1,Sam (12345),,,en,Europe/London,,
+task,Pack bags,,3,1,Sam (12345),,,en,Europe/London,,
+task,Water the plants,,4,1,Sam (12345),,,en,Europe/London,,
+`
+	tests := []struct {
+		title    string
+		priority models.TaskPriority
+	}{
+		{"Renew passport", models.TaskPriorityHigh},
+		{"Book flights", models.TaskPriorityMedium},
+		{"Pack bags", models.TaskPriorityLow},
+		{"Water the plants", models.TaskPriorityLow},
+	}
+
+	rows, err := parseTodoist(strings.NewReader(export))
+	if err != nil {
+		t.Fatalf("parseTodoist: %v", err)
+	}
+	if len(rows) != len(tests) {
+		t.Fatalf("got %d rows, want %d", len(rows), len(tests))
+	}
+	for i, tt := range tests {
+		if rows[i].Title != tt.title {
+			t.Errorf("row %d: title = %q, want %q", i, rows[i].Title, tt.title)
+		}
+		if rows[i].Priority != tt.priority {
+			t.Errorf("%s: priority = %s, want %s", tt.title, rows[i].Priority, tt.priority)
+		}
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	loc, err := time.LoadLocation("Pacific/Auckland")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+	day := func(y int, m time.Month, d int) *time.Time {
+		v := time.Date(y, m, d, 0, 0, 0, 0, loc)
+		return &v
+	}
+	instant := func(value string) *time.Time {
+		v, _ := time.Parse(time.RFC3339, value)
+		return &v
+	}
+	tasks := []models.Task{
+		{
+			ID: "all-day", Title: "Renew passport", Description: "Bring photos",
+			Status: models.TaskStatusInProgress, Priority: models.TaskPriorityHigh,
+			AllDay: true, StartDate: day(2026, time.March, 2), DueDate: day(2026, time.March, 6),
+			Timezone: "Pacific/Auckland", CategoryID: "c1", Tags: []string{"errands"},
+		},
+		{
+			ID: "timed", Title: "Call the bank",
+			Status: models.TaskStatusCompleted, Priority: models.TaskPriorityLow,
+			CompletedAt: instant("2026-03-02T16:05:00Z"),
+			StartDate:   instant("2026-03-02T09:00:00Z"), DueDate: instant("2026-03-02T17:30:00Z"),
+			Timezone: "Pacific/Auckland", CategoryID: "c1",
+		},
+	}
+	paths := map[string]string{"c1": "Personal / Admin"}
+
+	var document struct {
+		Tasks []export.Task `json:"tasks"`
+	}
+	for _, task := range tasks {
+		document.Tasks = append(document.Tasks, export.NewTask(task, paths))
+	}
+	data, err := json.Marshal(document)
+	if err != nil {
+		t.Fatalf("marshal export: %v", err)
+	}
+
+	rows, err := Parse(models.ImportFormatJSON, bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(rows) != len(tasks) {
+		t.Fatalf("got %d rows, want %d", len(rows), len(tasks))
+	}
+	for i, exported := range document.Tasks {
+		row := rows[i]
+		if row.Err != nil {
+			t.Fatalf("%s: %v", exported.Title, row.Err)
+		}
+		input := taskInput(models.ImportFormatJSON, row, "c1", "u1")
+
+		if input.Title != exported.Title || input.Description != exported.Description {
+			t.Errorf("%s: title/description = %q/%q", exported.Title, input.Title, input.Description)
+		}
+		if string(input.Status) != exported.Status || string(input.Priority) != exported.Priority {
+			t.Errorf("%s: status/priority = %s/%s, want %s/%s", exported.Title, input.Status, input.Priority, exported.Status, exported.Priority)
+		}
+		if input.AllDay == nil || *input.AllDay != exported.AllDay {
+			t.Errorf("%s: allDay = %v, want %v", exported.Title, input.AllDay, exported.AllDay)
+		}
+		if !equalDates(input.DueDate, exported.DueDate) {
+			t.Errorf("%s: dueDate = %v, want %v", exported.Title, deref(input.DueDate), deref(exported.DueDate))
+		}
+		if !equalDates(input.StartDate, exported.StartDate) {
+			t.Errorf("%s: startDate = %v, want %v", exported.Title, deref(input.StartDate), deref(exported.StartDate))
+		}
+		var completedAt *string
+		if input.CompletedAt != nil {
+			formatted := input.CompletedAt.Format(time.RFC3339)
+			completedAt = &formatted
+		}
+		if !equalDates(completedAt, exported.CompletedAt) {
+			t.Errorf("%s: completedAt = %v, want %v", exported.Title, deref(completedAt), deref(exported.CompletedAt))
+		}
+		if row.Category != exported.Category {
+			t.Errorf("%s: category = %q, want %q", exported.Title, row.Category, exported.Category)
+		}
+		if strings.Join(input.Tags, ",") != strings.Join(exported.Tags, ",") {
+			t.Errorf("%s: tags = %v, want %v", exported.Title, input.Tags, exported.Tags)
+		}
+	}
+}
+
+func equalDates(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return "<nil>"
+	}
+	return *s
+}
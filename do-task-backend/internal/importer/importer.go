@@ -0,0 +1,283 @@
+// Package importer reads tasks exported from DoTask and other task managers
+// and creates them for a user
+package importer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/apperr"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/database"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/export"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+)
+
+const (
+	// MaxFileSize caps the size of an uploaded import file
+	MaxFileSize = 10 << 20
+	// MaxRows caps how many tasks one import may contain
+	MaxRows = 5000
+	// batchSize is the number of tasks written per transaction
+	batchSize = 100
+	// defaultCategory receives rows without a category when the user has none
+	defaultCategory = "Imported"
+	// maxCategoryNameLength mirrors the size of the categories.name column
+	maxCategoryNameLength = 255
+)
+
+// Importer writes parsed rows into the database for a user
+type Importer struct {
+	DB *database.DB
+}
+
+// Run parses an import file and creates its tasks for userID. With dryRun set
+// nothing is written; the result reports what would have happened.
+func (im *Importer) Run(userID string, format models.ImportFormat, r io.Reader, dryRun bool) (models.ImportResult, error) {
+	result := models.ImportResult{
+		DryRun:            dryRun,
+		CreatedCategories: []string{},
+		Errors:            []*models.ImportRowError{},
+	}
+
+	// Parsing only fails on the file's content, so the reason is the client's to see
+	rows, err := Parse(format, io.LimitReader(r, MaxFileSize))
+	if err != nil {
+		return result, apperr.Invalid("file", "%v", err)
+	}
+	if len(rows) > MaxRows {
+		return result, apperr.Invalid("file", "imports are limited to %d tasks", MaxRows)
+	}
+	result.Total = len(rows)
+
+	fail := func(row Row, err error) {
+		message, ok := database.ClientMessage(err)
+		if !ok {
+			log.Printf("import: row %d failed: %v", row.Line, err)
+		}
+		result.Failed++
+		result.Errors = append(result.Errors, &models.ImportRowError{Row: row.Line, Message: message})
+	}
+
+	categories, err := newCategoryResolver(im.DB, userID, dryRun)
+	if err != nil {
+		return result, err
+	}
+
+	type pending struct {
+		row   Row
+		input models.CreateTaskInput
+	}
+	var valid []pending
+	for _, row := range rows {
+		if row.Err == nil {
+			row.Err = validate(row)
+		}
+		if row.Err != nil {
+			// Problems with the row's own content are the client's to see
+			fail(row, apperr.Invalid("", "%v", row.Err))
+			continue
+		}
+
+		categoryID, err := categories.resolve(row.Category)
+		if err != nil {
+			fail(row, err)
+			continue
+		}
+
+		valid = append(valid, pending{row: row, input: taskInput(format, row, categoryID, userID)})
+	}
+	result.CreatedCategories = append(result.CreatedCategories, categories.created...)
+
+	for start := 0; start < len(valid); start += batchSize {
+		end := start + batchSize
+		if end > len(valid) {
+			end = len(valid)
+		}
+		batch := valid[start:end]
+
+		if dryRun {
+			keys := make([]string, len(batch))
+			for i, p := range batch {
+				keys[i] = p.input.ImportKey
+			}
+			existing, err := im.DB.GetImportKeys(userID, keys)
+			if err != nil {
+				return result, err
+			}
+			for _, p := range batch {
+				if existing[p.input.ImportKey] {
+					result.Skipped++
+				} else {
+					result.Created++
+				}
+			}
+			continue
+		}
+
+		inputs := make([]models.CreateTaskInput, len(batch))
+		for i, p := range batch {
+			inputs[i] = p.input
+		}
+		outcomes, err := im.DB.ImportTasks(inputs)
+		if err != nil {
+			return result, err
+		}
+		for i, outcome := range outcomes {
+			switch {
+			case outcome == nil:
+				result.Created++
+			case errors.Is(outcome, database.ErrDuplicateImport):
+				result.Skipped++
+			default:
+				fail(batch[i].row, outcome)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// taskInput turns a parsed row into the input that creates its task
+func taskInput(format models.ImportFormat, row Row, categoryID string, userID string) models.CreateTaskInput {
+	allDay := row.AllDay
+	return models.CreateTaskInput{
+		Title:       row.Title,
+		Description: row.Description,
+		Status:      row.Status,
+		Priority:    row.Priority,
+		DueDate:     rowDate(row.DueDate, allDay),
+		StartDate:   rowDate(row.StartDate, allDay),
+		AllDay:      &allDay,
+		CategoryID:  categoryID,
+		UserID:      userID,
+		Tags:        row.Tags,
+		ImportKey:   importKey(format, row),
+		CompletedAt: row.CompletedAt,
+	}
+}
+
+// rowDate formats a row's date for the task inputs: a calendar day for
+// all-day rows and an RFC 3339 timestamp otherwise
+func rowDate(date *time.Time, allDay bool) *string {
+	if date == nil {
+		return nil
+	}
+	layout := time.RFC3339
+	if allDay {
+		layout = "2006-01-02"
+	}
+	formatted := date.Format(layout)
+	return &formatted
+}
+
+// validate checks the fields every task needs
+func validate(row Row) error {
+	if row.Title == "" {
+		return errors.New("title is required")
+	}
+	if utf8.RuneCountInString(row.Title) > 500 {
+		return errors.New("title cannot be longer than 500 characters")
+	}
+	return nil
+}
+
+// importKey identifies a row across imports so the same row is never created twice
+func importKey(format models.ImportFormat, row Row) string {
+	source := row.ExternalID
+	if source == "" {
+		due := ""
+		if row.DueDate != nil {
+			due = row.DueDate.Format(time.RFC3339)
+		}
+		source = contentKey(row.Title, row.Category, due)
+	}
+	sum := sha256.Sum256([]byte(string(format) + "\x00" + source))
+	return hex.EncodeToString(sum[:])
+}
+
+// categoryResolver maps category paths from the import onto the user's
+// categories, creating missing ones (or pretending to, on a dry run)
+type categoryResolver struct {
+	db        *database.DB
+	userID    string
+	dryRun    bool
+	byPath    map[string]string // lower-cased path -> category ID
+	defaultID string
+	created   []string
+}
+
+func newCategoryResolver(db *database.DB, userID string, dryRun bool) (*categoryResolver, error) {
+	categories, err := db.GetAllCategories(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	resolver := &categoryResolver{
+		db:     db,
+		userID: userID,
+		dryRun: dryRun,
+		byPath: make(map[string]string, len(categories)),
+	}
+	for id, path := range export.CategoryPaths(categories) {
+		resolver.byPath[strings.ToLower(path)] = id
+	}
+	// Uncategorised rows land in the first category, like createTask does
+	if len(categories) > 0 {
+		resolver.defaultID = categories[0].ID
+	}
+
+	return resolver, nil
+}
+
+// resolve returns the ID of the category at path, creating each missing level
+func (cr *categoryResolver) resolve(path string) (string, error) {
+	var names []string
+	for _, name := range strings.Split(path, strings.TrimSpace(models.CategoryPathSeparator)) {
+		if name = strings.TrimSpace(name); name != "" {
+			if utf8.RuneCountInString(name) > maxCategoryNameLength {
+				return "", apperr.Invalid("category", "category names cannot be longer than %d characters", maxCategoryNameLength)
+			}
+			names = append(names, name)
+		}
+	}
+
+	if len(names) == 0 {
+		if cr.defaultID != "" {
+			return cr.defaultID, nil
+		}
+		names = []string{defaultCategory}
+	}
+
+	var parentID *string
+	id := ""
+	for i := range names {
+		key := strings.ToLower(strings.Join(names[:i+1], models.CategoryPathSeparator))
+		if existing, ok := cr.byPath[key]; ok {
+			id = existing
+		} else {
+			displayPath := strings.Join(names[:i+1], models.CategoryPathSeparator)
+			if cr.dryRun {
+				id = "dry-run:" + key
+			} else {
+				category, err := cr.db.CreateCategory(names[i], parentID, cr.userID)
+				if err != nil {
+					return "", fmt.Errorf("failed to create category %q: %w", displayPath, err)
+				}
+				id = category.ID
+			}
+			cr.byPath[key] = id
+			cr.created = append(cr.created, displayPath)
+		}
+		current := id
+		parentID = &current
+	}
+
+	return id, nil
+}
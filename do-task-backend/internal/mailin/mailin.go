@@ -0,0 +1,141 @@
+// Package mailin turns email sent to a user's secret address into tasks. The
+// subject becomes the title, the plain-text body the description and any
+// attached files are stored as task attachments.
+package mailin
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/mail"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/apperr"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/database"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/quickadd"
+)
+
+const (
+	// maxTitleLength mirrors the size of the tasks.title column
+	maxTitleLength = 500
+	untitled       = "(no subject)"
+)
+
+// replyPrefix matches the reply and forward markers mail clients put in front of a subject
+var replyPrefix = regexp.MustCompile(`(?i)^\s*((re|fwd?|aw|wg)\s*(\[\d+\])?\s*:\s*)+`)
+
+// ErrNotConfigured is returned when INBOUND_EMAIL_DOMAIN is not set
+var ErrNotConfigured = apperr.Forbidden("email-to-task is not configured on this server")
+
+// Gateway creates tasks from messages addressed to <token>@Domain
+type Gateway struct {
+	DB     *database.DB
+	Domain string
+}
+
+// NewGateway returns a gateway for the domain in INBOUND_EMAIL_DOMAIN
+func NewGateway(db *database.DB) *Gateway {
+	return &Gateway{DB: db, Domain: domain()}
+}
+
+func domain() string {
+	return strings.ToLower(strings.TrimSpace(os.Getenv("INBOUND_EMAIL_DOMAIN")))
+}
+
+// NewToken returns a random local part for an inbound address. Hex keeps it
+// intact through mail systems that change the case of addresses.
+func NewToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Address returns the inbound address for a token
+func Address(token string) (string, error) {
+	d := domain()
+	if d == "" {
+		return "", ErrNotConfigured
+	}
+	return token + "@" + d, nil
+}
+
+// Lookup resolves a recipient address to the ID of the user it belongs to
+func (g *Gateway) Lookup(recipient string) (string, error) {
+	addr, err := mail.ParseAddress(recipient)
+	if err != nil {
+		return "", err
+	}
+
+	at := strings.LastIndex(addr.Address, "@")
+	if at < 0 || !strings.EqualFold(addr.Address[at+1:], g.Domain) {
+		return "", errors.New("inbound email address not found")
+	}
+	return g.DB.GetUserIDByInboundEmailToken(strings.ToLower(addr.Address[:at]))
+}
+
+// Deliver creates a task for userID from a raw message, with the same
+// defaults as the createTask mutation. Subjects use the quick-add tokens
+// (#tag, !high, !tomorrow, @Category) but plain words are left alone, and a
+// category reference that matches nothing falls back to the default category.
+func (g *Gateway) Deliver(userID string, raw []byte) (models.Task, error) {
+	msg, err := ParseMessage(bytes.NewReader(raw))
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	// Dates in the subject are read in the user's timezone
+	prefs, err := g.DB.GetUserPreferences(userID)
+	if err != nil {
+		return models.Task{}, err
+	}
+	loc := prefs.Location()
+
+	now := time.Now().In(loc)
+	subject := replyPrefix.ReplaceAllString(msg.Subject, "")
+	parsed := quickadd.Parse(subject, now, quickadd.Options{Location: loc, Strict: true})
+
+	input := models.CreateTaskInput{
+		Title:       truncate(parsed.Title, maxTitleLength),
+		Description: msg.Text,
+		Status:      models.TaskStatusTodo,
+		Priority:    models.TaskPriorityMedium,
+		DueDate:     parsed.DueDate(),
+		UserID:      userID,
+		Tags:        parsed.Tags,
+	}
+	if input.Title == "" {
+		input.Title = untitled
+	}
+	if parsed.Priority != "" {
+		input.Priority = parsed.Priority
+	}
+
+	if parsed.Category != "" {
+		categories, err := g.DB.GetAllCategories(userID)
+		if err != nil {
+			return models.Task{}, err
+		}
+		input.CategoryID, _ = quickadd.MatchCategory(categories, parsed.Category)
+	}
+	if err := g.DB.ApplyDefaultCategory(&input); err != nil {
+		return models.Task{}, err
+	}
+
+	return g.DB.CreateTaskWithAttachments(input, msg.Attachments)
+}
+
+// truncate shortens s to at most n characters
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
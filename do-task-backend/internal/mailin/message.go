@@ -0,0 +1,219 @@
+package mailin
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"path"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+)
+
+const (
+	// maxPartDepth bounds how deeply multiparts may nest
+	maxPartDepth = 8
+	// maxAttachments is how many files one message may attach to a task
+	maxAttachments = 20
+	// maxFilenameLength mirrors the size of the task_attachments.filename column
+	maxFilenameLength = 255
+)
+
+// Message is the part of an email the gateway turns into a task
+type Message struct {
+	Subject     string
+	Text        string // plain-text body, or the text of the HTML body if there is none
+	Attachments []models.NewAttachment
+}
+
+var wordDecoder = &mime.WordDecoder{CharsetReader: charsetReader}
+
+// ParseMessage reads a raw RFC 5322 message
+func ParseMessage(r io.Reader) (Message, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return Message{}, fmt.Errorf("invalid message: %w", err)
+	}
+
+	subject, err := wordDecoder.DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		subject = msg.Header.Get("Subject")
+	}
+
+	p := &messageParser{}
+	if err := p.walk(textproto.MIMEHeader(msg.Header), msg.Body, 0); err != nil {
+		return Message{}, err
+	}
+
+	text := p.text
+	if text == "" && p.html != "" {
+		text = htmlToText(p.html)
+	}
+
+	return Message{
+		Subject:     strings.TrimSpace(strings.ToValidUTF8(subject, "")),
+		Text:        strings.TrimSpace(text),
+		Attachments: p.attachments,
+	}, nil
+}
+
+// messageParser collects the bodies and attachments of a MIME tree
+type messageParser struct {
+	text        string
+	html        string
+	attachments []models.NewAttachment
+}
+
+func (p *messageParser) walk(header textproto.MIMEHeader, body io.Reader, depth int) error {
+	if depth > maxPartDepth {
+		return errors.New("message nests too deeply")
+	}
+
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("invalid multipart body: %w", err)
+			}
+			if err := p.walk(part.Header, part, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	content, err := io.ReadAll(decodeTransfer(header.Get("Content-Transfer-Encoding"), body))
+	if err != nil {
+		return fmt.Errorf("invalid %s part: %w", mediaType, err)
+	}
+
+	filename := partFilename(header, params)
+	disposition, _, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	inline := disposition != "attachment" && filename == ""
+
+	switch {
+	case inline && mediaType == "text/plain" && p.text == "":
+		p.text = decodeCharset(content, params["charset"])
+	case inline && mediaType == "text/html" && p.html == "":
+		p.html = decodeCharset(content, params["charset"])
+	case inline && strings.HasPrefix(mediaType, "text/"):
+		// Alternative bodies beyond the first are ignored
+	default:
+		if len(p.attachments) >= maxAttachments {
+			return fmt.Errorf("message cannot have more than %d attachments", maxAttachments)
+		}
+		if filename == "" {
+			filename = "attachment"
+			if mediaType == "message/rfc822" {
+				filename = "message.eml"
+			}
+		}
+		p.attachments = append(p.attachments, models.NewAttachment{
+			Filename:    filename,
+			ContentType: mediaType,
+			Data:        content,
+		})
+	}
+	return nil
+}
+
+// decodeTransfer undoes a Content-Transfer-Encoding
+func decodeTransfer(encoding string, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	}
+	return body
+}
+
+// partFilename returns the sanitised file name of a part, if it has one
+func partFilename(header textproto.MIMEHeader, contentTypeParams map[string]string) string {
+	name := ""
+	if _, params, err := mime.ParseMediaType(header.Get("Content-Disposition")); err == nil {
+		name = params["filename"]
+	}
+	if name == "" {
+		name = contentTypeParams["name"]
+	}
+	if decoded, err := wordDecoder.DecodeHeader(name); err == nil {
+		name = decoded
+	}
+
+	name = path.Base(strings.ReplaceAll(name, `\`, "/"))
+	name = strings.TrimSpace(strings.ToValidUTF8(name, ""))
+	if name == "." || name == "/" {
+		return ""
+	}
+	for len(name) > maxFilenameLength {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
+
+// decodeCharset converts a text body to UTF-8. Charsets other than Latin-1
+// are assumed to be ASCII-compatible and invalid bytes are dropped.
+func decodeCharset(content []byte, charset string) string {
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "latin1":
+		return latin1ToUTF8(content)
+	}
+	return strings.ToValidUTF8(string(content), "")
+}
+
+// charsetReader lets the word decoder handle the same charsets as decodeCharset
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	content, err := io.ReadAll(input)
+	if err != nil {
+		return nil, err
+	}
+	return strings.NewReader(decodeCharset(content, charset)), nil
+}
+
+func latin1ToUTF8(content []byte) string {
+	runes := make([]rune, len(content))
+	for i, b := range content {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
+
+var (
+	htmlHidden    = regexp.MustCompile(`(?is)<(script|style|head)\b.*?</(script|style|head)\s*>`)
+	htmlBreak     = regexp.MustCompile(`(?i)<(br|/p|/div|/li|/tr|/h[1-6])\b[^>]*>`)
+	htmlTag       = regexp.MustCompile(`(?s)<[^>]*>`)
+	htmlBlankRuns = regexp.MustCompile(`\n{3,}`)
+)
+
+// htmlToText reduces an HTML body to readable plain text
+func htmlToText(body string) string {
+	body = htmlHidden.ReplaceAllString(body, "")
+	body = htmlBreak.ReplaceAllString(body, "\n")
+	body = htmlTag.ReplaceAllString(body, "")
+	body = html.UnescapeString(body)
+
+	lines := strings.Split(strings.ReplaceAll(body, "\r", ""), "\n")
+	for i, line := range lines {
+		lines[i] = strings.Join(strings.Fields(line), " ")
+	}
+	return htmlBlankRuns.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+}
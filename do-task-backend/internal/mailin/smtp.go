@@ -0,0 +1,298 @@
+package mailin
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultMaxMessageSize is the largest message the listener accepts
+	DefaultMaxMessageSize = 25 << 20
+
+	maxRecipients  = 10
+	maxCommandSize = 64 << 10
+	commandTimeout = 5 * time.Minute
+	dataTimeout    = 10 * time.Minute
+)
+
+// Server is a minimal SMTP listener that only accepts mail for inbound
+// addresses. It does not offer STARTTLS or AUTH; put it behind the MX that
+// terminates TLS for the inbound domain.
+type Server struct {
+	Addr           string
+	Hostname       string
+	Gateway        *Gateway
+	MaxMessageSize int64
+}
+
+// NewServer returns a server for addr with production defaults
+func NewServer(addr string, gateway *Gateway) *Server {
+	hostname := gateway.Domain
+	if name, err := os.Hostname(); err == nil && hostname == "" {
+		hostname = name
+	}
+	return &Server{
+		Addr:           addr,
+		Hostname:       hostname,
+		Gateway:        gateway,
+		MaxMessageSize: DefaultMaxMessageSize,
+	}
+}
+
+// ListenAndServe accepts SMTP connections on s.Addr
+func (s *Server) ListenAndServe() error {
+	listener, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(listener)
+}
+
+// Serve accepts SMTP connections on listener until it is closed
+func (s *Server) Serve(listener net.Listener) error {
+	defer listener.Close()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				time.Sleep(100 * time.Millisecond)
+				continue
+			}
+			return err
+		}
+		go s.handle(conn)
+	}
+}
+
+// budgetReader fails once more than left bytes have been read, so a client
+// cannot make the server buffer an endless command line
+type budgetReader struct {
+	r    io.Reader
+	left int64
+}
+
+var errTooLong = errors.New("input too long")
+
+func (b *budgetReader) Read(p []byte) (int, error) {
+	if b.left <= 0 {
+		return 0, errTooLong
+	}
+	if int64(len(p)) > b.left {
+		p = p[:b.left]
+	}
+	n, err := b.r.Read(p)
+	b.left -= int64(n)
+	return n, err
+}
+
+// session is the state of one SMTP conversation
+type session struct {
+	server     *Server
+	conn       net.Conn
+	budget     *budgetReader
+	reader     *textproto.Reader
+	writer     *bufio.Writer
+	greeted    bool
+	sender     bool
+	recipients []string // user IDs
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+
+	budget := &budgetReader{r: conn}
+	sess := &session{
+		server: s,
+		conn:   conn,
+		budget: budget,
+		reader: textproto.NewReader(bufio.NewReader(budget)),
+		writer: bufio.NewWriter(conn),
+	}
+
+	if sess.reply(220, "%s ESMTP DoTask", s.Hostname) != nil {
+		return
+	}
+	for {
+		budget.left = maxCommandSize
+		conn.SetDeadline(time.Now().Add(commandTimeout))
+
+		line, err := sess.reader.ReadLine()
+		if err != nil {
+			if errors.Is(err, errTooLong) {
+				sess.reply(500, "5.5.2 Line too long")
+			}
+			return
+		}
+
+		verb, arg, _ := strings.Cut(line, " ")
+		if quit := sess.command(strings.ToUpper(verb), strings.TrimSpace(arg)); quit {
+			return
+		}
+	}
+}
+
+// command runs one SMTP command and reports whether the connection should close
+func (sess *session) command(verb string, arg string) bool {
+	var err error
+	switch verb {
+	case "HELO":
+		sess.greeted = true
+		sess.reset()
+		err = sess.reply(250, "%s", sess.server.Hostname)
+	case "EHLO":
+		sess.greeted = true
+		sess.reset()
+		err = sess.reply(250, "%s\n8BITMIME\nSIZE %d", sess.server.Hostname, sess.server.MaxMessageSize)
+	case "MAIL":
+		err = sess.mail(arg)
+	case "RCPT":
+		err = sess.rcpt(arg)
+	case "DATA":
+		err = sess.data()
+	case "RSET":
+		sess.reset()
+		err = sess.reply(250, "2.0.0 OK")
+	case "NOOP":
+		err = sess.reply(250, "2.0.0 OK")
+	case "VRFY":
+		err = sess.reply(252, "2.5.0 Cannot verify user")
+	case "QUIT":
+		sess.reply(221, "2.0.0 Bye")
+		return true
+	default:
+		err = sess.reply(502, "5.5.1 Command not implemented")
+	}
+	return err != nil
+}
+
+func (sess *session) reset() {
+	sess.sender = false
+	sess.recipients = nil
+}
+
+func (sess *session) mail(arg string) error {
+	if !sess.greeted {
+		return sess.reply(503, "5.5.1 Say hello first")
+	}
+	if sess.sender {
+		return sess.reply(503, "5.5.1 Sender already given")
+	}
+	if !strings.HasPrefix(strings.ToUpper(arg), "FROM:") {
+		return sess.reply(501, "5.5.4 Syntax: MAIL FROM:<address>")
+	}
+
+	fields := strings.Fields(arg[len("FROM:"):])
+	if len(fields) == 0 {
+		return sess.reply(501, "5.5.4 Syntax: MAIL FROM:<address>")
+	}
+	for _, param := range fields[1:] {
+		key, value, _ := strings.Cut(param, "=")
+		if strings.EqualFold(key, "SIZE") {
+			if size, err := strconv.ParseInt(value, 10, 64); err == nil && size > sess.server.MaxMessageSize {
+				return sess.reply(552, "5.3.4 Message too big")
+			}
+		}
+	}
+
+	sess.sender = true
+	return sess.reply(250, "2.1.0 OK")
+}
+
+func (sess *session) rcpt(arg string) error {
+	if !sess.sender {
+		return sess.reply(503, "5.5.1 Need MAIL first")
+	}
+	if !strings.HasPrefix(strings.ToUpper(arg), "TO:") {
+		return sess.reply(501, "5.5.4 Syntax: RCPT TO:<address>")
+	}
+	if len(sess.recipients) >= maxRecipients {
+		return sess.reply(452, "4.5.3 Too many recipients")
+	}
+
+	address, _, _ := strings.Cut(strings.TrimSpace(arg[len("TO:"):]), " ")
+	userID, err := sess.server.Gateway.Lookup(address)
+	if err != nil {
+		return sess.reply(550, "5.1.1 No such mailbox")
+	}
+
+	sess.recipients = append(sess.recipients, userID)
+	return sess.reply(250, "2.1.5 OK")
+}
+
+func (sess *session) data() error {
+	if len(sess.recipients) == 0 {
+		return sess.reply(503, "5.5.1 Need RCPT first")
+	}
+	if err := sess.reply(354, "Start mail input; end with <CRLF>.<CRLF>"); err != nil {
+		return err
+	}
+
+	max := sess.server.MaxMessageSize
+	sess.budget.left = max + maxCommandSize
+	sess.conn.SetDeadline(time.Now().Add(dataTimeout))
+
+	body := sess.reader.DotReader()
+	raw, err := io.ReadAll(io.LimitReader(body, max+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(raw)) > max {
+		// Drain the rest of the message so the client sees our reply
+		sess.budget.left = 1 << 62
+		if _, err := io.Copy(io.Discard, body); err != nil {
+			return err
+		}
+		sess.reset()
+		return sess.reply(552, "5.3.4 Message too big")
+	}
+
+	recipients := sess.recipients
+	sess.reset()
+
+	var delivered int
+	for _, userID := range recipients {
+		task, err := sess.server.Gateway.Deliver(userID, raw)
+		if err != nil {
+			log.Printf("mailin: delivery to user %s failed: %v", userID, err)
+			continue
+		}
+		log.Printf("mailin: created task %s for user %s", task.ID, userID)
+		delivered++
+	}
+
+	// The failures are logged above; their text can hold database details
+	// and stays out of the reply
+	if delivered == 0 {
+		return sess.reply(554, "5.6.0 Message could not be turned into a task")
+	}
+	return sess.reply(250, "2.0.0 Task created")
+}
+
+// reply sends a possibly multi-line response; lines in text are separated by \n
+func (sess *session) reply(code int, format string, args ...interface{}) error {
+	lines := strings.Split(fmt.Sprintf(format, args...), "\n")
+	var b bytes.Buffer
+	for i, line := range lines {
+		separator := "-"
+		if i == len(lines)-1 {
+			separator = " "
+		}
+		fmt.Fprintf(&b, "%d%s%s\r\n", code, separator, line)
+	}
+
+	if _, err := sess.writer.Write(b.Bytes()); err != nil {
+		return err
+	}
+	return sess.writer.Flush()
+}
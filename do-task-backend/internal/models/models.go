@@ -7,12 +7,183 @@ import (
 
 // User represents a user in the system
 type User struct {
-	ID        string    `json:"id"`
-	Name      string    `json:"name"`
-	Email     string    `json:"email"`
-	Password  string    `json:"password"` // This will be hashed
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	ID            string          `json:"id"`
+	Name          string          `json:"name"`
+	Email         string          `json:"email"`
+	EmailVerified bool            `json:"emailVerified"`
+	PendingEmail  *string         `json:"pendingEmail,omitempty"` // new address waiting for verification
+	Password      string          `json:"password"`               // This will be hashed
+	CreatedAt     time.Time       `json:"createdAt"`
+	UpdatedAt     time.Time       `json:"updatedAt"`
+	Preferences   UserPreferences `json:"preferences"`
+}
+
+// Theme is the colour scheme of the app
+type Theme string
+
+// Themes
+const (
+	ThemeLight  Theme = "LIGHT"
+	ThemeDark   Theme = "DARK"
+	ThemeSystem Theme = "SYSTEM"
+)
+
+// DateFormat is how dates are written out
+type DateFormat string
+
+// Date formats
+const (
+	DateFormatMonthDayYear DateFormat = "MM_DD_YYYY"
+	DateFormatDayMonthYear DateFormat = "DD_MM_YYYY"
+	DateFormatISO          DateFormat = "YYYY_MM_DD"
+)
+
+// Layout returns the time layout for the format
+func (f DateFormat) Layout() string {
+	switch f {
+	case DateFormatDayMonthYear:
+		return "02/01/2006"
+	case DateFormatISO:
+		return "2006-01-02"
+	default:
+		return "01/02/2006"
+	}
+}
+
+// Weekday is a day of the week, as chosen for the start of the week
+type Weekday string
+
+// Weekdays
+const (
+	WeekdaySunday    Weekday = "SUNDAY"
+	WeekdayMonday    Weekday = "MONDAY"
+	WeekdayTuesday   Weekday = "TUESDAY"
+	WeekdayWednesday Weekday = "WEDNESDAY"
+	WeekdayThursday  Weekday = "THURSDAY"
+	WeekdayFriday    Weekday = "FRIDAY"
+	WeekdaySaturday  Weekday = "SATURDAY"
+)
+
+// Weekdays lists the weekdays in time.Weekday order
+var Weekdays = []Weekday{
+	WeekdaySunday,
+	WeekdayMonday,
+	WeekdayTuesday,
+	WeekdayWednesday,
+	WeekdayThursday,
+	WeekdayFriday,
+	WeekdaySaturday,
+}
+
+// TimeWeekday converts d to a time.Weekday; unknown values are Monday
+func (d Weekday) TimeWeekday() time.Weekday {
+	for i, day := range Weekdays {
+		if day == d {
+			return time.Weekday(i)
+		}
+	}
+	return time.Monday
+}
+
+// SupportedLanguages are the locales the app is translated into
+var SupportedLanguages = []string{"en", "es", "fr", "de"}
+
+// Session timeout bounds, in minutes
+const (
+	MinSessionTimeoutMinutes = 5
+	MaxSessionTimeoutMinutes = 480
+)
+
+// NotificationSettings are the user's notification switches
+type NotificationSettings struct {
+	Email bool `json:"email"`
+	Push  bool `json:"push"`
+	// TaskReminders lets task reminders be sent at all
+	TaskReminders bool `json:"taskReminders"`
+	// WeeklyDigest opts the user in to the weekly summary email
+	WeeklyDigest    bool `json:"weeklyDigest"`
+	MarketingEmails bool `json:"marketingEmails"`
+}
+
+// UserPreferences are the settings a user carries between devices
+type UserPreferences struct {
+	Theme    Theme  `json:"theme"`
+	Language string `json:"language"`
+	// Timezone is an IANA zone name; server-side dates are shown in it
+	Timezone              string               `json:"timezone"`
+	DateFormat            DateFormat           `json:"dateFormat"`
+	StartOfWeek           Weekday              `json:"startOfWeek"`
+	Notifications         NotificationSettings `json:"notifications"`
+	SessionTimeoutMinutes int                  `json:"sessionTimeoutMinutes"`
+}
+
+// DefaultPreferences are the preferences of a new user. The column defaults
+// of the users table match them.
+func DefaultPreferences() UserPreferences {
+	return UserPreferences{
+		Theme:       ThemeSystem,
+		Language:    "en",
+		Timezone:    "UTC",
+		DateFormat:  DateFormatMonthDayYear,
+		StartOfWeek: WeekdayMonday,
+		Notifications: NotificationSettings{
+			Email:         true,
+			TaskReminders: true,
+		},
+		SessionTimeoutMinutes: 30,
+	}
+}
+
+// Location returns the preferred timezone, or UTC when it cannot be loaded
+func (p UserPreferences) Location() *time.Location {
+	loc, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// FormatDate writes t as a date in the preferred timezone and format
+func (p UserPreferences) FormatDate(t time.Time) string {
+	return t.In(p.Location()).Format(p.DateFormat.Layout())
+}
+
+// FormatDateTime writes t as a date and time in the preferred timezone and
+// format, naming the zone
+func (p UserPreferences) FormatDateTime(t time.Time) string {
+	return t.In(p.Location()).Format(p.DateFormat.Layout() + " 15:04 MST")
+}
+
+// FormatDue writes a task's due date in the preferred format, as a date alone
+// for all-day tasks. It is empty for tasks without a due date.
+func (p UserPreferences) FormatDue(task Task) string {
+	if task.DueDate == nil {
+		return ""
+	}
+	if task.AllDay {
+		return p.FormatDate(*task.DueDate)
+	}
+	return p.FormatDateTime(*task.DueDate)
+}
+
+// UpdateNotificationSettingsInput switches notifications on or off; nil fields are left alone
+type UpdateNotificationSettingsInput struct {
+	Email           *bool `json:"email"`
+	Push            *bool `json:"push"`
+	TaskReminders   *bool `json:"taskReminders"`
+	WeeklyDigest    *bool `json:"weeklyDigest"`
+	MarketingEmails *bool `json:"marketingEmails"`
+}
+
+// UpdatePreferencesInput changes some of a user's preferences; nil fields are left alone
+type UpdatePreferencesInput struct {
+	Theme                 *Theme                           `json:"theme"`
+	Language              *string                          `json:"language"`
+	Timezone              *string                          `json:"timezone"`
+	DateFormat            *DateFormat                      `json:"dateFormat"`
+	StartOfWeek           *Weekday                         `json:"startOfWeek"`
+	Notifications         *UpdateNotificationSettingsInput `json:"notifications"`
+	SessionTimeoutMinutes *int                             `json:"sessionTimeoutMinutes"`
 }
 
 // LoginInput represents the input for user login
@@ -30,8 +201,11 @@ type RegisterInput struct {
 
 // UpdateProfileInput represents the input for updating user profile
 type UpdateProfileInput struct {
-	Name  *string `json:"name"`
-	Email *string `json:"email"`
+	Name          *string `json:"name"`
+	Email         *string `json:"email"`
+	TaskReminders *bool   `json:"taskReminders"`
+	WeeklyDigest  *bool   `json:"weeklyDigest"`
+	Timezone      *string `json:"timezone"`
 }
 
 // ChangePasswordInput represents the input for changing user password
@@ -80,32 +254,94 @@ type Task struct {
 	Description string       `json:"description"`
 	Status      TaskStatus   `json:"status"`
 	Priority    TaskPriority `json:"priority"`
-	DueDate     time.Time    `json:"dueDate"`
+	DueDate     *time.Time   `json:"dueDate"` // nil for tasks without a due date
 	CreatedAt   time.Time    `json:"createdAt"`
 	UpdatedAt   time.Time    `json:"updatedAt"`
 	CategoryID  string       `json:"categoryId"`
 	UserID      string       `json:"userId"` // Associate task with user
 	Tags        []string     `json:"tags"`
+	DeletedAt   *time.Time   `json:"deletedAt"` // Set while the task is in the trash
+	CompletedAt *time.Time   `json:"completedAt"`
+	CalDAVName  string       `json:"-"` // Resource name chosen by a CalDAV client, if any
+	ICalUID     string       `json:"-"` // iCalendar UID chosen by a CalDAV client, if any
+	// AllDay tasks are due on a calendar day; DueDate and StartDate are then
+	// midnight of their day in the owner's timezone
+	AllDay    bool       `json:"allDay"`
+	StartDate *time.Time `json:"startDate"`
+	Timezone  string     `json:"-"` // the owner's timezone
+	// EstimateMinutes is the expected time to spend on the task, if estimated
+	EstimateMinutes *int `json:"estimateMinutes"`
+	// Version goes up by one with every change to the task
+	Version int `json:"version"`
+}
+
+// Location returns the owner's timezone, or UTC when it cannot be loaded
+func (t Task) Location() *time.Location {
+	loc, err := time.LoadLocation(t.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// IsOverdue reports whether the task is open and past due at now. All-day
+// tasks are overdue once their day has ended in the owner's timezone, and
+// tasks without a due date are never overdue.
+func (t Task) IsOverdue(now time.Time) bool {
+	if t.Status == TaskStatusCompleted || t.DueDate == nil {
+		return false
+	}
+	if t.AllDay {
+		due := t.DueDate.In(t.Location())
+		dayEnd := time.Date(due.Year(), due.Month(), due.Day()+1, 0, 0, 0, 0, due.Location())
+		return !now.Before(dayEnd)
+	}
+	return t.DueDate.Before(now)
+}
+
+// IsDueToday reports whether the task is due on the owner's current day at now
+func (t Task) IsDueToday(now time.Time) bool {
+	if t.DueDate == nil {
+		return false
+	}
+	loc := t.Location()
+	dueYear, dueMonth, dueDay := t.DueDate.In(loc).Date()
+	year, month, day := now.In(loc).Date()
+	return dueYear == year && dueMonth == month && dueDay == day
 }
 
 // Category represents a task category
 type Category struct {
 	ID        string    `json:"id"`
 	Name      string    `json:"name"`
+	ParentID  *string   `json:"parentId"`
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
+	Version   int       `json:"version"` // goes up by one with every change
 }
 
+// CategoryPathSeparator joins category names when a category is shown with its ancestors
+const CategoryPathSeparator = " / "
+
 // CreateTaskInput represents the input for creating a task
 type CreateTaskInput struct {
 	Title       string       `json:"title"`
 	Description string       `json:"description"`
 	Status      TaskStatus   `json:"status"`
 	Priority    TaskPriority `json:"priority"`
-	DueDate     string       `json:"dueDate"`
+	DueDate     *string      `json:"dueDate"` // a YYYY-MM-DD day or an RFC 3339 timestamp
+	AllDay      *bool        `json:"allDay"`  // defaults to whether DueDate is a day
+	StartDate   *string      `json:"startDate"`
 	CategoryID  string       `json:"categoryId"`
 	UserID      string       `json:"userId"` // Add UserID for authentication
 	Tags        []string     `json:"tags"`
+	ImportKey   string       `json:"-"` // Set by importers so re-imports don't duplicate tasks
+	CalDAVName  string       `json:"-"` // Set when a CalDAV client creates the task
+	ICalUID     string       `json:"-"` // Set when a CalDAV client creates the task
+	// CompletedAt is when an imported or synced task was finished at its source
+	CompletedAt *time.Time `json:"-"`
+	// EstimateMinutes must be positive when set
+	EstimateMinutes *int `json:"estimateMinutes"`
 }
 
 // UpdateTaskInput represents the input for updating a task
@@ -114,7 +350,768 @@ type UpdateTaskInput struct {
 	Description *string       `json:"description"`
 	Status      *TaskStatus   `json:"status"`
 	Priority    *TaskPriority `json:"priority"`
-	DueDate     *string       `json:"dueDate"`
+	DueDate     *string       `json:"dueDate"` // an empty string removes the due date
+	AllDay      *bool         `json:"allDay"`
+	StartDate   *string       `json:"startDate"` // an empty string removes the start date
 	CategoryID  *string       `json:"categoryId"`
 	Tags        []string      `json:"tags"`
+	// EstimateMinutes of 0 removes the estimate
+	EstimateMinutes *int `json:"estimateMinutes"`
+	// ExpectedVersion, when set, must be the task's current version
+	ExpectedVersion *int `json:"expectedVersion"`
+}
+
+// Tag represents a user-defined label that can be attached to tasks
+type Tag struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Color       *string   `json:"color"`
+	Description *string   `json:"description"`
+	UsageCount  int       `json:"usageCount"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// CreateTagInput represents the input for creating a tag
+type CreateTagInput struct {
+	Name        string  `json:"name"`
+	Color       *string `json:"color"`
+	Description *string `json:"description"`
+}
+
+// UpdateTagInput represents the input for updating a tag's details
+type UpdateTagInput struct {
+	Color       *string `json:"color"`
+	Description *string `json:"description"`
+}
+
+// BulkTaskItemResult reports the outcome of a bulk operation for a single task
+type BulkTaskItemResult struct {
+	ID      string  `json:"id"`
+	Success bool    `json:"success"`
+	Task    *Task   `json:"task"`
+	Error   *string `json:"error"`
+}
+
+// BulkTaskResult summarises a bulk task operation
+type BulkTaskResult struct {
+	Succeeded int                   `json:"succeeded"`
+	Failed    int                   `json:"failed"`
+	Results   []*BulkTaskItemResult `json:"results"`
+}
+
+// CategoryDeleteStrategy decides what happens to a category's tasks when it is deleted
+type CategoryDeleteStrategy string
+
+// Category delete strategies
+const (
+	CategoryDeleteStrategyReassignTo  CategoryDeleteStrategy = "REASSIGN_TO"
+	CategoryDeleteStrategyDeleteTasks CategoryDeleteStrategy = "DELETE_TASKS"
+	CategoryDeleteStrategyTrashTasks  CategoryDeleteStrategy = "TRASH_TASKS"
+	CategoryDeleteStrategyFail        CategoryDeleteStrategy = "FAIL"
+)
+
+// DeleteCategoryResult reports the outcome of deleting a category
+type DeleteCategoryResult struct {
+	Success            bool                   `json:"success"`
+	Strategy           CategoryDeleteStrategy `json:"strategy"`
+	DeletedCategoryIDs []string               `json:"deletedCategoryIds"`
+	AffectedTasks      int                    `json:"affectedTasks"`
+}
+
+// TaskFilter narrows down a task list. Every field is optional and the
+// conditions are combined with AND.
+type TaskFilter struct {
+	Status               []TaskStatus   `json:"status,omitempty"`
+	Priority             []TaskPriority `json:"priority,omitempty"`
+	CategoryID           *string        `json:"categoryId,omitempty"`
+	IncludeSubcategories *bool          `json:"includeSubcategories,omitempty"`
+	Tags                 []string       `json:"tags,omitempty"` // tasks must carry all of them
+	DueAfter             *string        `json:"dueAfter,omitempty"`
+	DueBefore            *string        `json:"dueBefore,omitempty"`
+	Search               *string        `json:"search,omitempty"`
+	Overdue              *bool          `json:"overdue,omitempty"`
+	DueOn                *string        `json:"dueOn,omitempty"` // a YYYY-MM-DD day in the user's timezone
+	HasDueDate           *bool          `json:"hasDueDate,omitempty"`
+	DueIn                *DueRange      `json:"dueIn,omitempty"` // relative to today in the user's timezone
+}
+
+// DueRange is a span of days relative to today, so that a saved filter keeps
+// following the calendar
+type DueRange string
+
+// Due ranges
+const (
+	DueRangeToday     DueRange = "TODAY"
+	DueRangeTomorrow  DueRange = "TOMORROW"
+	DueRangeThisWeek  DueRange = "THIS_WEEK"
+	DueRangeNext7Days DueRange = "NEXT_7_DAYS"
+)
+
+// TaskSortField is a column task lists can be sorted by
+type TaskSortField string
+
+// Task sort fields
+const (
+	TaskSortFieldCreatedAt TaskSortField = "CREATED_AT"
+	TaskSortFieldDueDate   TaskSortField = "DUE_DATE"
+	TaskSortFieldPriority  TaskSortField = "PRIORITY"
+	TaskSortFieldTitle     TaskSortField = "TITLE"
+)
+
+// SortDirection orders a sorted list
+type SortDirection string
+
+// Sort directions
+const (
+	SortDirectionAsc  SortDirection = "ASC"
+	SortDirectionDesc SortDirection = "DESC"
+)
+
+// TaskSort orders a task list. Tasks without a due date come last when
+// sorting by due date, in either direction.
+type TaskSort struct {
+	Field     TaskSortField  `json:"field"`
+	Direction *SortDirection `json:"direction"` // defaults to ASC
+}
+
+// ImportFormat identifies the layout of an imported file
+type ImportFormat string
+
+// Import formats
+const (
+	ImportFormatCSV     ImportFormat = "CSV"
+	ImportFormatJSON    ImportFormat = "JSON"
+	ImportFormatTodoist ImportFormat = "TODOIST"
+	ImportFormatTrello  ImportFormat = "TRELLO"
+)
+
+// ImportRowError describes why a single row of an import was rejected
+type ImportRowError struct {
+	Row     int    `json:"row"`
+	Message string `json:"message"`
+}
+
+// ImportResult summarises an import run
+type ImportResult struct {
+	DryRun            bool              `json:"dryRun"`
+	Total             int               `json:"total"`
+	Created           int               `json:"created"`
+	Skipped           int               `json:"skipped"`
+	Failed            int               `json:"failed"`
+	CreatedCategories []string          `json:"createdCategories"`
+	Errors            []*ImportRowError `json:"errors"`
+}
+
+// CalendarFeed is a user's secret iCalendar subscription feed. URL is filled
+// in by the API layer, which knows the public address of the server.
+type CalendarFeed struct {
+	Token     string    `json:"token"`
+	URL       string    `json:"url"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// AppPassword is a named secret that lets a non-browser client such as a
+// CalDAV app sign in. The secret itself is only shown once, on creation.
+type AppPassword struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+}
+
+// NewAppPassword is returned when an app password is created
+type NewAppPassword struct {
+	AppPassword *AppPassword `json:"appPassword"`
+	Secret      string       `json:"secret"`
+	CaldavURL   string       `json:"caldavUrl"`
+}
+
+// Webhook event types
+const (
+	EventTaskCreated       = "task.created"
+	EventTaskUpdated       = "task.updated"
+	EventTaskStatusChanged = "task.status_changed"
+	EventTaskDeleted       = "task.deleted"
+	EventCategoryCreated   = "category.created"
+	EventCategoryUpdated   = "category.updated"
+	EventCategoryDeleted   = "category.deleted"
+	EventReminderDue       = "reminder.due"
+	EventWebhookTest       = "webhook.test"
+)
+
+// WebhookEventTypes lists the events a webhook can subscribe to. Subscriptions
+// may also use wildcards such as "task.*" or "*".
+var WebhookEventTypes = []string{
+	EventTaskCreated,
+	EventTaskUpdated,
+	EventTaskStatusChanged,
+	EventTaskDeleted,
+	EventCategoryCreated,
+	EventCategoryUpdated,
+	EventCategoryDeleted,
+	EventReminderDue,
+}
+
+// Webhook is a user's subscription to task and category events
+type Webhook struct {
+	ID         string    `json:"id"`
+	URL        string    `json:"url"`
+	Secret     string    `json:"secret"`
+	EventTypes []string  `json:"eventTypes"`
+	Active     bool      `json:"active"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// CreateWebhookInput represents the input for creating a webhook
+type CreateWebhookInput struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"eventTypes"`
+	Secret     *string  `json:"secret"` // generated when omitted
+	Active     *bool    `json:"active"`
+}
+
+// UpdateWebhookInput represents the input for updating a webhook
+type UpdateWebhookInput struct {
+	URL        *string  `json:"url"`
+	EventTypes []string `json:"eventTypes"`
+	Secret     *string  `json:"secret"`
+	Active     *bool    `json:"active"`
+}
+
+// WebhookDeliveryStatus represents where a delivery is in its lifecycle
+type WebhookDeliveryStatus string
+
+// Webhook delivery statuses
+const (
+	WebhookDeliveryStatusPending   WebhookDeliveryStatus = "PENDING"
+	WebhookDeliveryStatusSucceeded WebhookDeliveryStatus = "SUCCEEDED"
+	WebhookDeliveryStatusFailed    WebhookDeliveryStatus = "FAILED"
+)
+
+// WebhookDelivery is one event queued for one webhook
+type WebhookDelivery struct {
+	ID             string                `json:"id"`
+	WebhookID      string                `json:"webhookId"`
+	EventType      string                `json:"eventType"`
+	Payload        string                `json:"payload"`
+	Status         WebhookDeliveryStatus `json:"status"`
+	Attempts       int                   `json:"attempts"`
+	ResponseStatus *int                  `json:"responseStatus"`
+	Error          *string               `json:"error"`
+	NextAttemptAt  time.Time             `json:"nextAttemptAt"`
+	LastAttemptAt  *time.Time            `json:"lastAttemptAt"`
+	DeliveredAt    *time.Time            `json:"deliveredAt"`
+	CreatedAt      time.Time             `json:"createdAt"`
+}
+
+// WebhookDispatch is a delivery claimed for sending, with everything needed to send it
+type WebhookDispatch struct {
+	DeliveryID string
+	EventType  string
+	Payload    string
+	Attempt    int
+	URL        string
+	Secret     string
+}
+
+// Attachment is a file attached to a task. The content is served separately.
+type Attachment struct {
+	ID          string    `json:"id"`
+	TaskID      string    `json:"taskId"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"contentType"`
+	Size        int       `json:"size"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// NewAttachment is the content of an attachment about to be stored
+type NewAttachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// QuickAddTokenKind says what part of a task a quick-add token set
+type QuickAddTokenKind string
+
+// Quick-add token kinds
+const (
+	QuickAddTokenDueDate  QuickAddTokenKind = "DUE_DATE"
+	QuickAddTokenPriority QuickAddTokenKind = "PRIORITY"
+	QuickAddTokenTag      QuickAddTokenKind = "TAG"
+	QuickAddTokenCategory QuickAddTokenKind = "CATEGORY"
+)
+
+// QuickAddToken is a piece of quick-add text that was recognised and removed from the title
+type QuickAddToken struct {
+	Kind QuickAddTokenKind `json:"kind"`
+	Text string            `json:"text"`
+}
+
+// QuickAddParse is what the quick-add parser read from a line of text
+type QuickAddParse struct {
+	Title    string          `json:"title"`
+	DueDate  *string         `json:"dueDate"`
+	AllDay   bool            `json:"allDay"`
+	Priority *TaskPriority   `json:"priority"`
+	Tags     []string        `json:"tags"`
+	Category *string         `json:"category"` // category reference as typed
+	Tokens   []QuickAddToken `json:"tokens"`
+}
+
+// QuickAddResult is returned by the quickAddTask mutation
+type QuickAddResult struct {
+	Task  *Task          `json:"task"`
+	Parse *QuickAddParse `json:"parse"`
+}
+
+// ReminderChannel is a way of delivering a reminder
+type ReminderChannel string
+
+// Reminder channels
+const (
+	ReminderChannelEmail   ReminderChannel = "EMAIL"
+	ReminderChannelWebhook ReminderChannel = "WEBHOOK"
+	ReminderChannelInApp   ReminderChannel = "IN_APP"
+)
+
+// ReminderStatus represents where a reminder is in its lifecycle
+type ReminderStatus string
+
+// Reminder statuses
+const (
+	ReminderStatusPending ReminderStatus = "PENDING"
+	ReminderStatusSent    ReminderStatus = "SENT"
+	ReminderStatusSkipped ReminderStatus = "SKIPPED" // task done or deleted, or reminders switched off
+	ReminderStatusFailed  ReminderStatus = "FAILED"
+)
+
+// Reminder fires at RemindAt, or OffsetMinutes before the task is due
+type Reminder struct {
+	ID            string            `json:"id"`
+	TaskID        string            `json:"taskId"`
+	RemindAt      *time.Time        `json:"remindAt"`
+	OffsetMinutes *int              `json:"offsetMinutes"`
+	FireAt        time.Time         `json:"fireAt"`
+	Channels      []ReminderChannel `json:"channels"`
+	Status        ReminderStatus    `json:"status"`
+	SentAt        *time.Time        `json:"sentAt"`
+	LastError     *string           `json:"lastError"`
+	CreatedAt     time.Time         `json:"createdAt"`
+}
+
+// CreateReminderInput represents the input for creating a reminder. Exactly
+// one of RemindAt and OffsetMinutes is required.
+type CreateReminderInput struct {
+	TaskID        string            `json:"taskId"`
+	RemindAt      *string           `json:"remindAt"`
+	OffsetMinutes *int              `json:"offsetMinutes"`
+	Channels      []ReminderChannel `json:"channels"` // IN_APP when empty
+}
+
+// DueReminder is a reminder claimed for sending, with everything needed to send it
+type DueReminder struct {
+	ReminderID string
+	Attempt    int
+	FireAt     time.Time
+	Channels   []ReminderChannel
+	Delivered  []ReminderChannel // channels that already succeeded on an earlier attempt
+	User       User
+	TaskID     string
+	Task       *Task // nil when the task is in the trash
+}
+
+// NotificationType says what a notification is about
+type NotificationType string
+
+// Notification types
+const (
+	NotificationTypeReminder    NotificationType = "REMINDER"
+	NotificationTypeTaskOverdue NotificationType = "TASK_OVERDUE"
+	NotificationTypeTaskDueSoon NotificationType = "TASK_DUE_SOON"
+)
+
+// NotificationTypes lists every notification type, in the order preferences are shown
+var NotificationTypes = []NotificationType{
+	NotificationTypeReminder,
+	NotificationTypeTaskOverdue,
+	NotificationTypeTaskDueSoon,
+}
+
+// Notification is an in-app message for a user
+type Notification struct {
+	ID        string           `json:"id"`
+	Type      NotificationType `json:"type"`
+	Title     string           `json:"title"`
+	Body      string           `json:"body"`
+	TaskID    *string          `json:"taskId"`
+	ReadAt    *time.Time       `json:"readAt"`
+	CreatedAt time.Time        `json:"createdAt"`
+}
+
+// NewNotification is a notification about to be stored
+type NewNotification struct {
+	UserID string
+	Type   NotificationType
+	Title  string
+	Body   string
+	TaskID *string
+	// OncePerTask skips the notification while an unread one of the same
+	// type exists for the task
+	OncePerTask bool
+}
+
+// NotificationConnection is one page of notifications, newest first
+type NotificationConnection struct {
+	Nodes       []*Notification `json:"nodes"`
+	EndCursor   *string         `json:"endCursor"`
+	HasNextPage bool            `json:"hasNextPage"`
+}
+
+// NotificationPreference says whether a user receives a type of notification
+type NotificationPreference struct {
+	Type    NotificationType `json:"type"`
+	Enabled bool             `json:"enabled"`
+}
+
+// WeeklyDigest summarises a user's tasks around the start of their week: what
+// was completed in the previous week, what is overdue and what falls due in
+// the coming one
+type WeeklyDigest struct {
+	Timezone  string    `json:"timezone"`
+	WeekStart time.Time `json:"weekStart"`
+	Completed []*Task   `json:"completed"`
+	Overdue   []*Task   `json:"overdue"`
+	Upcoming  []*Task   `json:"upcoming"`
+	Subject   string    `json:"subject"`
+	Text      string    `json:"text"`
+	HTML      string    `json:"html"`
+}
+
+// DueDigest is a user whose weekly digest has been claimed for sending
+type DueDigest struct {
+	User User
+	// ScheduledAt is nil when no send time has been worked out yet
+	ScheduledAt *time.Time
+}
+
+// StatsRange is the period task statistics cover, ending today
+type StatsRange string
+
+// Stats ranges
+const (
+	StatsRangeWeek    StatsRange = "WEEK"
+	StatsRangeMonth   StatsRange = "MONTH"
+	StatsRangeQuarter StatsRange = "QUARTER"
+	StatsRangeYear    StatsRange = "YEAR"
+)
+
+// Days returns the number of days in the range, today included
+func (r StatsRange) Days() int {
+	switch r {
+	case StatsRangeWeek:
+		return 7
+	case StatsRangeQuarter:
+		return 90
+	case StatsRangeYear:
+		return 365
+	default:
+		return 30
+	}
+}
+
+// StatsGroupBy is the period each point of a statistics series covers
+type StatsGroupBy string
+
+// Stats groupings
+const (
+	StatsGroupByDay  StatsGroupBy = "DAY"
+	StatsGroupByWeek StatsGroupBy = "WEEK"
+)
+
+// StatusCount counts the tasks in one status
+type StatusCount struct {
+	Status  TaskStatus `json:"status"`
+	Count   int        `json:"count"`
+	Overdue int        `json:"overdue"`
+}
+
+// PriorityCount counts the tasks of one priority
+type PriorityCount struct {
+	Priority TaskPriority `json:"priority"`
+	Count    int          `json:"count"`
+	Overdue  int          `json:"overdue"`
+}
+
+// CategoryCount counts the tasks directly in one category
+type CategoryCount struct {
+	CategoryID string `json:"categoryId"`
+	Name       string `json:"name"`
+	Count      int    `json:"count"`
+	Overdue    int    `json:"overdue"`
+}
+
+// StatsPoint is one period of a statistics series, starting on Date (YYYY-MM-DD)
+type StatsPoint struct {
+	Date  string `json:"date"`
+	Count int    `json:"count"`
+}
+
+// TaskStats summarises a user's tasks. The counts cover every task outside the
+// trash; completions, cycle time and streaks use days in the user's timezone.
+type TaskStats struct {
+	From            string          `json:"from"` // first day of the range, YYYY-MM-DD
+	To              string          `json:"to"`   // last day of the range, today
+	Total           int             `json:"total"`
+	Overdue         int             `json:"overdue"`
+	ByStatus        []StatusCount   `json:"byStatus"`
+	ByPriority      []PriorityCount `json:"byPriority"`
+	ByCategory      []CategoryCount `json:"byCategory"`
+	Completed       int             `json:"completed"` // completed within the range
+	CompletedSeries []StatsPoint    `json:"completedSeries"`
+	// AverageCycleTimeHours is the mean time from creation to completion of
+	// the tasks completed within the range, nil when there are none
+	AverageCycleTimeHours *float64     `json:"averageCycleTimeHours"`
+	CurrentStreak         int          `json:"currentStreak"` // days in a row ending today or yesterday with a completion
+	LongestStreak         int          `json:"longestStreak"`
+	FocusSeries           []FocusPoint `json:"focusSeries"`
+}
+
+// FocusPoint rolls up the focus sessions started in one period of a statistics
+// series, starting on Date (YYYY-MM-DD)
+type FocusPoint struct {
+	Date         string `json:"date"`
+	Sessions     int    `json:"sessions"`    // finished sessions, interrupted or not
+	Interrupted  int    `json:"interrupted"` // sessions ended before their focus time was up
+	FocusMinutes int    `json:"focusMinutes"`
+}
+
+// TimeEntry is time spent on a task, from a timer or entered by hand. A
+// running timer has no EndedAt.
+type TimeEntry struct {
+	ID        string     `json:"id"`
+	TaskID    string     `json:"taskId"`
+	StartedAt time.Time  `json:"startedAt"`
+	EndedAt   *time.Time `json:"endedAt"`
+	Note      string     `json:"note"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// Duration returns the time spent, counting a running timer up to now
+func (e TimeEntry) Duration(now time.Time) time.Duration {
+	if e.EndedAt == nil {
+		return now.Sub(e.StartedAt)
+	}
+	return e.EndedAt.Sub(e.StartedAt)
+}
+
+// CreateTimeEntryInput records time spent on a task by hand. Times are RFC
+// 3339 timestamps.
+type CreateTimeEntryInput struct {
+	TaskID    string  `json:"taskId"`
+	StartedAt string  `json:"startedAt"`
+	EndedAt   string  `json:"endedAt"`
+	Note      *string `json:"note"`
+}
+
+// UpdateTimeEntryInput changes a finished time entry; nil fields are left alone
+type UpdateTimeEntryInput struct {
+	StartedAt *string `json:"startedAt"`
+	EndedAt   *string `json:"endedAt"`
+	Note      *string `json:"note"`
+}
+
+// TimeReportGroupBy is what a time report adds time up by
+type TimeReportGroupBy string
+
+// Time report groupings
+const (
+	TimeReportGroupByCategory TimeReportGroupBy = "CATEGORY"
+	TimeReportGroupByTag      TimeReportGroupBy = "TAG"
+	TimeReportGroupByDay      TimeReportGroupBy = "DAY"
+)
+
+// TimeReportRow is the time spent in one group of a time report
+type TimeReportRow struct {
+	Key     *string `json:"key"` // category ID, tag ID or YYYY-MM-DD day; nil for tasks without a category or tag
+	Label   string  `json:"label"`
+	Seconds int     `json:"seconds"`
+	Entries int     `json:"entries"`
+}
+
+// TimeReport adds up the time spent between two days in the user's timezone.
+// Entries crossing the range are cut to it, and running timers count up to now.
+type TimeReport struct {
+	From         string            `json:"from"`
+	To           string            `json:"to"`
+	GroupBy      TimeReportGroupBy `json:"groupBy"`
+	TotalSeconds int               `json:"totalSeconds"` // every entry once, even when it counts towards several tags
+	Rows         []TimeReportRow   `json:"rows"`
+}
+
+// FocusSessionStatus is where a focus session is in its lifecycle
+type FocusSessionStatus string
+
+// Focus session statuses
+const (
+	FocusSessionStatusActive    FocusSessionStatus = "ACTIVE"
+	FocusSessionStatusCompleted FocusSessionStatus = "COMPLETED"
+	FocusSessionStatusAbandoned FocusSessionStatus = "ABANDONED"
+)
+
+// FocusPhase is what an active focus session is doing at a given moment
+type FocusPhase string
+
+// Focus phases
+const (
+	FocusPhaseFocus FocusPhase = "FOCUS"
+	FocusPhaseBreak FocusPhase = "BREAK"
+	FocusPhaseDone  FocusPhase = "DONE"
+)
+
+// Focus session limits, in minutes
+const (
+	DefaultFocusMinutes = 25
+	DefaultBreakMinutes = 5
+	MaxFocusMinutes     = 180
+	MaxBreakMinutes     = 60
+)
+
+// FocusSession is a timed stretch of focus on a task followed by a break. The
+// server sets EndsAt and BreakEndsAt when the session starts, so clients only
+// display the clock.
+type FocusSession struct {
+	ID           string             `json:"id"`
+	TaskID       string             `json:"taskId"`
+	FocusMinutes int                `json:"focusMinutes"`
+	BreakMinutes int                `json:"breakMinutes"`
+	StartedAt    time.Time          `json:"startedAt"`
+	EndsAt       time.Time          `json:"endsAt"`
+	BreakEndsAt  time.Time          `json:"breakEndsAt"`
+	Status       FocusSessionStatus `json:"status"`
+	Interrupted  bool               `json:"interrupted"` // ended before the focus time was up
+	EndedAt      *time.Time         `json:"endedAt"`
+}
+
+// PhaseAt returns what the session is doing at now
+func (s FocusSession) PhaseAt(now time.Time) FocusPhase {
+	switch {
+	case s.Status != FocusSessionStatusActive || !now.Before(s.BreakEndsAt):
+		return FocusPhaseDone
+	case now.Before(s.EndsAt):
+		return FocusPhaseFocus
+	default:
+		return FocusPhaseBreak
+	}
+}
+
+// RemainingSecondsAt returns the seconds left in the current phase at now
+func (s FocusSession) RemainingSecondsAt(now time.Time) int {
+	switch s.PhaseAt(now) {
+	case FocusPhaseFocus:
+		return int(s.EndsAt.Sub(now).Seconds())
+	case FocusPhaseBreak:
+		return int(s.BreakEndsAt.Sub(now).Seconds())
+	default:
+		return 0
+	}
+}
+
+// StartFocusSessionInput starts a focus session; the lengths default to
+// DefaultFocusMinutes and DefaultBreakMinutes
+type StartFocusSessionInput struct {
+	TaskID       string `json:"taskId"`
+	FocusMinutes *int   `json:"focusMinutes"`
+	BreakMinutes *int   `json:"breakMinutes"`
+}
+
+// ViewDisplayMode is how a client lays out the tasks of a saved view
+type ViewDisplayMode string
+
+// View display modes
+const (
+	ViewDisplayModeList     ViewDisplayMode = "LIST"
+	ViewDisplayModeBoard    ViewDisplayMode = "BOARD"
+	ViewDisplayModeCalendar ViewDisplayMode = "CALENDAR"
+)
+
+// SavedView is a named task filter and sort a user can come back to
+type SavedView struct {
+	ID          string          `json:"id"`
+	Name        string          `json:"name"`
+	Filter      TaskFilter      `json:"filter"`
+	Sort        *TaskSort       `json:"sort"`
+	DisplayMode ViewDisplayMode `json:"displayMode"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	UpdatedAt   time.Time       `json:"updatedAt"`
+}
+
+// CreateSavedViewInput saves a view; the display mode defaults to LIST
+type CreateSavedViewInput struct {
+	Name        string           `json:"name"`
+	Filter      *TaskFilter      `json:"filter"`
+	Sort        *TaskSort        `json:"sort"`
+	DisplayMode *ViewDisplayMode `json:"displayMode"`
+}
+
+// UpdateSavedViewInput changes a saved view; nil fields are left alone and a
+// given filter or sort replaces the saved one
+type UpdateSavedViewInput struct {
+	Name        *string          `json:"name"`
+	Filter      *TaskFilter      `json:"filter"`
+	Sort        *TaskSort        `json:"sort"`
+	ClearSort   *bool            `json:"clearSort"` // go back to newest first
+	DisplayMode *ViewDisplayMode `json:"displayMode"`
+}
+
+// TaskTemplateItem is one task of a template: the CreateTaskInput fields, with
+// dates given as days relative to the day the template is used
+type TaskTemplateItem struct {
+	Title           string       `json:"title"`
+	Description     string       `json:"description,omitempty"`
+	Status          TaskStatus   `json:"status"`
+	Priority        TaskPriority `json:"priority"`
+	CategoryID      *string      `json:"categoryId,omitempty"`
+	Tags            []string     `json:"tags,omitempty"`
+	EstimateMinutes *int         `json:"estimateMinutes,omitempty"`
+	DueOffsetDays   *int         `json:"dueOffsetDays,omitempty"`
+	StartOffsetDays *int         `json:"startOffsetDays,omitempty"`
+	Time            *string      `json:"time,omitempty"` // HH:MM for timed tasks, all-day without
+}
+
+// TaskTemplate is a named set of related tasks created together. Its owner
+// can share it with other users, who can use it but not change it.
+type TaskTemplate struct {
+	ID        string             `json:"id"`
+	UserID    string             `json:"userId"`
+	Name      string             `json:"name"`
+	Items     []TaskTemplateItem `json:"items"`
+	SharedBy  *string            `json:"sharedBy"` // the owner's name when shared with the user
+	CreatedAt time.Time          `json:"createdAt"`
+	UpdatedAt time.Time          `json:"updatedAt"`
+}
+
+// TaskTemplateInput creates a template
+type TaskTemplateInput struct {
+	Name  string             `json:"name"`
+	Items []TaskTemplateItem `json:"items"`
+}
+
+// UpdateTaskTemplateInput changes a template; given items replace the saved ones
+type UpdateTaskTemplateInput struct {
+	Name  *string            `json:"name"`
+	Items []TaskTemplateItem `json:"items"`
+}
+
+// TemplateOverrides adjusts the tasks created from a template. Offsets count
+// from Anchor, a YYYY-MM-DD day that defaults to today in the user's timezone.
+type TemplateOverrides struct {
+	Anchor     *string       `json:"anchor"`
+	Title      *string       `json:"title"` // only for templates with a single task
+	CategoryID *string       `json:"categoryId"`
+	Status     *TaskStatus   `json:"status"`
+	Priority   *TaskPriority `json:"priority"`
+	Tags       []string      `json:"tags"` // added to every task's tags
 }
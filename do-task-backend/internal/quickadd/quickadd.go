@@ -0,0 +1,561 @@
+// Package quickadd reads a task from a single line of text such as
+// "Call dentist tomorrow 3pm #health !high @Personal"
+package quickadd
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/export"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+)
+
+// Options control how text is read
+type Options struct {
+	// Location is the timezone dates and times are read in; UTC when nil
+	Location *time.Location
+	// Strict only recognises prefixed tokens (#tag, !priority, !date and
+	// @category) and leaves plain words such as "tomorrow" in the title
+	Strict bool
+}
+
+// Result is the task described by a line of text
+type Result struct {
+	Title    string
+	Due      *time.Time
+	AllDay   bool                // Due is a date without a time of day
+	Priority models.TaskPriority // empty when the text names none
+	Tags     []string
+	Category string // category name or "Parent/Child" path; empty when none
+	Tokens   []models.QuickAddToken
+}
+
+// DueDate returns the due date in the form CreateTaskInput takes: a
+// YYYY-MM-DD day for all-day dates and an RFC 3339 timestamp otherwise. It is
+// nil for text without a date.
+func (r Result) DueDate() *string {
+	if r.Due == nil {
+		return nil
+	}
+	due := r.Due.Format(time.RFC3339)
+	if r.AllDay {
+		due = r.Due.Format("2006-01-02")
+	}
+	return &due
+}
+
+// Parse reads text relative to now. Recognised tokens are removed from the
+// title:
+//
+//	#tag, #"two words"          tags
+//	!high, !medium, !low, !1-3  priority
+//	@Work, @Work/Errands, @"Side projects"  category
+//	today, tomorrow, friday, next week, in 3 days, jun 5, 5 june 2026,
+//	2025-07-01, or any of these after a "!"          due date
+//	3pm, 9:30am, 15:00, noon, at 5, in 2 hours       due time
+//
+// A weekday is the next one after today and a time without a date is the
+// next time that clock time comes round. A bare hour after "at" is read as a
+// working hour, so "at 9" is 9:00 and "at 5" is 17:00. Weekday abbreviations
+// such as "sun" and a bare "at 3" are also ordinary words, so they only count
+// after "on" or "by", or when nothing but other tokens follows them.
+func Parse(text string, now time.Time, opts Options) Result {
+	loc := opts.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	p := &parser{
+		now:    now.In(loc),
+		strict: opts.Strict,
+	}
+	p.today = time.Date(p.now.Year(), p.now.Month(), p.now.Day(), 0, 0, 0, 0, loc)
+	p.parse(split(text))
+	return p.result()
+}
+
+// parser holds the state of one Parse call
+type parser struct {
+	now    time.Time
+	today  time.Time
+	strict bool
+
+	title    []string
+	date     *time.Time // midnight of the due date
+	clock    *[2]int    // hour and minute
+	instant  *time.Time // set by "in 2 hours"
+	priority models.TaskPriority
+	tags     []string
+	category string
+	tokens   []models.QuickAddToken
+}
+
+func (p *parser) parse(words []string) {
+	for i := 0; i < len(words); {
+		if p.prefixed(words[i]) {
+			i++
+			continue
+		}
+		if !p.strict {
+			if n := p.phrase(words[i:]); n > 0 {
+				p.token(models.QuickAddTokenDueDate, strings.Join(words[i:i+n], " "))
+				i += n
+				continue
+			}
+		}
+		p.title = append(p.title, words[i])
+		i++
+	}
+}
+
+func (p *parser) token(kind models.QuickAddTokenKind, text string) {
+	p.tokens = append(p.tokens, models.QuickAddToken{Kind: kind, Text: text})
+}
+
+// prefixed handles #tag, @category and !priority or !date words
+func (p *parser) prefixed(word string) bool {
+	if len(word) < 2 {
+		return false
+	}
+	value := unquote(strings.TrimRight(word[1:], ",;."))
+	if value == "" {
+		return false
+	}
+
+	switch word[0] {
+	case '#':
+		p.tags = append(p.tags, value)
+		p.token(models.QuickAddTokenTag, word)
+		return true
+	case '@':
+		if p.category != "" {
+			return false
+		}
+		p.category = value
+		p.token(models.QuickAddTokenCategory, word)
+		return true
+	case '!':
+		if priority, ok := priorities[strings.ToLower(value)]; ok && p.priority == "" {
+			p.priority = priority
+			p.token(models.QuickAddTokenPriority, word)
+			return true
+		}
+		if p.date == nil && p.instant == nil {
+			if n, date, ok := p.matchDate([]string{normalize(value)}); ok && n == 1 {
+				p.date = &date
+				p.token(models.QuickAddTokenDueDate, word)
+				return true
+			}
+		}
+	}
+	return false
+}
+
+var priorities = map[string]models.TaskPriority{
+	"high": models.TaskPriorityHigh, "hi": models.TaskPriorityHigh, "h": models.TaskPriorityHigh,
+	"urgent": models.TaskPriorityHigh, "1": models.TaskPriorityHigh, "p1": models.TaskPriorityHigh,
+	"medium": models.TaskPriorityMedium, "med": models.TaskPriorityMedium, "m": models.TaskPriorityMedium,
+	"normal": models.TaskPriorityMedium, "2": models.TaskPriorityMedium, "p2": models.TaskPriorityMedium,
+	"low": models.TaskPriorityLow, "lo": models.TaskPriorityLow, "l": models.TaskPriorityLow,
+	"3": models.TaskPriorityLow, "p3": models.TaskPriorityLow,
+}
+
+// fillers may precede a date or time and are consumed with it
+var fillers = map[string]bool{"on": true, "at": true, "by": true, "due": true}
+
+// phrase matches a due date or time at the start of words and returns how
+// many words it used
+func (p *parser) phrase(words []string) int {
+	normalized := make([]string, 0, 5)
+	for _, word := range words {
+		if len(normalized) == cap(normalized) {
+			break
+		}
+		normalized = append(normalized, normalize(word))
+	}
+
+	skip := 0
+	if len(normalized) > 1 && fillers[normalized[0]] {
+		skip = 1
+	}
+	afterAt := skip == 1 && normalized[0] == "at"
+	anchored := skip == 1 && (normalized[0] == "on" || normalized[0] == "by")
+
+	saved := *p
+	n, ambiguous := p.phraseAt(normalized[skip:], afterAt)
+	if n == 0 {
+		return 0
+	}
+	if ambiguous && !anchored && !p.trailing(words[skip+n:]) {
+		*p = saved
+		return 0
+	}
+	return skip + n
+}
+
+// phraseAt applies the date or time at the start of words and returns how
+// many words it used, and whether they could just as well be ordinary words
+func (p *parser) phraseAt(words []string, afterAt bool) (int, bool) {
+	if p.instant != nil {
+		return 0, false
+	}
+	if p.date == nil && p.clock == nil {
+		if n, instant, ok := p.matchRelativeTime(words); ok {
+			p.instant = &instant
+			return n, false
+		}
+	}
+	if p.date == nil {
+		if n, date, ok := p.matchDate(words); ok {
+			p.date = &date
+			_, weekday := weekdays[words[0]]
+			return n, n == 1 && weekday && !strings.HasSuffix(words[0], "day")
+		}
+	}
+	if p.clock == nil {
+		if n, clock, ok := matchClock(words, afterAt); ok {
+			p.clock = &clock
+			return n, afterAt && n == 1 && bareHourPattern.MatchString(words[0])
+		}
+	}
+	return 0, false
+}
+
+// trailing reports whether words hold nothing but tokens, so that a phrase
+// right before them ends the title
+func (p *parser) trailing(words []string) bool {
+	probe := *p
+	probe.title, probe.tags, probe.tokens = nil, nil, nil
+	probe.parse(words)
+	return len(probe.title) == 0
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tues": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+var months = map[string]time.Month{
+	"jan": time.January, "january": time.January,
+	"feb": time.February, "february": time.February,
+	"mar": time.March, "march": time.March,
+	"apr": time.April, "april": time.April,
+	"may": time.May,
+	"jun": time.June, "june": time.June,
+	"jul": time.July, "july": time.July,
+	"aug": time.August, "august": time.August,
+	"sep": time.September, "sept": time.September, "september": time.September,
+	"oct": time.October, "october": time.October,
+	"nov": time.November, "november": time.November,
+	"dec": time.December, "december": time.December,
+}
+
+var dateUnits = map[string][3]int{
+	"day": {0, 0, 1}, "days": {0, 0, 1},
+	"week": {0, 0, 7}, "weeks": {0, 0, 7},
+	"month": {0, 1, 0}, "months": {0, 1, 0},
+	"year": {1, 0, 0}, "years": {1, 0, 0},
+}
+
+var timeUnits = map[string]time.Duration{
+	"minute": time.Minute, "minutes": time.Minute, "min": time.Minute, "mins": time.Minute,
+	"hour": time.Hour, "hours": time.Hour, "hr": time.Hour, "hrs": time.Hour,
+}
+
+// matchDate matches a calendar date and returns its midnight
+func (p *parser) matchDate(words []string) (int, time.Time, bool) {
+	if len(words) == 0 {
+		return 0, time.Time{}, false
+	}
+
+	switch words[0] {
+	case "today":
+		return 1, p.today, true
+	case "tomorrow", "tmr", "tmrw":
+		return 1, p.today.AddDate(0, 0, 1), true
+	}
+
+	if weekday, ok := weekdays[words[0]]; ok {
+		return 1, p.nextWeekday(weekday), true
+	}
+
+	if len(words) > 1 && (words[0] == "next" || words[0] == "this") {
+		if weekday, ok := weekdays[words[1]]; ok {
+			return 2, p.nextWeekday(weekday), true
+		}
+		if words[0] == "next" {
+			switch words[1] {
+			case "week":
+				return 2, p.today.AddDate(0, 0, 7), true
+			case "month":
+				return 2, p.today.AddDate(0, 1, 0), true
+			}
+		}
+	}
+
+	if len(words) > 2 && words[0] == "in" {
+		if count, ok := parseCount(words[1]); ok {
+			if unit, ok := dateUnits[words[2]]; ok {
+				return 3, p.today.AddDate(unit[0]*count, unit[1]*count, unit[2]*count), true
+			}
+		}
+	}
+
+	if date, err := time.ParseInLocation("2006-01-02", words[0], p.today.Location()); err == nil {
+		return 1, date, true
+	}
+
+	// "jun 5", "june 5th 2026", "5 june" and "5th of june 2026"
+	if len(words) > 1 {
+		if month, ok := months[words[0]]; ok {
+			if day, ok := parseDay(words[1]); ok {
+				return p.monthDay(words[2:], 2, month, day)
+			}
+		}
+		if day, ok := parseDay(words[0]); ok {
+			rest := words[1:]
+			used := 1
+			if len(rest) > 1 && rest[0] == "of" {
+				rest = rest[1:]
+				used++
+			}
+			if month, ok := months[rest[0]]; ok {
+				return p.monthDay(rest[1:], used+1, month, day)
+			}
+		}
+	}
+
+	return 0, time.Time{}, false
+}
+
+// monthDay finishes a month and day match with an optional year. Without a
+// year the next such date is meant.
+func (p *parser) monthDay(rest []string, used int, month time.Month, day int) (int, time.Time, bool) {
+	year := p.today.Year()
+	explicitYear := false
+	if len(rest) > 0 && len(rest[0]) == 4 {
+		if y, err := strconv.Atoi(rest[0]); err == nil {
+			year = y
+			explicitYear = true
+			used++
+		}
+	}
+
+	date := time.Date(year, month, day, 0, 0, 0, 0, p.today.Location())
+	if date.Month() != month {
+		return 0, time.Time{}, false
+	}
+	if !explicitYear && date.Before(p.today) {
+		date = time.Date(year+1, month, day, 0, 0, 0, 0, p.today.Location())
+	}
+	return used, date, true
+}
+
+// matchRelativeTime matches "in 2 hours" and "in 30 minutes"
+func (p *parser) matchRelativeTime(words []string) (int, time.Time, bool) {
+	if len(words) < 3 || words[0] != "in" {
+		return 0, time.Time{}, false
+	}
+	count, ok := parseCount(words[1])
+	if !ok {
+		return 0, time.Time{}, false
+	}
+	unit, ok := timeUnits[words[2]]
+	if !ok {
+		return 0, time.Time{}, false
+	}
+	return 3, p.now.Add(time.Duration(count) * unit).Truncate(time.Minute), true
+}
+
+func (p *parser) nextWeekday(weekday time.Weekday) time.Time {
+	days := (int(weekday) - int(p.today.Weekday()) + 7) % 7
+	if days == 0 {
+		days = 7
+	}
+	return p.today.AddDate(0, 0, days)
+}
+
+var (
+	clockPattern    = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(am|pm|a\.m|p\.m)?$`)
+	bareHourPattern = regexp.MustCompile(`^\d{1,2}$`)
+)
+
+// matchClock matches a time of day
+func matchClock(words []string, afterAt bool) (int, [2]int, bool) {
+	if len(words) == 0 {
+		return 0, [2]int{}, false
+	}
+
+	switch words[0] {
+	case "noon", "midday":
+		return 1, [2]int{12, 0}, true
+	case "midnight":
+		return 1, [2]int{0, 0}, true
+	}
+
+	m := clockPattern.FindStringSubmatch(words[0])
+	if m == nil {
+		return 0, [2]int{}, false
+	}
+	hour, _ := strconv.Atoi(m[1])
+	minute := 0
+	if m[2] != "" {
+		minute, _ = strconv.Atoi(m[2])
+	}
+
+	used := 1
+	meridiem := m[3]
+	if meridiem == "" && len(words) > 1 {
+		switch words[1] {
+		case "am", "pm", "a.m", "p.m":
+			meridiem = words[1]
+			used = 2
+		}
+	}
+
+	switch {
+	case meridiem != "":
+		if hour < 1 || hour > 12 {
+			return 0, [2]int{}, false
+		}
+		hour %= 12
+		if strings.HasPrefix(meridiem, "p") {
+			hour += 12
+		}
+	case m[2] != "":
+		// 24-hour clock
+	case afterAt:
+		// A bare "at 5" means a working hour
+		if hour >= 1 && hour <= 6 {
+			hour += 12
+		}
+	default:
+		return 0, [2]int{}, false
+	}
+
+	if hour > 23 || minute > 59 {
+		return 0, [2]int{}, false
+	}
+	return used, [2]int{hour, minute}, true
+}
+
+// parseCount reads "3", "a" or "an"
+func parseCount(word string) (int, bool) {
+	if word == "a" || word == "an" {
+		return 1, true
+	}
+	n, err := strconv.Atoi(word)
+	return n, err == nil && n > 0 && n < 1000
+}
+
+// parseDay reads a day of the month such as "5" or "5th"
+func parseDay(word string) (int, bool) {
+	for _, suffix := range []string{"st", "nd", "rd", "th"} {
+		word = strings.TrimSuffix(word, suffix)
+	}
+	day, err := strconv.Atoi(word)
+	return day, err == nil && day >= 1 && day <= 31
+}
+
+func (p *parser) result() Result {
+	result := Result{
+		Title:    strings.Join(p.title, " "),
+		Priority: p.priority,
+		Tags:     p.tags,
+		Category: p.category,
+		Tokens:   p.tokens,
+	}
+
+	switch {
+	case p.instant != nil:
+		result.Due = p.instant
+	case p.date != nil && p.clock != nil:
+		due := p.date.Add(time.Duration(p.clock[0])*time.Hour + time.Duration(p.clock[1])*time.Minute)
+		result.Due = &due
+	case p.date != nil:
+		result.Due = p.date
+		result.AllDay = true
+	case p.clock != nil:
+		due := time.Date(p.today.Year(), p.today.Month(), p.today.Day(), p.clock[0], p.clock[1], 0, 0, p.today.Location())
+		if !due.After(p.now) {
+			due = due.AddDate(0, 0, 1)
+		}
+		result.Due = &due
+	}
+
+	return result
+}
+
+// split breaks text into words, keeping #"..." and @"..." together
+func split(text string) []string {
+	var words []string
+	for text = strings.TrimSpace(text); text != ""; text = strings.TrimSpace(text) {
+		if len(text) > 2 && (text[0] == '#' || text[0] == '@') && text[1] == '"' {
+			if end := strings.IndexByte(text[2:], '"'); end >= 0 {
+				words = append(words, text[:end+3])
+				text = text[end+3:]
+				continue
+			}
+		}
+
+		end := strings.IndexFunc(text, unicode.IsSpace)
+		if end < 0 {
+			end = len(text)
+		}
+		words = append(words, text[:end])
+		text = text[end:]
+	}
+	return words
+}
+
+// normalize lower-cases a word and drops trailing punctuation for matching
+func normalize(word string) string {
+	return strings.TrimRight(strings.ToLower(word), ",;.")
+}
+
+func unquote(value string) string {
+	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+		return strings.TrimSpace(value[1 : len(value)-1])
+	}
+	return value
+}
+
+// MatchCategory finds the category a reference names: a full path with "/"
+// between levels, or a name that only one category has
+func MatchCategory(categories []models.Category, ref string) (string, bool) {
+	var names []string
+	for _, name := range strings.Split(ref, "/") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	want := strings.ToLower(strings.Join(names, models.CategoryPathSeparator))
+	if want == "" {
+		return "", false
+	}
+
+	for id, path := range export.CategoryPaths(categories) {
+		if strings.ToLower(path) == want {
+			return id, true
+		}
+	}
+
+	match := ""
+	for _, category := range categories {
+		if strings.ToLower(category.Name) == want {
+			if match != "" {
+				return "", false
+			}
+			match = category.ID
+		}
+	}
+	return match, match != ""
+}
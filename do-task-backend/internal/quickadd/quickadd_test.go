@@ -0,0 +1,328 @@
+package quickadd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+)
+
+var (
+	zone = time.FixedZone("UTC-5", -5*60*60)
+	// now is Wednesday 4 June 2025, 10:00 local time
+	now = time.Date(2025, time.June, 4, 10, 0, 0, 0, zone)
+)
+
+func at(year int, month time.Month, day, hour, minute int) *time.Time {
+	t := time.Date(year, month, day, hour, minute, 0, 0, zone)
+	return &t
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		strict   bool
+		title    string
+		due      *time.Time
+		allDay   bool
+		priority models.TaskPriority
+		tags     []string
+		category string
+	}{
+		{
+			name:     "full example",
+			text:     "Call dentist tomorrow 3pm #health !high",
+			title:    "Call dentist",
+			due:      at(2025, time.June, 5, 15, 0),
+			priority: models.TaskPriorityHigh,
+			tags:     []string{"health"},
+		},
+		{
+			name:  "plain title",
+			text:  "  Water the plants  ",
+			title: "Water the plants",
+		},
+		{
+			name:   "today",
+			text:   "Pay rent today",
+			title:  "Pay rent",
+			due:    at(2025, time.June, 4, 0, 0),
+			allDay: true,
+		},
+		{
+			name:   "weekday is the next one",
+			text:   "Standup notes on wednesday",
+			title:  "Standup notes",
+			due:    at(2025, time.June, 11, 0, 0),
+			allDay: true,
+		},
+		{
+			name:  "next weekday with time",
+			text:  "Review PR next fri at 9:30am",
+			title: "Review PR",
+			due:   at(2025, time.June, 6, 9, 30),
+		},
+		{
+			name:   "next week",
+			text:   "Plan sprint next week",
+			title:  "Plan sprint",
+			due:    at(2025, time.June, 11, 0, 0),
+			allDay: true,
+		},
+		{
+			name:   "in days",
+			text:   "Follow up in 3 days",
+			title:  "Follow up",
+			due:    at(2025, time.June, 7, 0, 0),
+			allDay: true,
+		},
+		{
+			name:  "in hours",
+			text:  "Check oven in 2 hours",
+			title: "Check oven",
+			due:   at(2025, time.June, 4, 12, 0),
+		},
+		{
+			name:   "month and day",
+			text:   "Renew passport jul 1st",
+			title:  "Renew passport",
+			due:    at(2025, time.July, 1, 0, 0),
+			allDay: true,
+		},
+		{
+			name:   "past month and day rolls over to next year",
+			text:   "Taxes by April 15",
+			title:  "Taxes",
+			due:    at(2026, time.April, 15, 0, 0),
+			allDay: true,
+		},
+		{
+			name:   "day of month with year",
+			text:   "Conference 5th of june 2026",
+			title:  "Conference",
+			due:    at(2026, time.June, 5, 0, 0),
+			allDay: true,
+		},
+		{
+			name:     "iso date",
+			text:     "Launch 2025-09-01 !1",
+			title:    "Launch",
+			due:      at(2025, time.September, 1, 0, 0),
+			allDay:   true,
+			priority: models.TaskPriorityHigh,
+		},
+		{
+			name:  "time later today",
+			text:  "Lunch with Sam noon",
+			title: "Lunch with Sam",
+			due:   at(2025, time.June, 4, 12, 0),
+		},
+		{
+			name:  "time already passed is tomorrow",
+			text:  "Morning run 7 am",
+			title: "Morning run",
+			due:   at(2025, time.June, 5, 7, 0),
+		},
+		{
+			name:  "bare hour after at is a working hour",
+			text:  "Meet Alex at 5",
+			title: "Meet Alex",
+			due:   at(2025, time.June, 4, 17, 0),
+		},
+		{
+			name:  "24-hour clock",
+			text:  "Deploy 21:15 friday",
+			title: "Deploy",
+			due:   at(2025, time.June, 6, 21, 15),
+		},
+		{
+			name:  "numbers that are not dates stay in the title",
+			text:  "Buy 3 apples and put 2 in 4 bags",
+			title: "Buy 3 apples and put 2 in 4 bags",
+		},
+		{
+			name:  "weekday abbreviation inside the title is a word",
+			text:  "Fix sun visor",
+			title: "Fix sun visor",
+		},
+		{
+			name:  "bare hour after at inside the title is a word",
+			text:  "Look at 3 options",
+			title: "Look at 3 options",
+		},
+		{
+			name:   "weekday abbreviation at the end",
+			text:   "Car wash sat",
+			title:  "Car wash",
+			due:    at(2025, time.June, 7, 0, 0),
+			allDay: true,
+		},
+		{
+			name:   "weekday abbreviation after on",
+			text:   "Plan on wed for the review",
+			title:  "Plan for the review",
+			due:    at(2025, time.June, 11, 0, 0),
+			allDay: true,
+		},
+		{
+			name:  "bare hour followed only by tokens",
+			text:  "Meet Alex at 5 #work",
+			title: "Meet Alex",
+			due:   at(2025, time.June, 4, 17, 0),
+			tags:  []string{"work"},
+		},
+		{
+			name:   "only the first date is used",
+			text:   "Move meeting from monday to tuesday",
+			title:  "Move meeting from to tuesday",
+			due:    at(2025, time.June, 9, 0, 0),
+			allDay: true,
+		},
+		{
+			name:     "category and quoted tag",
+			text:     `Fix gutter @Home/Garden #"weekend job" !low`,
+			title:    "Fix gutter",
+			priority: models.TaskPriorityLow,
+			tags:     []string{"weekend job"},
+			category: "Home/Garden",
+		},
+		{
+			name:     "quoted category",
+			text:     `Write chapter @"Side projects"`,
+			title:    "Write chapter",
+			category: "Side projects",
+		},
+		{
+			name:   "bang date",
+			text:   "Send invoice !tomorrow",
+			title:  "Send invoice",
+			due:    at(2025, time.June, 5, 0, 0),
+			allDay: true,
+		},
+		{
+			name:  "unknown bang words stay in the title",
+			text:  "Celebrate !!! !soon",
+			title: "Celebrate !!! !soon",
+		},
+		{
+			name:   "strict mode ignores plain words",
+			text:   "Notes from monday meeting #work !tomorrow",
+			strict: true,
+			title:  "Notes from monday meeting",
+			due:    at(2025, time.June, 5, 0, 0),
+			allDay: true,
+			tags:   []string{"work"},
+		},
+		{
+			name:  "trailing punctuation",
+			text:  "Call mom tomorrow, 6pm.",
+			title: "Call mom",
+			due:   at(2025, time.June, 5, 18, 0),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Parse(tt.text, now, Options{Location: zone, Strict: tt.strict})
+
+			if got.Title != tt.title {
+				t.Errorf("title = %q, want %q", got.Title, tt.title)
+			}
+			switch {
+			case (got.Due == nil) != (tt.due == nil):
+				t.Errorf("due = %v, want %v", got.Due, tt.due)
+			case got.Due != nil && !got.Due.Equal(*tt.due):
+				t.Errorf("due = %v, want %v", *got.Due, *tt.due)
+			}
+			if got.AllDay != tt.allDay {
+				t.Errorf("allDay = %v, want %v", got.AllDay, tt.allDay)
+			}
+			if got.Priority != tt.priority {
+				t.Errorf("priority = %q, want %q", got.Priority, tt.priority)
+			}
+			if !reflect.DeepEqual(got.Tags, tt.tags) {
+				t.Errorf("tags = %q, want %q", got.Tags, tt.tags)
+			}
+			if got.Category != tt.category {
+				t.Errorf("category = %q, want %q", got.Category, tt.category)
+			}
+		})
+	}
+}
+
+func TestParseTokens(t *testing.T) {
+	got := Parse("Call dentist on friday at 3pm #health !high @Personal", now, Options{Location: zone})
+	want := []models.QuickAddToken{
+		{Kind: models.QuickAddTokenDueDate, Text: "on friday"},
+		{Kind: models.QuickAddTokenDueDate, Text: "at 3pm"},
+		{Kind: models.QuickAddTokenTag, Text: "#health"},
+		{Kind: models.QuickAddTokenPriority, Text: "!high"},
+		{Kind: models.QuickAddTokenCategory, Text: "@Personal"},
+	}
+	if !reflect.DeepEqual(got.Tokens, want) {
+		t.Errorf("tokens = %+v, want %+v", got.Tokens, want)
+	}
+}
+
+func TestResultDueDate(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "Call dentist", want: ""},
+		{text: "Call dentist friday", want: "2025-06-06"},
+		{text: "Call dentist friday 3pm", want: "2025-06-06T15:00:00-05:00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			var got string
+			if due := Parse(tt.text, now, Options{Location: zone}).DueDate(); due != nil {
+				got = *due
+			}
+			if got != tt.want {
+				t.Errorf("DueDate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchCategory(t *testing.T) {
+	categories := []models.Category{
+		{ID: "home", Name: "Home"},
+		{ID: "garden", Name: "Garden", ParentID: stringPtr("home")},
+		{ID: "work", Name: "Work"},
+		{ID: "work-misc", Name: "Misc", ParentID: stringPtr("work")},
+		{ID: "home-misc", Name: "Misc", ParentID: stringPtr("home")},
+	}
+
+	tests := []struct {
+		ref    string
+		id     string
+		wantOK bool
+	}{
+		{ref: "work", id: "work", wantOK: true},
+		{ref: "Home/Garden", id: "garden", wantOK: true},
+		{ref: " home / garden ", id: "garden", wantOK: true},
+		{ref: "garden", id: "garden", wantOK: true},
+		{ref: "Work/Misc", id: "work-misc", wantOK: true},
+		{ref: "misc", wantOK: false}, // ambiguous
+		{ref: "Errands", wantOK: false},
+		{ref: "/", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			id, ok := MatchCategory(categories, tt.ref)
+			if ok != tt.wantOK || id != tt.id {
+				t.Errorf("MatchCategory(%q) = %q, %v, want %q, %v", tt.ref, id, ok, tt.id, tt.wantOK)
+			}
+		})
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
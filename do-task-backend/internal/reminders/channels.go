@@ -0,0 +1,70 @@
+package reminders
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/database"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/mailer"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+)
+
+// EmailChannel emails the reminder to the task owner
+type EmailChannel struct {
+	Mailer mailer.Mailer
+}
+
+// Send emails reminder. Users who switched email notifications off are
+// skipped, which counts as delivered.
+func (c *EmailChannel) Send(ctx context.Context, reminder models.DueReminder) error {
+	if !reminder.User.Preferences.Notifications.Email {
+		return nil
+	}
+
+	var due string
+	if reminder.Task.DueDate != nil {
+		due = ", due " + reminder.User.Preferences.FormatDue(*reminder.Task)
+	}
+	text := fmt.Sprintf("Hi %s,\n\nThis is your reminder for \"%s\"%s.\n",
+		reminder.User.Name, reminder.Task.Title, due)
+	if reminder.Task.Description != "" {
+		text += "\n" + reminder.Task.Description + "\n"
+	}
+
+	return c.Mailer.Send(ctx, mailer.Message{
+		To:      reminder.User.Email,
+		Subject: "Reminder: " + reminder.Task.Title,
+		Text:    text,
+	})
+}
+
+// WebhookChannel queues a reminder.due event for the owner's webhooks
+type WebhookChannel struct {
+	DB *database.DB
+}
+
+// Send queues the event; the webhook dispatcher delivers it
+func (c *WebhookChannel) Send(ctx context.Context, reminder models.DueReminder) error {
+	return c.DB.EmitReminderEvent(reminder)
+}
+
+// InAppChannel adds a notification to the owner's notification list
+type InAppChannel struct {
+	DB *database.DB
+}
+
+// Send stores the notification
+func (c *InAppChannel) Send(ctx context.Context, reminder models.DueReminder) error {
+	var body string
+	if reminder.Task.DueDate != nil {
+		body = "Due " + reminder.User.Preferences.FormatDue(*reminder.Task)
+	}
+	_, err := c.DB.CreateNotification(models.NewNotification{
+		UserID: reminder.User.ID,
+		Type:   models.NotificationTypeReminder,
+		Title:  reminder.Task.Title,
+		Body:   body,
+		TaskID: &reminder.Task.ID,
+	})
+	return err
+}
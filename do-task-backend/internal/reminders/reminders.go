@@ -0,0 +1,135 @@
+// Package reminders sends task reminders when they fall due. Each reminder
+// goes out through its channels (email, webhook, in-app), and channels that
+// fail are retried without repeating the ones that already succeeded.
+package reminders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/database"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/mailer"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+)
+
+const (
+	// MaxAttempts is how often a reminder is tried before it is marked FAILED
+	MaxAttempts = 5
+	// lease keeps a claimed reminder away from other replicas while it is sent
+	lease       = 2 * time.Minute
+	sendTimeout = 30 * time.Second
+)
+
+// Channel delivers a reminder one way
+type Channel interface {
+	Send(ctx context.Context, reminder models.DueReminder) error
+}
+
+// Scheduler fires due reminders
+type Scheduler struct {
+	DB           *database.DB
+	Channels     map[models.ReminderChannel]Channel
+	PollInterval time.Duration
+	BatchSize    int
+}
+
+// NewScheduler returns a scheduler with the built-in channels
+func NewScheduler(db *database.DB, m mailer.Mailer) *Scheduler {
+	return &Scheduler{
+		DB: db,
+		Channels: map[models.ReminderChannel]Channel{
+			models.ReminderChannelEmail:   &EmailChannel{Mailer: m},
+			models.ReminderChannelWebhook: &WebhookChannel{DB: db},
+			models.ReminderChannelInApp:   &InAppChannel{DB: db},
+		},
+		PollInterval: 30 * time.Second,
+		BatchSize:    50,
+	}
+}
+
+// Run fires due reminders, and raises overdue alerts for tasks whose due date
+// passed, until ctx is cancelled
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.ProcessDue(ctx); err != nil {
+			log.Printf("reminders: %v", err)
+		}
+		if _, err := s.DB.NotifyOverdueTasks(s.BatchSize); err != nil {
+			log.Printf("reminders: overdue tasks: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessDue fires one batch of due reminders and returns how many were handled
+func (s *Scheduler) ProcessDue(ctx context.Context) (int, error) {
+	due, err := s.DB.ClaimDueReminders(s.BatchSize, lease)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, reminder := range due {
+		if err := s.fire(ctx, reminder); err != nil {
+			return 0, err
+		}
+	}
+	return len(due), nil
+}
+
+// fire sends a claimed reminder through its outstanding channels and records the outcome
+func (s *Scheduler) fire(ctx context.Context, reminder models.DueReminder) error {
+	task := reminder.Task
+	if task == nil || task.Status == models.TaskStatusCompleted || !reminder.User.Preferences.Notifications.TaskReminders {
+		return s.DB.RecordReminderAttempt(reminder.ReminderID, models.ReminderStatusSkipped, reminder.Delivered, nil, nil)
+	}
+
+	delivered := reminder.Delivered
+	done := make(map[models.ReminderChannel]bool, len(delivered))
+	for _, channel := range delivered {
+		done[channel] = true
+	}
+
+	var errs []error
+	for _, name := range reminder.Channels {
+		if done[name] {
+			continue
+		}
+
+		channel, ok := s.Channels[name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: channel not configured", name))
+			continue
+		}
+
+		sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+		err := channel.Send(sendCtx, reminder)
+		cancel()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		delivered = append(delivered, name)
+	}
+
+	if len(errs) == 0 {
+		return s.DB.RecordReminderAttempt(reminder.ReminderID, models.ReminderStatusSent, delivered, nil, nil)
+	}
+
+	sendErr := errors.Join(errs...)
+	if reminder.Attempt >= MaxAttempts {
+		return s.DB.RecordReminderAttempt(reminder.ReminderID, models.ReminderStatusFailed, delivered, sendErr, nil)
+	}
+	retryAt := time.Now().Add(time.Duration(reminder.Attempt*reminder.Attempt) * time.Minute)
+	return s.DB.RecordReminderAttempt(reminder.ReminderID, models.ReminderStatusPending, delivered, sendErr, &retryAt)
+}
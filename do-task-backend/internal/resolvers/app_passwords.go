@@ -0,0 +1,95 @@
+package resolvers
+
+import (
+	"context"
+	"time"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/auth"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/caldav"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/graph/generated"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+)
+
+// appPasswordPrefix makes app passwords recognisable, e.g. in secret scanners
+const appPasswordPrefix = "dtp_"
+
+// AppPassword returns the app password resolver
+func (r *Resolver) AppPassword() generated.AppPasswordResolver {
+	return &appPasswordResolver{r}
+}
+
+type appPasswordResolver struct{ *Resolver }
+
+// AppPasswords returns the authenticated user's app passwords
+func (r *queryResolver) AppPasswords(ctx context.Context) ([]*models.AppPassword, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	appPasswords, err := r.DB.GetAppPasswords(userInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert to pointer slice
+	result := make([]*models.AppPassword, len(appPasswords))
+	for i := range appPasswords {
+		appPassword := appPasswords[i]
+		result[i] = &appPassword
+	}
+	return result, nil
+}
+
+// CreateAppPassword creates an app password for the authenticated user and
+// returns its secret, which cannot be retrieved again
+func (r *mutationResolver) CreateAppPassword(ctx context.Context, name string) (*models.NewAppPassword, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := auth.GenerateSecretToken()
+	if err != nil {
+		return nil, err
+	}
+	secret := appPasswordPrefix + token
+
+	appPassword, err := r.DB.CreateAppPassword(name, auth.HashSecretToken(secret), userInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.NewAppPassword{
+		AppPassword: &appPassword,
+		Secret:      secret,
+		CaldavURL:   publicURL(ctx, caldav.Prefix+"/"),
+	}, nil
+}
+
+// RevokeAppPassword deletes one of the authenticated user's app passwords
+func (r *mutationResolver) RevokeAppPassword(ctx context.Context, id string) (bool, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if err := r.DB.DeleteAppPassword(id, userInfo.ID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// CreatedAt resolves the createdAt field for AppPassword
+func (r *appPasswordResolver) CreatedAt(ctx context.Context, obj *models.AppPassword) (string, error) {
+	return obj.CreatedAt.Format(time.RFC3339), nil
+}
+
+// LastUsedAt resolves the lastUsedAt field for AppPassword
+func (r *appPasswordResolver) LastUsedAt(ctx context.Context, obj *models.AppPassword) (*string, error) {
+	if obj.LastUsedAt == nil {
+		return nil, nil
+	}
+	lastUsedAt := obj.LastUsedAt.Format(time.RFC3339)
+	return &lastUsedAt, nil
+}
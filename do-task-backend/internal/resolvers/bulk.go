@@ -0,0 +1,60 @@
+package resolvers
+
+import (
+	"context"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/auth"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+)
+
+// BulkUpdateTasks applies the same update to several of the authenticated user's tasks
+func (r *mutationResolver) BulkUpdateTasks(ctx context.Context, ids []string, input models.UpdateTaskInput) (*models.BulkTaskResult, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.normalizeUpdateTaskInput(&input, userInfo.ID); err != nil {
+		return nil, err
+	}
+
+	result, err := r.DB.BulkUpdateTasks(ids, input, userInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.DueDate != nil || input.Status != nil {
+		for _, item := range result.Results {
+			r.notifyTaskSaved(item.Task)
+		}
+	}
+	return &result, nil
+}
+
+// BulkDeleteTasks deletes several of the authenticated user's tasks
+func (r *mutationResolver) BulkDeleteTasks(ctx context.Context, ids []string) (*models.BulkTaskResult, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := r.DB.BulkDeleteTasks(ids, userInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// BulkMoveTasks moves several of the authenticated user's tasks into a category
+func (r *mutationResolver) BulkMoveTasks(ctx context.Context, ids []string, categoryID string) (*models.BulkTaskResult, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := r.DB.BulkMoveTasks(ids, categoryID, userInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -0,0 +1,99 @@
+package resolvers
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/auth"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/calendar"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/graph/generated"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// CalendarFeed returns the calendar feed resolver
+func (r *Resolver) CalendarFeed() generated.CalendarFeedResolver {
+	return &calendarFeedResolver{r}
+}
+
+type calendarFeedResolver struct{ *Resolver }
+
+// CalendarFeed returns the authenticated user's calendar feed, or null if it is disabled
+func (r *queryResolver) CalendarFeed(ctx context.Context) (*models.CalendarFeed, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	feed, err := r.DB.GetCalendarFeed(userInfo.ID)
+	if err != nil || feed == nil {
+		return nil, err
+	}
+
+	feed.URL = publicURL(ctx, calendar.FeedPath(feed.Token))
+	return feed, nil
+}
+
+// RotateCalendarFeedToken enables the calendar feed, or replaces its token so
+// the previous URL stops working
+func (r *mutationResolver) RotateCalendarFeedToken(ctx context.Context) (*models.CalendarFeed, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := auth.GenerateSecretToken()
+	if err != nil {
+		return nil, err
+	}
+
+	feed, err := r.DB.SetCalendarFeedToken(userInfo.ID, token)
+	if err != nil {
+		return nil, err
+	}
+
+	feed.URL = publicURL(ctx, calendar.FeedPath(feed.Token))
+	return &feed, nil
+}
+
+// DisableCalendarFeed turns off the authenticated user's calendar feed
+func (r *mutationResolver) DisableCalendarFeed(ctx context.Context) (bool, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if err := r.DB.DeleteCalendarFeed(userInfo.ID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// CreatedAt resolves the createdAt field for CalendarFeed
+func (r *calendarFeedResolver) CreatedAt(ctx context.Context, obj *models.CalendarFeed) (string, error) {
+	return obj.CreatedAt.Format(time.RFC3339), nil
+}
+
+// publicURL turns a server path into an absolute URL. PUBLIC_API_URL wins when
+// set, since behind a proxy the request host is not necessarily reachable.
+func publicURL(ctx context.Context, path string) string {
+	if base := os.Getenv("PUBLIC_API_URL"); base != "" {
+		return strings.TrimSuffix(base, "/") + path
+	}
+
+	ginContext, exists := ctx.Value("GinContextKey").(*gin.Context)
+	if !exists {
+		return path
+	}
+
+	scheme := "http"
+	if ginContext.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := ginContext.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + ginContext.Request.Host + path
+}
@@ -0,0 +1,42 @@
+package resolvers
+
+import (
+	"context"
+	"time"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/auth"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/digest"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/graph/generated"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+)
+
+// WeeklyDigest returns the weekly digest resolver
+func (r *Resolver) WeeklyDigest() generated.WeeklyDigestResolver {
+	return &weeklyDigestResolver{r}
+}
+
+type weeklyDigestResolver struct{ *Resolver }
+
+// WeeklyDigestPreview renders the authenticated user's weekly digest as it would be sent now
+func (r *queryResolver) WeeklyDigestPreview(ctx context.Context) (*models.WeeklyDigest, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := r.DB.GetUserByID(userInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	preview, err := digest.Compose(r.DB, user, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return &preview, nil
+}
+
+// WeekStart resolves the weekStart field for WeeklyDigest, keeping the user's UTC offset
+func (r *weeklyDigestResolver) WeekStart(ctx context.Context, obj *models.WeeklyDigest) (string, error) {
+	return obj.WeekStart.Format(time.RFC3339), nil
+}
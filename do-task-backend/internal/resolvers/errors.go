@@ -0,0 +1,73 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/apperr"
+	"github.com/lib/pq"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// invalidTextRepresentation is the Postgres error code for a value that does
+// not parse as its column type, such as a bad UUID
+const invalidTextRepresentation = "22P02"
+
+// PresentError is the GraphQL error presenter. Application errors keep their
+// message and get their code in extensions.code, with the invalid fields of a
+// validation error in extensions.fields. Errors gqlgen raises for malformed
+// input pass through. Anything else is logged and reported as an internal
+// error without its details, which may come from the database.
+func PresentError(ctx context.Context, err error) *gqlerror.Error {
+	var appErr *apperr.Error
+	if errors.As(err, &appErr) {
+		gqlErr := graphql.DefaultErrorPresenter(ctx, err)
+		if gqlErr.Extensions == nil {
+			gqlErr.Extensions = map[string]interface{}{}
+		}
+		gqlErr.Extensions["code"] = appErr.Code
+		if len(appErr.Fields) > 0 {
+			gqlErr.Extensions["fields"] = appErr.Fields
+		}
+		for key, value := range appErr.Details {
+			gqlErr.Extensions[key] = value
+		}
+		return gqlErr
+	}
+
+	// A malformed ID only fails once Postgres casts it to a UUID
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == invalidTextRepresentation {
+		return PresentError(ctx, apperr.Invalid("", "invalid ID"))
+	}
+
+	var gqlErr *gqlerror.Error
+	if errors.As(err, &gqlErr) {
+		if _, ok := gqlErr.Extensions["code"]; !ok {
+			if gqlErr.Extensions == nil {
+				gqlErr.Extensions = map[string]interface{}{}
+			}
+			gqlErr.Extensions["code"] = apperr.CodeValidation
+		}
+		return gqlErr
+	}
+
+	path := graphql.GetPath(ctx)
+	log.Printf("GraphQL error at %s: %v", path.String(), err)
+	return &gqlerror.Error{
+		Message:    "internal server error",
+		Path:       path,
+		Extensions: map[string]interface{}{"code": apperr.CodeInternal},
+	}
+}
+
+// RecoverPanic logs a panic in a resolver and reports it as an internal error
+func RecoverPanic(ctx context.Context, err interface{}) error {
+	log.Printf("GraphQL panic at %s: %v", graphql.GetPath(ctx).String(), err)
+	return &gqlerror.Error{
+		Message:    "internal server error",
+		Extensions: map[string]interface{}{"code": apperr.CodeInternal},
+	}
+}
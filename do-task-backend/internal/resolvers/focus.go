@@ -0,0 +1,109 @@
+package resolvers
+
+import (
+	"context"
+	"time"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/auth"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/graph/generated"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+)
+
+// FocusSession returns the focus session resolver
+func (r *Resolver) FocusSession() generated.FocusSessionResolver {
+	return &focusSessionResolver{r}
+}
+
+type focusSessionResolver struct{ *Resolver }
+
+// CurrentFocusSession returns the authenticated user's focus session in progress, if any
+func (r *queryResolver) CurrentFocusSession(ctx context.Context) (*models.FocusSession, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.DB.GetCurrentFocusSession(userInfo.ID)
+}
+
+// StartFocusSession starts a focus session on one of the authenticated user's tasks
+func (r *mutationResolver) StartFocusSession(ctx context.Context, input models.StartFocusSessionInput) (*models.FocusSession, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := r.DB.StartFocusSession(input, userInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// CompleteFocusSession completes the authenticated user's active focus session
+func (r *mutationResolver) CompleteFocusSession(ctx context.Context, id string) (*models.FocusSession, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := r.DB.CompleteFocusSession(id, userInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// AbandonFocusSession gives up the authenticated user's active focus session
+func (r *mutationResolver) AbandonFocusSession(ctx context.Context, id string) (*models.FocusSession, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := r.DB.AbandonFocusSession(id, userInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Task resolves the task field for FocusSession; it is null while the task is in the trash
+func (r *focusSessionResolver) Task(ctx context.Context, obj *models.FocusSession) (*models.Task, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.DB.LookupTask(obj.TaskID, userInfo.ID)
+}
+
+// StartedAt resolves the startedAt field for FocusSession
+func (r *focusSessionResolver) StartedAt(ctx context.Context, obj *models.FocusSession) (string, error) {
+	return obj.StartedAt.Format(time.RFC3339), nil
+}
+
+// EndsAt resolves the endsAt field for FocusSession
+func (r *focusSessionResolver) EndsAt(ctx context.Context, obj *models.FocusSession) (string, error) {
+	return obj.EndsAt.Format(time.RFC3339), nil
+}
+
+// BreakEndsAt resolves the breakEndsAt field for FocusSession
+func (r *focusSessionResolver) BreakEndsAt(ctx context.Context, obj *models.FocusSession) (string, error) {
+	return obj.BreakEndsAt.Format(time.RFC3339), nil
+}
+
+// Phase resolves the phase field for FocusSession from the server clock
+func (r *focusSessionResolver) Phase(ctx context.Context, obj *models.FocusSession) (models.FocusPhase, error) {
+	return obj.PhaseAt(time.Now()), nil
+}
+
+// RemainingSeconds resolves the remainingSeconds field for FocusSession from the server clock
+func (r *focusSessionResolver) RemainingSeconds(ctx context.Context, obj *models.FocusSession) (int, error) {
+	return obj.RemainingSecondsAt(time.Now()), nil
+}
+
+// EndedAt resolves the endedAt field for FocusSession
+func (r *focusSessionResolver) EndedAt(ctx context.Context, obj *models.FocusSession) (*string, error) {
+	return formatOptionalTime(obj.EndedAt), nil
+}
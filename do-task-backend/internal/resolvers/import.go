@@ -0,0 +1,30 @@
+package resolvers
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/apperr"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/auth"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/importer"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+)
+
+// ImportTasks imports tasks from an uploaded file for the authenticated user
+func (r *mutationResolver) ImportTasks(ctx context.Context, file graphql.Upload, format models.ImportFormat, dryRun *bool) (*models.ImportResult, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if file.Size > importer.MaxFileSize {
+		return nil, apperr.Invalid("file", "import files are limited to %d MB", importer.MaxFileSize>>20)
+	}
+
+	im := &importer.Importer{DB: r.DB}
+	result, err := im.Run(userInfo.ID, format, file.File, dryRun != nil && *dryRun)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -0,0 +1,106 @@
+package resolvers
+
+import (
+	"context"
+	"time"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/attachments"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/auth"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/graph/generated"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/mailin"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+)
+
+// Attachment returns the attachment resolver
+func (r *Resolver) Attachment() generated.AttachmentResolver {
+	return &attachmentResolver{r}
+}
+
+type attachmentResolver struct{ *Resolver }
+
+// InboundEmailAddress returns the authenticated user's email-to-task address,
+// or null if it is disabled
+func (r *queryResolver) InboundEmailAddress(ctx context.Context) (*string, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := r.DB.GetInboundEmailToken(userInfo.ID)
+	if err != nil || token == nil {
+		return nil, err
+	}
+
+	address, err := mailin.Address(*token)
+	if err != nil {
+		return nil, err
+	}
+	return &address, nil
+}
+
+// RotateInboundEmailAddress enables email-to-task, or replaces the address so
+// mail to the previous one is refused
+func (r *mutationResolver) RotateInboundEmailAddress(ctx context.Context) (string, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	token, err := mailin.NewToken()
+	if err != nil {
+		return "", err
+	}
+
+	address, err := mailin.Address(token)
+	if err != nil {
+		return "", err
+	}
+
+	if err := r.DB.SetInboundEmailToken(userInfo.ID, token); err != nil {
+		return "", err
+	}
+	return address, nil
+}
+
+// DisableInboundEmail turns off email-to-task for the authenticated user
+func (r *mutationResolver) DisableInboundEmail(ctx context.Context) (bool, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if err := r.DB.DeleteInboundEmailToken(userInfo.ID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Attachments resolves the attachments field for Task
+func (r *taskResolver) Attachments(ctx context.Context, obj *models.Task) ([]*models.Attachment, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	taskAttachments, err := r.DB.GetTaskAttachments(obj.ID, userInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert to pointer slice
+	result := make([]*models.Attachment, len(taskAttachments))
+	for i := range taskAttachments {
+		result[i] = &taskAttachments[i]
+	}
+	return result, nil
+}
+
+// URL resolves the url field for Attachment
+func (r *attachmentResolver) URL(ctx context.Context, obj *models.Attachment) (string, error) {
+	return publicURL(ctx, attachments.Path(obj.ID)), nil
+}
+
+// CreatedAt resolves the createdAt field for Attachment
+func (r *attachmentResolver) CreatedAt(ctx context.Context, obj *models.Attachment) (string, error) {
+	return obj.CreatedAt.Format(time.RFC3339), nil
+}
@@ -0,0 +1,179 @@
+package resolvers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/apperr"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/auth"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/graph/generated"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+)
+
+// maxNotificationPage caps the first argument of the notifications query
+const maxNotificationPage = 100
+
+// dueSoonWindow is how close a due date must be for a TASK_DUE_SOON notification
+const dueSoonWindow = 24 * time.Hour
+
+// Notification returns the notification resolver
+func (r *Resolver) Notification() generated.NotificationResolver {
+	return &notificationResolver{r}
+}
+
+type notificationResolver struct{ *Resolver }
+
+// Notifications returns a page of the authenticated user's notifications
+func (r *queryResolver) Notifications(ctx context.Context, first *int, after *string, unreadOnly *bool) (*models.NotificationConnection, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pageSize := 20
+	if first != nil {
+		pageSize = *first
+	}
+	if pageSize < 1 || pageSize > maxNotificationPage {
+		return nil, apperr.Invalid("first", "first must be between 1 and %d", maxNotificationPage)
+	}
+
+	connection, err := r.DB.GetNotifications(userInfo.ID, pageSize, after, unreadOnly != nil && *unreadOnly)
+	if err != nil {
+		return nil, err
+	}
+	return &connection, nil
+}
+
+// UnreadNotificationCount returns how many unread notifications the authenticated user has
+func (r *queryResolver) UnreadNotificationCount(ctx context.Context) (int, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return r.DB.CountUnreadNotifications(userInfo.ID)
+}
+
+// NotificationPreferences returns the authenticated user's setting for every notification type
+func (r *queryResolver) NotificationPreferences(ctx context.Context) ([]*models.NotificationPreference, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	preferences, err := r.DB.GetNotificationPreferences(userInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert to pointer slice
+	result := make([]*models.NotificationPreference, len(preferences))
+	for i := range preferences {
+		result[i] = &preferences[i]
+	}
+	return result, nil
+}
+
+// MarkNotificationRead marks one of the authenticated user's notifications as read
+func (r *mutationResolver) MarkNotificationRead(ctx context.Context, id string) (*models.Notification, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	notification, err := r.DB.MarkNotificationRead(id, userInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &notification, nil
+}
+
+// MarkAllNotificationsRead marks all of the authenticated user's notifications
+// as read and returns how many were unread
+func (r *mutationResolver) MarkAllNotificationsRead(ctx context.Context) (int, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return r.DB.MarkAllNotificationsRead(userInfo.ID)
+}
+
+// UpdateNotificationPreference switches a type of notification on or off for the authenticated user
+func (r *mutationResolver) UpdateNotificationPreference(ctx context.Context, notificationType models.NotificationType, enabled bool) (*models.NotificationPreference, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	preference, err := r.DB.SetNotificationPreference(userInfo.ID, notificationType, enabled)
+	if err != nil {
+		return nil, err
+	}
+	return &preference, nil
+}
+
+// Task resolves the task field for Notification
+func (r *notificationResolver) Task(ctx context.Context, obj *models.Notification) (*models.Task, error) {
+	if obj.TaskID == nil {
+		return nil, nil
+	}
+
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.DB.LookupTask(*obj.TaskID, userInfo.ID)
+}
+
+// Read resolves the read field for Notification
+func (r *notificationResolver) Read(ctx context.Context, obj *models.Notification) (bool, error) {
+	return obj.ReadAt != nil, nil
+}
+
+// ReadAt resolves the readAt field for Notification
+func (r *notificationResolver) ReadAt(ctx context.Context, obj *models.Notification) (*string, error) {
+	return formatOptionalTime(obj.ReadAt), nil
+}
+
+// CreatedAt resolves the createdAt field for Notification
+func (r *notificationResolver) CreatedAt(ctx context.Context, obj *models.Notification) (string, error) {
+	return obj.CreatedAt.Format(time.RFC3339), nil
+}
+
+// notifyTaskSaved is the notification hook of the task mutations. It raises a
+// due-soon alert for a task saved with such a due date, and clears the
+// overdue and due-soon alerts once the task is completed. Overdue alerts come
+// from the reminder scheduler. Failures are logged; they never fail the
+// mutation.
+func (r *Resolver) notifyTaskSaved(task *models.Task) {
+	if task == nil {
+		return
+	}
+
+	var err error
+	now := time.Now()
+	switch {
+	case task.Status == models.TaskStatusCompleted:
+		err = r.DB.MarkTaskNotificationsRead(task.ID, task.UserID,
+			models.NotificationTypeTaskOverdue, models.NotificationTypeTaskDueSoon)
+	case task.IsOverdue(now):
+		// The reminder scheduler raises the overdue alert
+	case task.DueDate != nil && task.DueDate.Before(now.Add(dueSoonWindow)):
+		_, err = r.DB.CreateNotification(models.NewNotification{
+			UserID:      task.UserID,
+			Type:        models.NotificationTypeTaskDueSoon,
+			Title:       task.Title,
+			Body:        "Due " + r.formatDueForUser(*task),
+			TaskID:      &task.ID,
+			OncePerTask: true,
+		})
+	}
+
+	if err != nil {
+		log.Printf("notifications: task %s: %v", task.ID, err)
+	}
+}
@@ -0,0 +1,100 @@
+package resolvers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/apperr"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/auth"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+)
+
+// UpdatePreferences changes the authenticated user's preferences
+func (r *mutationResolver) UpdatePreferences(ctx context.Context, input models.UpdatePreferencesInput) (*models.UserPreferences, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validatePreferences(&input); err != nil {
+		return nil, err
+	}
+
+	user, err := r.DB.UpdateUserPreferences(userInfo.ID, input)
+	if err != nil {
+		return nil, err
+	}
+	return &user.Preferences, nil
+}
+
+// TaskReminders resolves the taskReminders field for User
+func (r *userResolver) TaskReminders(ctx context.Context, obj *models.User) (bool, error) {
+	return obj.Preferences.Notifications.TaskReminders, nil
+}
+
+// WeeklyDigest resolves the weeklyDigest field for User
+func (r *userResolver) WeeklyDigest(ctx context.Context, obj *models.User) (bool, error) {
+	return obj.Preferences.Notifications.WeeklyDigest, nil
+}
+
+// Timezone resolves the timezone field for User
+func (r *userResolver) Timezone(ctx context.Context, obj *models.User) (string, error) {
+	return obj.Preferences.Timezone, nil
+}
+
+// formatDueForUser writes a task's due date in its owner's timezone and date
+// format, falling back to the defaults when their preferences cannot be loaded
+func (r *Resolver) formatDueForUser(task models.Task) string {
+	prefs, err := r.DB.GetUserPreferences(task.UserID)
+	if err != nil {
+		prefs = models.DefaultPreferences()
+	}
+	return prefs.FormatDue(task)
+}
+
+// validatePreferences checks the values set in input that GraphQL doesn't
+// already restrict; the language is normalised to lower case
+func validatePreferences(input *models.UpdatePreferencesInput) error {
+	if input.Language != nil {
+		language := strings.ToLower(strings.TrimSpace(*input.Language))
+		if !isSupportedLanguage(language) {
+			return apperr.Invalid("language", "unsupported language %q; use one of %s",
+				*input.Language, strings.Join(models.SupportedLanguages, ", "))
+		}
+		input.Language = &language
+	}
+	if input.Timezone != nil {
+		if err := validateTimezone(*input.Timezone); err != nil {
+			return err
+		}
+	}
+	if input.SessionTimeoutMinutes != nil {
+		minutes := *input.SessionTimeoutMinutes
+		if minutes < models.MinSessionTimeoutMinutes || minutes > models.MaxSessionTimeoutMinutes {
+			return apperr.Invalid("sessionTimeoutMinutes", "session timeout must be between %d and %d minutes",
+				models.MinSessionTimeoutMinutes, models.MaxSessionTimeoutMinutes)
+		}
+	}
+	return nil
+}
+
+// validateTimezone accepts IANA timezone names such as Europe/Berlin or UTC
+func validateTimezone(name string) error {
+	if name == "" || name == "Local" {
+		return apperr.Invalid("timezone", "invalid timezone %q", name)
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return apperr.Invalid("timezone", "invalid timezone %q", name)
+	}
+	return nil
+}
+
+func isSupportedLanguage(language string) bool {
+	for _, supported := range models.SupportedLanguages {
+		if language == supported {
+			return true
+		}
+	}
+	return false
+}
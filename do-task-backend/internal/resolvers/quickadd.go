@@ -0,0 +1,104 @@
+package resolvers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/apperr"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/auth"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/quickadd"
+)
+
+// QuickAddTask creates a task for the authenticated user from a line of text
+func (r *mutationResolver) QuickAddTask(ctx context.Context, text string, timezone *string) (*models.QuickAddResult, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Dates are read in the given timezone, or else the user's own
+	var loc *time.Location
+	if timezone != nil && *timezone != "" {
+		if err := validateTimezone(*timezone); err != nil {
+			return nil, err
+		}
+		loc, _ = time.LoadLocation(*timezone)
+	} else {
+		prefs, err := r.DB.GetUserPreferences(userInfo.ID)
+		if err != nil {
+			return nil, err
+		}
+		loc = prefs.Location()
+	}
+
+	now := time.Now().In(loc)
+	parsed := quickadd.Parse(text, now, quickadd.Options{Location: loc})
+	if parsed.Title == "" {
+		return nil, apperr.Invalid("text", "quick-add text needs a title")
+	}
+
+	input := models.CreateTaskInput{
+		Title:    parsed.Title,
+		Status:   models.TaskStatusTodo,
+		Priority: models.TaskPriorityMedium,
+		DueDate:  parsed.DueDate(),
+		UserID:   userInfo.ID,
+		Tags:     parsed.Tags,
+	}
+	if parsed.Priority != "" {
+		input.Priority = parsed.Priority
+	}
+
+	if parsed.Category != "" {
+		categories, err := r.DB.GetAllCategories(userInfo.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find categories: %w", err)
+		}
+		id, ok := quickadd.MatchCategory(categories, parsed.Category)
+		if !ok {
+			return nil, apperr.Invalid("text", "no single category matches %q", parsed.Category)
+		}
+		input.CategoryID = id
+	} else if err := r.DB.ApplyDefaultCategory(&input); err != nil {
+		return nil, err
+	}
+
+	task, err := r.DB.CreateTask(input)
+	if err != nil {
+		return nil, err
+	}
+
+	r.notifyTaskSaved(&task)
+	return &models.QuickAddResult{Task: &task, Parse: quickAddParse(parsed)}, nil
+}
+
+// quickAddParse converts a parser result to its GraphQL shape
+func quickAddParse(parsed quickadd.Result) *models.QuickAddParse {
+	result := &models.QuickAddParse{
+		Title:  parsed.Title,
+		AllDay: parsed.AllDay,
+		Tags:   parsed.Tags,
+		Tokens: parsed.Tokens,
+	}
+	if result.Tags == nil {
+		result.Tags = []string{}
+	}
+	if result.Tokens == nil {
+		result.Tokens = []models.QuickAddToken{}
+	}
+	if parsed.Due != nil {
+		due := parsed.Due.Format(time.RFC3339)
+		result.DueDate = &due
+	}
+	if parsed.Priority != "" {
+		priority := parsed.Priority
+		result.Priority = &priority
+	}
+	if parsed.Category != "" {
+		category := parsed.Category
+		result.Category = &category
+	}
+	return result
+}
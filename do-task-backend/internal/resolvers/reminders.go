@@ -0,0 +1,101 @@
+package resolvers
+
+import (
+	"context"
+	"time"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/auth"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/graph/generated"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+)
+
+// Reminder returns the reminder resolver
+func (r *Resolver) Reminder() generated.ReminderResolver {
+	return &reminderResolver{r}
+}
+
+type reminderResolver struct{ *Resolver }
+
+// Reminders returns the authenticated user's reminders, optionally of one task
+func (r *queryResolver) Reminders(ctx context.Context, taskID *string) ([]*models.Reminder, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reminders, err := r.DB.GetReminders(userInfo.ID, taskID)
+	if err != nil {
+		return nil, err
+	}
+	return reminderPointers(reminders), nil
+}
+
+// CreateReminder adds a reminder to a task of the authenticated user
+func (r *mutationResolver) CreateReminder(ctx context.Context, input models.CreateReminderInput) (*models.Reminder, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reminder, err := r.DB.CreateReminder(input, userInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &reminder, nil
+}
+
+// DeleteReminder removes a reminder of the authenticated user
+func (r *mutationResolver) DeleteReminder(ctx context.Context, id string) (bool, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if err := r.DB.DeleteReminder(id, userInfo.ID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Reminders resolves the reminders field for Task
+func (r *taskResolver) Reminders(ctx context.Context, obj *models.Task) ([]*models.Reminder, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reminders, err := r.DB.GetReminders(userInfo.ID, &obj.ID)
+	if err != nil {
+		return nil, err
+	}
+	return reminderPointers(reminders), nil
+}
+
+// RemindAt resolves the remindAt field for Reminder
+func (r *reminderResolver) RemindAt(ctx context.Context, obj *models.Reminder) (*string, error) {
+	return formatOptionalTime(obj.RemindAt), nil
+}
+
+// FireAt resolves the fireAt field for Reminder
+func (r *reminderResolver) FireAt(ctx context.Context, obj *models.Reminder) (string, error) {
+	return obj.FireAt.Format(time.RFC3339), nil
+}
+
+// SentAt resolves the sentAt field for Reminder
+func (r *reminderResolver) SentAt(ctx context.Context, obj *models.Reminder) (*string, error) {
+	return formatOptionalTime(obj.SentAt), nil
+}
+
+// CreatedAt resolves the createdAt field for Reminder
+func (r *reminderResolver) CreatedAt(ctx context.Context, obj *models.Reminder) (string, error) {
+	return obj.CreatedAt.Format(time.RFC3339), nil
+}
+
+// reminderPointers converts reminders to the pointer slice gqlgen expects
+func reminderPointers(reminders []models.Reminder) []*models.Reminder {
+	result := make([]*models.Reminder, len(reminders))
+	for i := range reminders {
+		result[i] = &reminders[i]
+	}
+	return result
+}
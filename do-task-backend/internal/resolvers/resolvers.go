@@ -3,20 +3,27 @@ package resolvers
 
 import (
 	"context"
-	"errors"
 	"fmt"
+	"log"
+	"strings"
 	"time"
 
+	"github.com/Zayan-Mohamed/do-task-backend/internal/apperr"
 	"github.com/Zayan-Mohamed/do-task-backend/internal/auth"
 	"github.com/Zayan-Mohamed/do-task-backend/internal/database"
 	"github.com/Zayan-Mohamed/do-task-backend/internal/graph/generated"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/mailer"
 	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/webhooks"
 	"github.com/gin-gonic/gin"
 )
 
 // Resolver is the root resolver for the GraphQL schema
 type Resolver struct {
-	DB *database.DB
+	DB        *database.DB
+	Webhooks  *webhooks.Dispatcher // optional; sends test events immediately when set
+	Mailer    mailer.Mailer        // optional; verification links are only logged without it
+	Passwords *auth.PasswordPolicy // optional; the default policy applies without it
 }
 
 // Query returns the query resolver
@@ -44,14 +51,14 @@ func (r *Resolver) User() generated.UserResolver {
 	return &userResolver{r}
 }
 
-// Tasks returns all tasks for the authenticated user
-func (r *queryResolver) Tasks(ctx context.Context) ([]*models.Task, error) {
+// Tasks returns the authenticated user's tasks, optionally filtered
+func (r *queryResolver) Tasks(ctx context.Context, filter *models.TaskFilter, sort *models.TaskSort) ([]*models.Task, error) {
 	userInfo, err := auth.RequireAuthentication(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	tasks, err := r.DB.GetAllTasksByUser(userInfo.ID)
+	tasks, err := r.DB.GetTasks(userInfo.ID, filter, sort)
 	if err != nil {
 		return nil, err
 	}
@@ -121,29 +128,25 @@ func (r *mutationResolver) CreateTask(ctx context.Context, input models.CreateTa
 		return nil, err
 	}
 
+	var v apperr.Validation
+	checkTitle(&v, "title", input.Title)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
 	// Set the user ID from authentication context
 	input.UserID = userInfo.ID
 
-	// Validate that categoryId is not empty
-	if input.CategoryID == "" {
-		// Attempt to find a category for this user
-		categories, err := r.DB.GetAllCategories(userInfo.ID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to find categories: %w", err)
-		}
-
-		if len(categories) == 0 {
-			return nil, fmt.Errorf("cannot create task without a category. Please create a category first")
-		}
-
-		// Use the first available category
-		input.CategoryID = categories[0].ID
+	if err := r.DB.ApplyDefaultCategory(&input); err != nil {
+		return nil, err
 	}
 
 	task, err := r.DB.CreateTask(input)
 	if err != nil {
 		return nil, err
 	}
+
+	r.notifyTaskSaved(&task)
 	return &task, nil
 }
 
@@ -154,12 +157,37 @@ func (r *mutationResolver) UpdateTask(ctx context.Context, id string, input mode
 		return nil, err
 	}
 
+	if err := r.normalizeUpdateTaskInput(&input, userInfo.ID); err != nil {
+		return nil, err
+	}
+
+	task, err := r.DB.UpdateTask(id, input, userInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.DueDate != nil || input.Status != nil {
+		r.notifyTaskSaved(&task)
+	}
+	return &task, nil
+}
+
+// normalizeUpdateTaskInput applies the patch rules shared by every task update
+func (r *Resolver) normalizeUpdateTaskInput(input *models.UpdateTaskInput, userID string) error {
+	if input.Title != nil {
+		var v apperr.Validation
+		checkTitle(&v, "title", *input.Title)
+		if err := v.Err(); err != nil {
+			return err
+		}
+	}
+
 	// Check if categoryId is provided and empty
 	if input.CategoryID != nil && *input.CategoryID == "" {
 		// If an empty string is provided, find a valid category
-		categories, err := r.DB.GetAllCategories(userInfo.ID)
+		categories, err := r.DB.GetAllCategories(userID)
 		if err != nil {
-			return nil, fmt.Errorf("failed to find categories: %w", err)
+			return fmt.Errorf("failed to find categories: %w", err)
 		}
 
 		if len(categories) > 0 {
@@ -171,11 +199,7 @@ func (r *mutationResolver) UpdateTask(ctx context.Context, id string, input mode
 		}
 	}
 
-	task, err := r.DB.UpdateTask(id, input, userInfo.ID)
-	if err != nil {
-		return nil, err
-	}
-	return &task, nil
+	return nil
 }
 
 // DeleteTask deletes a task for the authenticated user
@@ -192,27 +216,35 @@ func (r *mutationResolver) DeleteTask(ctx context.Context, id string) (bool, err
 }
 
 // UpdateTaskStatus updates a task's status for the authenticated user
-func (r *mutationResolver) UpdateTaskStatus(ctx context.Context, id string, status models.TaskStatus) (*models.Task, error) {
+func (r *mutationResolver) UpdateTaskStatus(ctx context.Context, id string, status models.TaskStatus, expectedVersion *int) (*models.Task, error) {
 	userInfo, err := auth.RequireAuthentication(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	task, err := r.DB.UpdateTaskStatus(id, status, userInfo.ID)
+	task, err := r.DB.UpdateTaskStatus(id, status, expectedVersion, userInfo.ID)
 	if err != nil {
 		return nil, err
 	}
+
+	r.notifyTaskSaved(&task)
 	return &task, nil
 }
 
 // CreateCategory creates a new category for the authenticated user
-func (r *mutationResolver) CreateCategory(ctx context.Context, name string) (*models.Category, error) {
+func (r *mutationResolver) CreateCategory(ctx context.Context, name string, parentID *string) (*models.Category, error) {
 	userInfo, err := auth.RequireAuthentication(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	category, err := r.DB.CreateCategory(name, userInfo.ID)
+	var v apperr.Validation
+	checkName(&v, "name", name)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	category, err := r.DB.CreateCategory(name, parentID, userInfo.ID)
 	if err != nil {
 		return nil, err
 	}
@@ -220,34 +252,65 @@ func (r *mutationResolver) CreateCategory(ctx context.Context, name string) (*mo
 }
 
 // UpdateCategory updates an existing category for the authenticated user
-func (r *mutationResolver) UpdateCategory(ctx context.Context, id string, name string) (*models.Category, error) {
+func (r *mutationResolver) UpdateCategory(ctx context.Context, id string, name string, expectedVersion *int) (*models.Category, error) {
 	userInfo, err := auth.RequireAuthentication(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	category, err := r.DB.UpdateCategory(id, name, userInfo.ID)
+	var v apperr.Validation
+	checkName(&v, "name", name)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	category, err := r.DB.UpdateCategory(id, name, expectedVersion, userInfo.ID)
 	if err != nil {
 		return nil, err
 	}
 	return &category, nil
 }
 
-// DeleteCategory deletes a category for the authenticated user
-func (r *mutationResolver) DeleteCategory(ctx context.Context, id string) (bool, error) {
+// MoveCategory moves a category under a new parent for the authenticated user
+func (r *mutationResolver) MoveCategory(ctx context.Context, id string, parentID *string) (*models.Category, error) {
 	userInfo, err := auth.RequireAuthentication(ctx)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
-	if err := r.DB.DeleteCategory(id, userInfo.ID); err != nil {
-		return false, err
+	category, err := r.DB.MoveCategory(id, parentID, userInfo.ID)
+	if err != nil {
+		return nil, err
 	}
-	return true, nil
+	return &category, nil
 }
 
-// Category returns the category associated with a task
+// DeleteCategory deletes a category for the authenticated user using the chosen strategy
+func (r *mutationResolver) DeleteCategory(ctx context.Context, id string, strategy *models.CategoryDeleteStrategy, targetCategoryID *string) (*models.DeleteCategoryResult, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deleteStrategy := models.CategoryDeleteStrategyFail
+	if strategy != nil {
+		deleteStrategy = *strategy
+	}
+
+	result, err := r.DB.DeleteCategory(id, deleteStrategy, targetCategoryID, userInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Category returns the category associated with a task, or nil for a trashed
+// task whose category has since been deleted
 func (r *taskResolver) Category(ctx context.Context, obj *models.Task) (*models.Category, error) {
+	if obj.CategoryID == "" {
+		return nil, nil
+	}
+
 	userInfo, err := auth.RequireAuthentication(ctx)
 	if err != nil {
 		return nil, err
@@ -261,13 +324,13 @@ func (r *taskResolver) Category(ctx context.Context, obj *models.Task) (*models.
 }
 
 // Tasks returns all tasks in a category for the authenticated user
-func (r *categoryResolver) Tasks(ctx context.Context, obj *models.Category) ([]*models.Task, error) {
+func (r *categoryResolver) Tasks(ctx context.Context, obj *models.Category, includeDescendants *bool) ([]*models.Task, error) {
 	userInfo, err := auth.RequireAuthentication(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	tasks, err := r.DB.GetTasksInCategory(obj.ID, userInfo.ID)
+	tasks, err := r.DB.GetTasksInCategory(obj.ID, userInfo.ID, includeDescendants != nil && *includeDescendants)
 	if err != nil {
 		return nil, err
 	}
@@ -281,9 +344,103 @@ func (r *categoryResolver) Tasks(ctx context.Context, obj *models.Category) ([]*
 	return result, nil
 }
 
-// DueDate returns a string representation of the task's due date
-func (r *taskResolver) DueDate(ctx context.Context, obj *models.Task) (string, error) {
-	return obj.DueDate.Format(time.RFC3339), nil
+// Parent returns the parent of a category, or nil for a top-level category
+func (r *categoryResolver) Parent(ctx context.Context, obj *models.Category) (*models.Category, error) {
+	if obj.ParentID == nil {
+		return nil, nil
+	}
+
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	parent, err := r.DB.GetCategory(*obj.ParentID, userInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &parent, nil
+}
+
+// Children returns the direct subcategories of a category
+func (r *categoryResolver) Children(ctx context.Context, obj *models.Category) ([]*models.Category, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	children, err := r.DB.GetChildCategories(obj.ID, userInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert to pointer slice
+	result := make([]*models.Category, len(children))
+	for i := range children {
+		child := children[i]
+		result[i] = &child
+	}
+	return result, nil
+}
+
+// Path returns the category name prefixed by its ancestors, e.g. "Work / Clients"
+func (r *categoryResolver) Path(ctx context.Context, obj *models.Category) (string, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	path, err := r.DB.GetCategoryPath(obj.ID, userInfo.ID)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(path, models.CategoryPathSeparator), nil
+}
+
+// DueDate returns the task's due date in the owner's timezone, if it has one
+func (r *taskResolver) DueDate(ctx context.Context, obj *models.Task) (*string, error) {
+	if obj.DueDate == nil {
+		return nil, nil
+	}
+	dueDate := obj.DueDate.In(obj.Location()).Format(time.RFC3339)
+	return &dueDate, nil
+}
+
+// DueOn returns the day the task is due on in the owner's timezone, if it has a due date
+func (r *taskResolver) DueOn(ctx context.Context, obj *models.Task) (*string, error) {
+	if obj.DueDate == nil {
+		return nil, nil
+	}
+	dueOn := obj.DueDate.In(obj.Location()).Format("2006-01-02")
+	return &dueOn, nil
+}
+
+// StartDate returns the task's start date in the owner's timezone, if it has one
+func (r *taskResolver) StartDate(ctx context.Context, obj *models.Task) (*string, error) {
+	if obj.StartDate == nil {
+		return nil, nil
+	}
+	startDate := obj.StartDate.In(obj.Location()).Format(time.RFC3339)
+	return &startDate, nil
+}
+
+// StartOn returns the day the task starts on in the owner's timezone, if it has a start date
+func (r *taskResolver) StartOn(ctx context.Context, obj *models.Task) (*string, error) {
+	if obj.StartDate == nil {
+		return nil, nil
+	}
+	startOn := obj.StartDate.In(obj.Location()).Format("2006-01-02")
+	return &startOn, nil
+}
+
+// Overdue reports whether the task is open and past due
+func (r *taskResolver) Overdue(ctx context.Context, obj *models.Task) (bool, error) {
+	return obj.IsOverdue(time.Now()), nil
+}
+
+// DueToday reports whether the task is due on the owner's current day
+func (r *taskResolver) DueToday(ctx context.Context, obj *models.Task) (bool, error) {
+	return obj.IsDueToday(time.Now()), nil
 }
 
 // CreatedAt returns a string representation of the task's creation time
@@ -296,10 +453,34 @@ func (r *taskResolver) UpdatedAt(ctx context.Context, obj *models.Task) (string,
 	return obj.UpdatedAt.Format(time.RFC3339), nil
 }
 
+// DeletedAt returns when the task was moved to the trash, if it was
+func (r *taskResolver) DeletedAt(ctx context.Context, obj *models.Task) (*string, error) {
+	if obj.DeletedAt == nil {
+		return nil, nil
+	}
+	deletedAt := obj.DeletedAt.Format(time.RFC3339)
+	return &deletedAt, nil
+}
+
+// CompletedAt returns when the task was last completed, while it is completed
+func (r *taskResolver) CompletedAt(ctx context.Context, obj *models.Task) (*string, error) {
+	return formatOptionalTime(obj.CompletedAt), nil
+}
+
 // Authentication resolvers
 
 // Register creates a new user account
 func (r *mutationResolver) Register(ctx context.Context, input models.RegisterInput) (*models.AuthResponse, error) {
+	input.Email = database.NormalizeEmail(input.Email)
+
+	var v apperr.Validation
+	v.Check(strings.TrimSpace(input.Name) != "", "name", "name cannot be empty")
+	checkEmail(&v, "email", input.Email)
+	r.checkPassword(&v, "password", input.Password)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
 	// Hash the password
 	hashedPassword, err := auth.HashPassword(input.Password)
 	if err != nil {
@@ -312,6 +493,11 @@ func (r *mutationResolver) Register(ctx context.Context, input models.RegisterIn
 		return nil, err
 	}
 
+	// The account works right away; a failed mail can be resent later
+	if err := r.sendEmailVerification(ctx, user); err != nil {
+		log.Printf("Failed to send verification email to user %s: %v", user.ID, err)
+	}
+
 	// Generate JWT token
 	token, err := auth.GenerateToken(&user)
 	if err != nil {
@@ -334,12 +520,15 @@ func (r *mutationResolver) Login(ctx context.Context, input models.LoginInput) (
 	// Get user by email
 	user, err := r.DB.GetUserByEmail(input.Email)
 	if err != nil {
-		return nil, fmt.Errorf("invalid email or password")
+		if apperr.CodeOf(err) != apperr.CodeNotFound {
+			return nil, err
+		}
+		return nil, apperr.Unauthenticated("invalid email or password")
 	}
 
 	// Check password
 	if !auth.CheckPassword(input.Password, user.Password) {
-		return nil, fmt.Errorf("invalid email or password")
+		return nil, apperr.Unauthenticated("invalid email or password")
 	}
 
 	// Generate JWT token
@@ -384,11 +573,37 @@ func (r *mutationResolver) UpdateProfile(ctx context.Context, input models.Updat
 		return nil, err
 	}
 
+	var v apperr.Validation
+	if input.Name != nil {
+		v.Check(strings.TrimSpace(*input.Name) != "", "name", "name cannot be empty")
+	}
+	if input.Email != nil {
+		email := database.NormalizeEmail(*input.Email)
+		input.Email = &email
+		checkEmail(&v, "email", email)
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	if input.Timezone != nil {
+		if err := validateTimezone(*input.Timezone); err != nil {
+			return nil, err
+		}
+	}
+
 	user, err := r.DB.UpdateUserProfile(userInfo.ID, input)
 	if err != nil {
 		return nil, err
 	}
 
+	// A new email only replaces the current one once its link is followed
+	if input.Email != nil && user.PendingEmail != nil {
+		if err := r.sendEmailVerification(ctx, user); err != nil {
+			log.Printf("Failed to send verification email to user %s: %v", user.ID, err)
+		}
+	}
+
 	return &user, nil
 }
 
@@ -407,7 +622,13 @@ func (r *mutationResolver) ChangePassword(ctx context.Context, input models.Chan
 
 	// Verify current password
 	if !auth.CheckPassword(input.CurrentPassword, user.Password) {
-		return false, errors.New("current password is incorrect")
+		return false, apperr.Invalid("currentPassword", "current password is incorrect")
+	}
+
+	var v apperr.Validation
+	r.checkPassword(&v, "newPassword", input.NewPassword)
+	if err := v.Err(); err != nil {
+		return false, err
 	}
 
 	// Hash new password
@@ -0,0 +1,41 @@
+package resolvers
+
+import (
+	"context"
+	"time"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/auth"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+)
+
+// TaskStats aggregates the authenticated user's tasks over the range ending today
+func (r *queryResolver) TaskStats(ctx context.Context, statsRange *models.StatsRange, groupBy *models.StatsGroupBy) (*models.TaskStats, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	prefs, err := r.DB.GetUserPreferences(userInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	days := models.StatsRangeMonth.Days()
+	if statsRange != nil {
+		days = statsRange.Days()
+	}
+	grouping := models.StatsGroupByDay
+	if groupBy != nil {
+		grouping = *groupBy
+	}
+
+	now := time.Now().In(prefs.Location())
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	from := to.AddDate(0, 0, 1-days)
+
+	stats, err := r.DB.GetTaskStats(userInfo.ID, from, to, grouping, prefs)
+	if err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
@@ -0,0 +1,135 @@
+package resolvers
+
+import (
+	"context"
+	"time"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/auth"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/graph/generated"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+)
+
+// Tag returns the tag resolver
+func (r *Resolver) Tag() generated.TagResolver {
+	return &tagResolver{r}
+}
+
+type tagResolver struct{ *Resolver }
+
+// Tags returns all tags for the authenticated user
+func (r *queryResolver) Tags(ctx context.Context) ([]*models.Tag, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := r.DB.GetAllTags(userInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return tagPointers(tags), nil
+}
+
+// CreateTag creates a new tag for the authenticated user
+func (r *mutationResolver) CreateTag(ctx context.Context, input models.CreateTagInput) (*models.Tag, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tag, err := r.DB.CreateTag(input, userInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// UpdateTag updates a tag's colour and description for the authenticated user
+func (r *mutationResolver) UpdateTag(ctx context.Context, id string, input models.UpdateTagInput) (*models.Tag, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tag, err := r.DB.UpdateTag(id, input, userInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// RenameTag renames a tag across all of the authenticated user's tasks
+func (r *mutationResolver) RenameTag(ctx context.Context, id string, name string) (*models.Tag, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tag, err := r.DB.RenameTag(id, name, userInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// MergeTags folds the source tags into the target tag for the authenticated user
+func (r *mutationResolver) MergeTags(ctx context.Context, sourceIds []string, targetID string) (*models.Tag, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tag, err := r.DB.MergeTags(sourceIds, targetID, userInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// DeleteTag deletes a tag and removes it from the authenticated user's tasks
+func (r *mutationResolver) DeleteTag(ctx context.Context, id string) (bool, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if err := r.DB.DeleteTag(id, userInfo.ID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// TagDetails returns the full tag objects attached to a task
+func (r *taskResolver) TagDetails(ctx context.Context, obj *models.Task) ([]*models.Tag, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := r.DB.GetTagsForTask(obj.ID, userInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return tagPointers(tags), nil
+}
+
+// CreatedAt resolves the createdAt field for Tag
+func (r *tagResolver) CreatedAt(ctx context.Context, obj *models.Tag) (string, error) {
+	return obj.CreatedAt.Format(time.RFC3339), nil
+}
+
+// UpdatedAt resolves the updatedAt field for Tag
+func (r *tagResolver) UpdatedAt(ctx context.Context, obj *models.Tag) (string, error) {
+	return obj.UpdatedAt.Format(time.RFC3339), nil
+}
+
+func tagPointers(tags []models.Tag) []*models.Tag {
+	result := make([]*models.Tag, len(tags))
+	for i := range tags {
+		tag := tags[i]
+		result[i] = &tag
+	}
+	return result
+}
@@ -0,0 +1,163 @@
+package resolvers
+
+import (
+	"context"
+	"time"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/apperr"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/auth"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/database"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/graph/generated"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+)
+
+// TaskTemplate returns the task template resolver
+func (r *Resolver) TaskTemplate() generated.TaskTemplateResolver {
+	return &taskTemplateResolver{r}
+}
+
+type taskTemplateResolver struct{ *Resolver }
+
+// TaskTemplates returns the templates the authenticated user owns or that were shared with them
+func (r *queryResolver) TaskTemplates(ctx context.Context) ([]*models.TaskTemplate, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	templates, err := r.DB.GetTaskTemplates(userInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert to pointer slice
+	result := make([]*models.TaskTemplate, len(templates))
+	for i := range templates {
+		result[i] = &templates[i]
+	}
+	return result, nil
+}
+
+// CreateTaskTemplate saves a template for the authenticated user
+func (r *mutationResolver) CreateTaskTemplate(ctx context.Context, input models.TaskTemplateInput) (*models.TaskTemplate, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	template, err := r.DB.CreateTaskTemplate(input, userInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// UpdateTaskTemplate changes one of the authenticated user's templates
+func (r *mutationResolver) UpdateTaskTemplate(ctx context.Context, id string, input models.UpdateTaskTemplateInput) (*models.TaskTemplate, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	template, err := r.DB.UpdateTaskTemplate(id, input, userInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// DeleteTaskTemplate removes one of the authenticated user's templates
+func (r *mutationResolver) DeleteTaskTemplate(ctx context.Context, id string) (bool, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if err := r.DB.DeleteTaskTemplate(id, userInfo.ID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ShareTaskTemplate shares one of the authenticated user's templates with another user
+func (r *mutationResolver) ShareTaskTemplate(ctx context.Context, id string, email string) (*models.TaskTemplate, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var v apperr.Validation
+	checkEmail(&v, "email", database.NormalizeEmail(email))
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := r.DB.ShareTaskTemplate(id, email, userInfo.ID); err != nil {
+		return nil, err
+	}
+
+	template, err := r.DB.GetTaskTemplate(id, userInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// UnshareTaskTemplate stops sharing one of the authenticated user's templates with another user
+func (r *mutationResolver) UnshareTaskTemplate(ctx context.Context, id string, email string) (*models.TaskTemplate, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.DB.UnshareTaskTemplate(id, email, userInfo.ID); err != nil {
+		return nil, err
+	}
+
+	template, err := r.DB.GetTaskTemplate(id, userInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// CreateTaskFromTemplate creates the tasks of a template for the authenticated user
+func (r *mutationResolver) CreateTaskFromTemplate(ctx context.Context, templateID string, overrides *models.TemplateOverrides) ([]*models.Task, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks, err := r.DB.CreateTasksFromTemplate(templateID, overrides, userInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert to pointer slice
+	result := make([]*models.Task, len(tasks))
+	for i := range tasks {
+		result[i] = &tasks[i]
+		r.notifyTaskSaved(result[i])
+	}
+	return result, nil
+}
+
+// SharedWith resolves the sharedWith field for TaskTemplate
+func (r *taskTemplateResolver) SharedWith(ctx context.Context, obj *models.TaskTemplate) ([]string, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.DB.GetTaskTemplateShares(obj.ID, userInfo.ID)
+}
+
+// CreatedAt resolves the createdAt field for TaskTemplate
+func (r *taskTemplateResolver) CreatedAt(ctx context.Context, obj *models.TaskTemplate) (string, error) {
+	return obj.CreatedAt.Format(time.RFC3339), nil
+}
+
+// UpdatedAt resolves the updatedAt field for TaskTemplate
+func (r *taskTemplateResolver) UpdatedAt(ctx context.Context, obj *models.TaskTemplate) (string, error) {
+	return obj.UpdatedAt.Format(time.RFC3339), nil
+}
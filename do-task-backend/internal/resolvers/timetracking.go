@@ -0,0 +1,174 @@
+package resolvers
+
+import (
+	"context"
+	"time"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/auth"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/graph/generated"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+)
+
+// TimeEntry returns the time entry resolver
+func (r *Resolver) TimeEntry() generated.TimeEntryResolver {
+	return &timeEntryResolver{r}
+}
+
+type timeEntryResolver struct{ *Resolver }
+
+// RunningTimer returns the authenticated user's running timer, if any
+func (r *queryResolver) RunningTimer(ctx context.Context) (*models.TimeEntry, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.DB.GetRunningTimer(userInfo.ID)
+}
+
+// TimeReport adds up the authenticated user's time between two days
+func (r *queryResolver) TimeReport(ctx context.Context, from string, to string, groupBy models.TimeReportGroupBy) (*models.TimeReport, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := r.DB.GetTimeReport(userInfo.ID, from, to, groupBy)
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// StartTimer starts timing one of the authenticated user's tasks
+func (r *mutationResolver) StartTimer(ctx context.Context, taskID string, note *string) (*models.TimeEntry, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	text := ""
+	if note != nil {
+		text = *note
+	}
+	entry, err := r.DB.StartTimer(taskID, text, userInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// StopTimer stops the authenticated user's running timer
+func (r *mutationResolver) StopTimer(ctx context.Context) (*models.TimeEntry, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := r.DB.StopTimer(userInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// CreateTimeEntry records time spent on one of the authenticated user's tasks
+func (r *mutationResolver) CreateTimeEntry(ctx context.Context, input models.CreateTimeEntryInput) (*models.TimeEntry, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := r.DB.CreateTimeEntry(input, userInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// UpdateTimeEntry changes one of the authenticated user's time entries
+func (r *mutationResolver) UpdateTimeEntry(ctx context.Context, id string, input models.UpdateTimeEntryInput) (*models.TimeEntry, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := r.DB.UpdateTimeEntry(id, input, userInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// DeleteTimeEntry removes one of the authenticated user's time entries
+func (r *mutationResolver) DeleteTimeEntry(ctx context.Context, id string) (bool, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if err := r.DB.DeleteTimeEntry(id, userInfo.ID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// TimeSpent resolves the timeSpent field for Task
+func (r *taskResolver) TimeSpent(ctx context.Context, obj *models.Task) (int, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return r.DB.GetTimeSpent(obj.ID, userInfo.ID)
+}
+
+// TimeEntries resolves the timeEntries field for Task
+func (r *taskResolver) TimeEntries(ctx context.Context, obj *models.Task) ([]*models.TimeEntry, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := r.DB.GetTimeEntries(obj.ID, userInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert to pointer slice
+	result := make([]*models.TimeEntry, len(entries))
+	for i := range entries {
+		result[i] = &entries[i]
+	}
+	return result, nil
+}
+
+// Task resolves the task field for TimeEntry; it is null while the task is in the trash
+func (r *timeEntryResolver) Task(ctx context.Context, obj *models.TimeEntry) (*models.Task, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.DB.LookupTask(obj.TaskID, userInfo.ID)
+}
+
+// StartedAt resolves the startedAt field for TimeEntry
+func (r *timeEntryResolver) StartedAt(ctx context.Context, obj *models.TimeEntry) (string, error) {
+	return obj.StartedAt.Format(time.RFC3339), nil
+}
+
+// EndedAt resolves the endedAt field for TimeEntry
+func (r *timeEntryResolver) EndedAt(ctx context.Context, obj *models.TimeEntry) (*string, error) {
+	return formatOptionalTime(obj.EndedAt), nil
+}
+
+// Seconds resolves the seconds field for TimeEntry
+func (r *timeEntryResolver) Seconds(ctx context.Context, obj *models.TimeEntry) (int, error) {
+	return int(obj.Duration(time.Now()).Seconds()), nil
+}
+
+// CreatedAt resolves the createdAt field for TimeEntry
+func (r *timeEntryResolver) CreatedAt(ctx context.Context, obj *models.TimeEntry) (string, error) {
+	return obj.CreatedAt.Format(time.RFC3339), nil
+}
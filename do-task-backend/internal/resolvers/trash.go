@@ -0,0 +1,55 @@
+package resolvers
+
+import (
+	"context"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/auth"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+)
+
+// TrashedTasks returns the authenticated user's trashed tasks
+func (r *queryResolver) TrashedTasks(ctx context.Context) ([]*models.Task, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks, err := r.DB.GetTrashedTasks(userInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert to pointer slice
+	result := make([]*models.Task, len(tasks))
+	for i := range tasks {
+		task := tasks[i]
+		result[i] = &task
+	}
+	return result, nil
+}
+
+// RestoreTask moves one of the authenticated user's trashed tasks back into the task list
+func (r *mutationResolver) RestoreTask(ctx context.Context, id string, categoryID *string) (*models.Task, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	task, err := r.DB.RestoreTask(id, categoryID, userInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	r.notifyTaskSaved(&task)
+	return &task, nil
+}
+
+// EmptyTrash permanently deletes the authenticated user's trashed tasks
+func (r *mutationResolver) EmptyTrash(ctx context.Context) (int, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return r.DB.EmptyTrash(userInfo.ID)
+}
@@ -0,0 +1,47 @@
+package resolvers
+
+import (
+	"net/mail"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/apperr"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/auth"
+)
+
+// maxTitleLength mirrors the size of the tasks.title column
+const maxTitleLength = 500
+
+// maxNameLength mirrors the size of the categories.name column
+const maxNameLength = 255
+
+// checkTitle records a task title that is empty or too long
+func checkTitle(v *apperr.Validation, field string, title string) {
+	title = strings.TrimSpace(title)
+	v.Check(title != "", field, "title cannot be empty")
+	v.Check(utf8.RuneCountInString(title) <= maxTitleLength, field, "title cannot be longer than %d characters", maxTitleLength)
+}
+
+// checkName records a category name that is empty or too long
+func checkName(v *apperr.Validation, field string, name string) {
+	name = strings.TrimSpace(name)
+	v.Check(name != "", field, "name cannot be empty")
+	v.Check(utf8.RuneCountInString(name) <= maxNameLength, field, "name cannot be longer than %d characters", maxNameLength)
+}
+
+// checkEmail records an email that is not a bare address like name@example.com
+func checkEmail(v *apperr.Validation, field string, email string) {
+	address, err := mail.ParseAddress(email)
+	v.Check(err == nil && address.Address == email && address.Name == "", field, "invalid email address")
+}
+
+// checkPassword records a password the password policy does not accept
+func (r *Resolver) checkPassword(v *apperr.Validation, field string, password string) {
+	policy := r.Passwords
+	if policy == nil {
+		policy = auth.DefaultPasswordPolicy()
+	}
+	if err := policy.Check(password); err != nil {
+		v.Add(field, "%s", err)
+	}
+}
@@ -0,0 +1,90 @@
+package resolvers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/apperr"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/auth"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/mailer"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+)
+
+// emailVerificationTTL is how long a verification link works
+const emailVerificationTTL = 24 * time.Hour
+
+// VerifyEmail follows a verification link. It needs no session since the link
+// is often opened in another browser.
+func (r *mutationResolver) VerifyEmail(ctx context.Context, token string) (*models.User, error) {
+	user, err := r.DB.VerifyEmail(auth.HashSecretToken(token))
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// ResendVerificationEmail mails a new link for the pending email, or for the
+// current email while it is unverified
+func (r *mutationResolver) ResendVerificationEmail(ctx context.Context) (bool, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	user, err := r.DB.GetUserByID(userInfo.ID)
+	if err != nil {
+		return false, err
+	}
+	if user.PendingEmail == nil && user.EmailVerified {
+		return false, apperr.Invalid("", "email address is already verified")
+	}
+
+	if err := r.sendEmailVerification(ctx, user); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// sendEmailVerification mails user a link that verifies the pending email, or
+// the current one when nothing is pending
+func (r *Resolver) sendEmailVerification(ctx context.Context, user models.User) error {
+	email := user.Email
+	if user.PendingEmail != nil {
+		email = *user.PendingEmail
+	}
+
+	token, err := auth.GenerateSecretToken()
+	if err != nil {
+		return err
+	}
+	err = r.DB.CreateEmailVerification(user.ID, email, auth.HashSecretToken(token), time.Now().Add(emailVerificationTTL))
+	if err != nil {
+		return err
+	}
+
+	outbox := r.Mailer
+	if outbox == nil {
+		outbox = mailer.LogMailer{}
+	}
+	link := appURL("/verify-email?token=" + token)
+	return outbox.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: "Verify your email address",
+		Text: fmt.Sprintf("Hi %s,\n\nFollow this link within %d hours to verify %s for your DoTask account:\n\n%s\n\n"+
+			"If you did not ask for this, you can ignore this email.\n",
+			user.Name, int(emailVerificationTTL.Hours()), email, link),
+	})
+}
+
+// appURL turns a frontend path into an absolute URL under APP_URL, which
+// defaults to the development server
+func appURL(path string) string {
+	base := os.Getenv("APP_URL")
+	if base == "" {
+		base = "http://localhost:5173"
+	}
+	return strings.TrimSuffix(base, "/") + path
+}
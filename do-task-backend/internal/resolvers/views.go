@@ -0,0 +1,108 @@
+package resolvers
+
+import (
+	"context"
+	"time"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/auth"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/graph/generated"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+)
+
+// SavedView returns the saved view resolver
+func (r *Resolver) SavedView() generated.SavedViewResolver {
+	return &savedViewResolver{r}
+}
+
+type savedViewResolver struct{ *Resolver }
+
+// SavedViews returns the authenticated user's saved views
+func (r *queryResolver) SavedViews(ctx context.Context) ([]*models.SavedView, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	views, err := r.DB.GetSavedViews(userInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert to pointer slice
+	result := make([]*models.SavedView, len(views))
+	for i := range views {
+		result[i] = &views[i]
+	}
+	return result, nil
+}
+
+// ViewTasks returns the tasks matching one of the authenticated user's saved views
+func (r *queryResolver) ViewTasks(ctx context.Context, viewID string) ([]*models.Task, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks, err := r.DB.GetViewTasks(viewID, userInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert to pointer slice
+	result := make([]*models.Task, len(tasks))
+	for i := range tasks {
+		result[i] = &tasks[i]
+	}
+	return result, nil
+}
+
+// CreateSavedView saves a view for the authenticated user
+func (r *mutationResolver) CreateSavedView(ctx context.Context, input models.CreateSavedViewInput) (*models.SavedView, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	view, err := r.DB.CreateSavedView(input, userInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &view, nil
+}
+
+// UpdateSavedView changes one of the authenticated user's saved views
+func (r *mutationResolver) UpdateSavedView(ctx context.Context, id string, input models.UpdateSavedViewInput) (*models.SavedView, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	view, err := r.DB.UpdateSavedView(id, input, userInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &view, nil
+}
+
+// DeleteSavedView removes one of the authenticated user's saved views
+func (r *mutationResolver) DeleteSavedView(ctx context.Context, id string) (bool, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if err := r.DB.DeleteSavedView(id, userInfo.ID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// CreatedAt resolves the createdAt field for SavedView
+func (r *savedViewResolver) CreatedAt(ctx context.Context, obj *models.SavedView) (string, error) {
+	return obj.CreatedAt.Format(time.RFC3339), nil
+}
+
+// UpdatedAt resolves the updatedAt field for SavedView
+func (r *savedViewResolver) UpdatedAt(ctx context.Context, obj *models.SavedView) (string, error) {
+	return obj.UpdatedAt.Format(time.RFC3339), nil
+}
@@ -0,0 +1,225 @@
+package resolvers
+
+import (
+	"context"
+	"time"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/apperr"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/auth"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/graph/generated"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/webhooks"
+)
+
+// maxWebhookDeliveries caps the size of the delivery log returned at once
+const maxWebhookDeliveries = 200
+
+// Webhook returns the webhook resolver
+func (r *Resolver) Webhook() generated.WebhookResolver {
+	return &webhookResolver{r}
+}
+
+// WebhookDelivery returns the webhook delivery resolver
+func (r *Resolver) WebhookDelivery() generated.WebhookDeliveryResolver {
+	return &webhookDeliveryResolver{r}
+}
+
+type webhookResolver struct{ *Resolver }
+type webhookDeliveryResolver struct{ *Resolver }
+
+// Webhooks returns the authenticated user's webhooks
+func (r *queryResolver) Webhooks(ctx context.Context) ([]*models.Webhook, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	hooks, err := r.DB.GetWebhooks(userInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert to pointer slice
+	result := make([]*models.Webhook, len(hooks))
+	for i := range hooks {
+		hook := hooks[i]
+		result[i] = &hook
+	}
+	return result, nil
+}
+
+// WebhookDeliveries returns the most recent deliveries of one of the authenticated user's webhooks
+func (r *queryResolver) WebhookDeliveries(ctx context.Context, webhookID string, limit *int) ([]*models.WebhookDelivery, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	n := 50
+	if limit != nil {
+		n = *limit
+	}
+	if n < 1 || n > maxWebhookDeliveries {
+		return nil, apperr.Invalid("limit", "limit must be between 1 and 200")
+	}
+
+	deliveries, err := r.DB.GetWebhookDeliveries(webhookID, n, userInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert to pointer slice
+	result := make([]*models.WebhookDelivery, len(deliveries))
+	for i := range deliveries {
+		delivery := deliveries[i]
+		result[i] = &delivery
+	}
+	return result, nil
+}
+
+// CreateWebhook subscribes a URL to events of the authenticated user
+func (r *mutationResolver) CreateWebhook(ctx context.Context, input models.CreateWebhookInput) (*models.Webhook, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	url, err := webhooks.ValidateURL(input.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := webhookSecret(input.Secret)
+	if err != nil {
+		return nil, err
+	}
+
+	active := true
+	if input.Active != nil {
+		active = *input.Active
+	}
+
+	hook, err := r.DB.CreateWebhook(url, secret, input.EventTypes, active, userInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &hook, nil
+}
+
+// UpdateWebhook updates one of the authenticated user's webhooks
+func (r *mutationResolver) UpdateWebhook(ctx context.Context, id string, input models.UpdateWebhookInput) (*models.Webhook, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.URL != nil {
+		url, err := webhooks.ValidateURL(*input.URL)
+		if err != nil {
+			return nil, err
+		}
+		input.URL = &url
+	}
+	if input.Secret != nil {
+		secret, err := webhookSecret(input.Secret)
+		if err != nil {
+			return nil, err
+		}
+		input.Secret = &secret
+	}
+
+	hook, err := r.DB.UpdateWebhook(id, input, userInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &hook, nil
+}
+
+// DeleteWebhook deletes one of the authenticated user's webhooks
+func (r *mutationResolver) DeleteWebhook(ctx context.Context, id string) (bool, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if err := r.DB.DeleteWebhook(id, userInfo.ID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// SendTestWebhook queues a webhook.test event for a webhook and, when the
+// dispatcher is available, sends it straight away
+func (r *mutationResolver) SendTestWebhook(ctx context.Context, id string) (*models.WebhookDelivery, error) {
+	userInfo, err := auth.RequireAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery, err := r.DB.CreateTestWebhookDelivery(id, userInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Webhooks != nil {
+		delivery, err = r.Webhooks.DeliverNow(delivery.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &delivery, nil
+}
+
+// webhookSecret validates a user supplied secret, generating one when it is empty
+func webhookSecret(secret *string) (string, error) {
+	if secret == nil || *secret == "" {
+		return auth.GenerateSecretToken()
+	}
+	if len(*secret) < 16 || len(*secret) > 255 {
+		return "", apperr.Invalid("secret", "webhook secret must be between 16 and 255 characters")
+	}
+	return *secret, nil
+}
+
+// CreatedAt resolves the createdAt field for Webhook
+func (r *webhookResolver) CreatedAt(ctx context.Context, obj *models.Webhook) (string, error) {
+	return obj.CreatedAt.Format(time.RFC3339), nil
+}
+
+// UpdatedAt resolves the updatedAt field for Webhook
+func (r *webhookResolver) UpdatedAt(ctx context.Context, obj *models.Webhook) (string, error) {
+	return obj.UpdatedAt.Format(time.RFC3339), nil
+}
+
+// NextAttemptAt resolves the nextAttemptAt field for WebhookDelivery; it is
+// only meaningful while the delivery is pending
+func (r *webhookDeliveryResolver) NextAttemptAt(ctx context.Context, obj *models.WebhookDelivery) (*string, error) {
+	if obj.Status != models.WebhookDeliveryStatusPending {
+		return nil, nil
+	}
+	return formatOptionalTime(&obj.NextAttemptAt), nil
+}
+
+// LastAttemptAt resolves the lastAttemptAt field for WebhookDelivery
+func (r *webhookDeliveryResolver) LastAttemptAt(ctx context.Context, obj *models.WebhookDelivery) (*string, error) {
+	return formatOptionalTime(obj.LastAttemptAt), nil
+}
+
+// DeliveredAt resolves the deliveredAt field for WebhookDelivery
+func (r *webhookDeliveryResolver) DeliveredAt(ctx context.Context, obj *models.WebhookDelivery) (*string, error) {
+	return formatOptionalTime(obj.DeliveredAt), nil
+}
+
+// CreatedAt resolves the createdAt field for WebhookDelivery
+func (r *webhookDeliveryResolver) CreatedAt(ctx context.Context, obj *models.WebhookDelivery) (string, error) {
+	return obj.CreatedAt.Format(time.RFC3339), nil
+}
+
+// formatOptionalTime formats t as RFC 3339, or returns nil for a nil time
+func formatOptionalTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	formatted := t.Format(time.RFC3339)
+	return &formatted
+}
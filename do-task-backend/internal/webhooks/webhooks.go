@@ -0,0 +1,242 @@
+// Package webhooks delivers queued task and category events to the URLs users
+// subscribed, signing each request and retrying failures with backoff
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Zayan-Mohamed/do-task-backend/internal/apperr"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/database"
+	"github.com/Zayan-Mohamed/do-task-backend/internal/models"
+)
+
+const (
+	// MaxAttempts is how often a delivery is tried before it is marked FAILED
+	MaxAttempts = 8
+	// SignatureHeader carries "sha256=<hex HMAC of timestamp.body>"
+	SignatureHeader = "X-DoTask-Signature"
+	// TimestampHeader carries the Unix time the request was signed at
+	TimestampHeader = "X-DoTask-Timestamp"
+
+	// baseBackoff is the delay before the first retry; it doubles every attempt
+	baseBackoff = 30 * time.Second
+	maxBackoff  = 6 * time.Hour
+	// lease keeps a claimed delivery away from other dispatchers while it is sent
+	lease          = 2 * time.Minute
+	requestTimeout = 10 * time.Second
+	// retention is how long finished deliveries stay in the delivery log
+	retention = 30 * 24 * time.Hour
+)
+
+// Dispatcher sends due webhook deliveries
+type Dispatcher struct {
+	DB           *database.DB
+	Client       *http.Client
+	PollInterval time.Duration
+	BatchSize    int
+}
+
+// NewDispatcher returns a dispatcher with production defaults. Requests to
+// loopback and private networks are refused unless
+// WEBHOOK_ALLOW_PRIVATE_NETWORKS=true, which is handy when testing against a
+// local HTTP server.
+func NewDispatcher(db *database.DB) *Dispatcher {
+	allowPrivate, _ := strconv.ParseBool(os.Getenv("WEBHOOK_ALLOW_PRIVATE_NETWORKS"))
+	return &Dispatcher{
+		DB:           db,
+		Client:       NewClient(allowPrivate),
+		PollInterval: 5 * time.Second,
+		BatchSize:    20,
+	}
+}
+
+// NewClient returns the HTTP client used for deliveries. Redirects are not
+// followed and, unless allowPrivate is set, connections to non-public
+// addresses are refused at dial time so DNS tricks cannot reach them.
+func NewClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+				return fmt.Errorf("webhook target %s is not a public address", host)
+			}
+			return nil
+		}
+	}
+
+	return &http.Client{
+		Timeout: requestTimeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 5 * time.Second,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func isPublicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsUnspecified() &&
+		!ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() && !ip.IsMulticast()
+}
+
+// ValidateURL checks that a webhook URL is an absolute http(s) URL
+func ValidateURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if len(raw) > 2048 {
+		return "", apperr.Invalid("url", "webhook URL cannot be longer than 2048 characters")
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", apperr.Invalid("url", "webhook URL must be an absolute http or https URL")
+	}
+	return u.String(), nil
+}
+
+// Run delivers due events until ctx is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.PollInterval)
+	defer ticker.Stop()
+
+	lastPrune := time.Time{}
+	for {
+		if _, err := d.ProcessDue(); err != nil {
+			log.Printf("webhooks: %v", err)
+		}
+
+		if time.Since(lastPrune) > time.Hour {
+			if _, err := d.DB.PruneWebhookDeliveries(time.Now().Add(-retention)); err != nil {
+				log.Printf("webhooks: %v", err)
+			}
+			lastPrune = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessDue sends one batch of due deliveries and returns how many were attempted
+func (d *Dispatcher) ProcessDue() (int, error) {
+	dispatches, err := d.DB.ClaimWebhookDeliveries(d.BatchSize, lease, "")
+	if err != nil {
+		return 0, err
+	}
+
+	for _, dispatch := range dispatches {
+		if err := d.attempt(dispatch); err != nil {
+			return 0, err
+		}
+	}
+	return len(dispatches), nil
+}
+
+// DeliverNow sends a pending delivery immediately, regardless of its schedule,
+// and returns its updated state
+func (d *Dispatcher) DeliverNow(deliveryID string) (models.WebhookDelivery, error) {
+	dispatches, err := d.DB.ClaimWebhookDeliveries(1, lease, deliveryID)
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	for _, dispatch := range dispatches {
+		if err := d.attempt(dispatch); err != nil {
+			return models.WebhookDelivery{}, err
+		}
+	}
+	return d.DB.GetWebhookDelivery(deliveryID)
+}
+
+// attempt sends a claimed delivery and records the outcome
+func (d *Dispatcher) attempt(dispatch models.WebhookDispatch) error {
+	status, sendErr := d.send(dispatch)
+
+	var retryAt *time.Time
+	if sendErr != nil && dispatch.Attempt < MaxAttempts {
+		next := time.Now().Add(Backoff(dispatch.Attempt))
+		retryAt = &next
+	}
+
+	return d.DB.RecordWebhookAttempt(dispatch.DeliveryID, status, sendErr, retryAt)
+}
+
+// send posts a delivery and returns the response status. Any non-2xx
+// response is an error.
+func (d *Dispatcher) send(dispatch models.WebhookDispatch) (int, error) {
+	body := []byte(dispatch.Payload)
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequest(http.MethodPost, dispatch.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "DoTask-Webhooks/1.0")
+	req.Header.Set("X-DoTask-Event", dispatch.EventType)
+	req.Header.Set("X-DoTask-Delivery", dispatch.DeliveryID)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(dispatch.Secret, timestamp, body))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded with %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign returns the signature header value for a request body. Receivers
+// recompute HMAC-SHA256 over "<timestamp>.<body>" with their secret and
+// compare it in constant time.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature matches body, for receivers written in Go
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// Backoff returns the delay before retrying after the given attempt
+func Backoff(attempt int) time.Duration {
+	delay := baseBackoff
+	for i := 1; i < attempt && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay
+}
@@ -0,0 +1,9 @@
+ALTER TABLE tasks ADD COLUMN tags TEXT[] DEFAULT '{}';
+
+UPDATE tasks t SET tags = ARRAY(
+    SELECT tg.name FROM task_tags tt JOIN tags tg ON tg.id = tt.tag_id
+    WHERE tt.task_id = t.id ORDER BY tt.position, tg.name
+);
+
+DROP TABLE IF EXISTS task_tags;
+DROP TABLE IF EXISTS tags;
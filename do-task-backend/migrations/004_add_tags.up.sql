@@ -0,0 +1,40 @@
+-- Promote tags from a TEXT[] column on tasks to a per-user tags table
+
+CREATE TABLE tags (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    color VARCHAR(7),
+    description TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Tag names are unique per user regardless of case
+CREATE UNIQUE INDEX idx_tags_user_lower_name ON tags(user_id, (lower(name)));
+
+CREATE TABLE task_tags (
+    task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    tag_id UUID NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    position SMALLINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (task_id, tag_id)
+);
+
+CREATE INDEX idx_task_tags_tag_id ON task_tags(tag_id);
+
+-- Backfill tags from the existing arrays, keeping the first spelling seen.
+-- Legacy tags longer than a tag name can be are cut to fit.
+INSERT INTO tags (user_id, name)
+SELECT DISTINCT ON (t.user_id, lower(left(btrim(tag.name), 100))) t.user_id, left(btrim(tag.name), 100)
+FROM tasks t, unnest(t.tags) AS tag(name)
+WHERE t.user_id IS NOT NULL AND btrim(tag.name) <> ''
+ORDER BY t.user_id, lower(left(btrim(tag.name), 100)), t.created_at;
+
+INSERT INTO task_tags (task_id, tag_id, position)
+SELECT t.id, tg.id, MIN(tag.ord) - 1
+FROM tasks t
+CROSS JOIN LATERAL unnest(t.tags) WITH ORDINALITY AS tag(name, ord)
+JOIN tags tg ON tg.user_id = t.user_id AND lower(tg.name) = lower(left(btrim(tag.name), 100))
+GROUP BY t.id, tg.id;
+
+ALTER TABLE tasks DROP COLUMN tags;
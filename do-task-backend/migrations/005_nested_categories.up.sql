@@ -0,0 +1,13 @@
+-- Allow categories to be nested and scope their names per user and parent
+
+ALTER TABLE categories DROP CONSTRAINT IF EXISTS categories_name_key;
+
+ALTER TABLE categories ADD COLUMN parent_id UUID REFERENCES categories(id) ON DELETE RESTRICT;
+CREATE INDEX idx_categories_parent_id ON categories(parent_id);
+
+-- Top-level categories share the all-zero parent so one index covers both cases
+CREATE UNIQUE INDEX idx_categories_user_parent_name ON categories(
+    user_id,
+    COALESCE(parent_id, '00000000-0000-0000-0000-000000000000'::uuid),
+    name
+);
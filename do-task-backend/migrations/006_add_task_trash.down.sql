@@ -0,0 +1,4 @@
+DELETE FROM tasks WHERE deleted_at IS NOT NULL;
+ALTER TABLE tasks ALTER COLUMN category_id SET NOT NULL;
+DROP INDEX IF EXISTS idx_tasks_user_deleted_at;
+ALTER TABLE tasks DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,9 @@
+-- Soft deletion for tasks so category deletion can move them to a trash
+
+ALTER TABLE tasks ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX idx_tasks_user_deleted_at ON tasks(user_id, deleted_at);
+
+-- Trashed tasks lose their category when it is deleted; restoring them asks
+-- for a new one
+ALTER TABLE tasks ALTER COLUMN category_id DROP NOT NULL;
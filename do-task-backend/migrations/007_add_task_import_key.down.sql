@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_tasks_user_import_key;
+ALTER TABLE tasks DROP COLUMN IF EXISTS import_key;
@@ -0,0 +1,5 @@
+-- Remember where imported tasks came from so re-imports don't duplicate them
+
+ALTER TABLE tasks ADD COLUMN import_key VARCHAR(64);
+
+CREATE UNIQUE INDEX idx_tasks_user_import_key ON tasks(user_id, import_key) WHERE import_key IS NOT NULL;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_tasks_user_caldav_name;
+ALTER TABLE tasks DROP COLUMN IF EXISTS ical_uid;
+ALTER TABLE tasks DROP COLUMN IF EXISTS caldav_name;
+
+DROP TABLE IF EXISTS app_passwords;
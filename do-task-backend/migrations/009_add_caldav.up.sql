@@ -0,0 +1,18 @@
+-- App passwords for CalDAV clients, and the resource names and UIDs those
+-- clients pick for the tasks they create
+
+CREATE TABLE app_passwords (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    last_used_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_app_passwords_user_id ON app_passwords(user_id);
+
+ALTER TABLE tasks ADD COLUMN caldav_name VARCHAR(255);
+ALTER TABLE tasks ADD COLUMN ical_uid VARCHAR(255);
+
+CREATE UNIQUE INDEX idx_tasks_user_caldav_name ON tasks(user_id, caldav_name) WHERE caldav_name IS NOT NULL;
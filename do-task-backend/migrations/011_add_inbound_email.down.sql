@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS task_attachments;
+DROP TABLE IF EXISTS inbound_email_addresses;
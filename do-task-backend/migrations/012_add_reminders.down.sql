@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN IF EXISTS task_reminders;
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS reminders;
@@ -0,0 +1,41 @@
+-- Task reminders. A reminder fires at an absolute time or a number of minutes
+-- before the task's due date; a scheduler claims due rows with SKIP LOCKED so
+-- several server replicas never send the same reminder twice.
+
+CREATE TABLE reminders (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    remind_at TIMESTAMP WITH TIME ZONE,
+    offset_minutes INTEGER,
+    fire_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    channels TEXT[] NOT NULL,
+    delivered_channels TEXT[] NOT NULL DEFAULT '{}',
+    status VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_error TEXT,
+    sent_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CHECK ((remind_at IS NULL) <> (offset_minutes IS NULL))
+);
+
+CREATE INDEX idx_reminders_due ON reminders(next_attempt_at) WHERE status = 'PENDING';
+CREATE INDEX idx_reminders_task_id ON reminders(task_id);
+
+-- In-app notifications, written by the reminder scheduler's in-app channel
+CREATE TABLE notifications (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type VARCHAR(50) NOT NULL,
+    title VARCHAR(500) NOT NULL,
+    body TEXT NOT NULL DEFAULT '',
+    task_id UUID REFERENCES tasks(id) ON DELETE SET NULL,
+    read_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_notifications_user_id ON notifications(user_id, created_at DESC);
+
+-- The "Task Reminders" switch in the profile settings
+ALTER TABLE users ADD COLUMN task_reminders BOOLEAN NOT NULL DEFAULT TRUE;
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_tasks_completed_at;
+ALTER TABLE tasks DROP COLUMN IF EXISTS completed_at;
+
+DROP INDEX IF EXISTS idx_users_digest_next_at;
+ALTER TABLE users DROP COLUMN IF EXISTS digest_next_at;
+ALTER TABLE users DROP COLUMN IF EXISTS timezone;
+ALTER TABLE users DROP COLUMN IF EXISTS weekly_digest;
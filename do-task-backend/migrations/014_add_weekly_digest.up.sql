@@ -0,0 +1,13 @@
+-- Weekly digest opt-in, the timezone it is rendered in, and when it is next due
+ALTER TABLE users ADD COLUMN weekly_digest BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN timezone TEXT NOT NULL DEFAULT 'UTC';
+ALTER TABLE users ADD COLUMN digest_next_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX idx_users_digest_next_at ON users(digest_next_at) WHERE weekly_digest;
+
+-- When a task was completed, for "completed last week"
+ALTER TABLE tasks ADD COLUMN completed_at TIMESTAMP WITH TIME ZONE;
+
+UPDATE tasks SET completed_at = updated_at WHERE status = 'COMPLETED';
+
+CREATE INDEX idx_tasks_completed_at ON tasks(user_id, completed_at) WHERE completed_at IS NOT NULL;
@@ -0,0 +1,8 @@
+ALTER TABLE users DROP COLUMN IF EXISTS session_timeout_minutes;
+ALTER TABLE users DROP COLUMN IF EXISTS marketing_emails;
+ALTER TABLE users DROP COLUMN IF EXISTS push_notifications;
+ALTER TABLE users DROP COLUMN IF EXISTS email_notifications;
+ALTER TABLE users DROP COLUMN IF EXISTS start_of_week;
+ALTER TABLE users DROP COLUMN IF EXISTS date_format;
+ALTER TABLE users DROP COLUMN IF EXISTS language;
+ALTER TABLE users DROP COLUMN IF EXISTS theme;
@@ -0,0 +1,11 @@
+-- Preferences collected on the settings page. timezone, task_reminders and
+-- weekly_digest already live on users; the defaults match
+-- models.DefaultPreferences.
+ALTER TABLE users ADD COLUMN theme VARCHAR(20) NOT NULL DEFAULT 'SYSTEM';
+ALTER TABLE users ADD COLUMN language VARCHAR(20) NOT NULL DEFAULT 'en';
+ALTER TABLE users ADD COLUMN date_format VARCHAR(20) NOT NULL DEFAULT 'MM_DD_YYYY';
+ALTER TABLE users ADD COLUMN start_of_week VARCHAR(20) NOT NULL DEFAULT 'MONDAY';
+ALTER TABLE users ADD COLUMN email_notifications BOOLEAN NOT NULL DEFAULT TRUE;
+ALTER TABLE users ADD COLUMN push_notifications BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN marketing_emails BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN session_timeout_minutes INTEGER NOT NULL DEFAULT 30;
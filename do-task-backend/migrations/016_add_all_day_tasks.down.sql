@@ -0,0 +1,4 @@
+ALTER TABLE tasks DROP COLUMN IF EXISTS start_on;
+ALTER TABLE tasks DROP COLUMN IF EXISTS start_date;
+ALTER TABLE tasks DROP COLUMN IF EXISTS due_on;
+ALTER TABLE tasks DROP COLUMN IF EXISTS all_day;
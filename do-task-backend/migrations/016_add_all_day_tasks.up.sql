@@ -0,0 +1,8 @@
+-- All-day tasks are due on a calendar day rather than at an instant. due_on
+-- holds that day and due_date its midnight in the owner's timezone, which is
+-- recomputed when the owner changes timezone. start_date/start_on follow the
+-- same rules for the optional start date.
+ALTER TABLE tasks ADD COLUMN all_day BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE tasks ADD COLUMN due_on DATE;
+ALTER TABLE tasks ADD COLUMN start_date TIMESTAMP WITH TIME ZONE;
+ALTER TABLE tasks ADD COLUMN start_on DATE;
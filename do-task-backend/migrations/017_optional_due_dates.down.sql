@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_tasks_without_due_date;
+
+-- Earlier versions expect every task to have a due date
+UPDATE tasks SET due_date = created_at WHERE due_date IS NULL;
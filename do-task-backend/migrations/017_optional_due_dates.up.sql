@@ -0,0 +1,9 @@
+-- Due dates are optional. The column always allowed NULL but the API did not,
+-- so tasks created without one hold Go's zero time; those have no due date.
+UPDATE tasks SET due_date = NULL, due_on = NULL WHERE due_date < '0002-01-01';
+
+-- Offset reminders count back from the due date and go with it
+DELETE FROM reminders r USING tasks t
+WHERE t.id = r.task_id AND r.offset_minutes IS NOT NULL AND t.due_date IS NULL;
+
+CREATE INDEX idx_tasks_without_due_date ON tasks(user_id) WHERE due_date IS NULL;
@@ -0,0 +1,20 @@
+-- Focus (pomodoro) sessions on tasks. The server keeps the clock: a session
+-- focuses until ends_at, then breaks until break_ends_at. Active sessions left
+-- past their break are completed the next time the user's sessions are read.
+CREATE TABLE focus_sessions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    focus_minutes INTEGER NOT NULL CHECK (focus_minutes > 0),
+    break_minutes INTEGER NOT NULL CHECK (break_minutes >= 0),
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    ends_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    break_ends_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'ACTIVE',
+    interrupted BOOLEAN NOT NULL DEFAULT FALSE,
+    ended_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX idx_focus_sessions_active ON focus_sessions(user_id) WHERE status = 'ACTIVE';
+CREATE INDEX idx_focus_sessions_user_started ON focus_sessions(user_id, started_at);
+CREATE INDEX idx_focus_sessions_task_id ON focus_sessions(task_id);
@@ -0,0 +1,14 @@
+-- Saved views: a named task filter and sort, stored as the JSON of the
+-- GraphQL inputs and compiled to SQL whenever the view is opened
+CREATE TABLE saved_views (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    filter JSONB NOT NULL DEFAULT '{}',
+    sort JSONB,
+    display_mode VARCHAR(20) NOT NULL DEFAULT 'LIST' CHECK (display_mode IN ('LIST', 'BOARD', 'CALENDAR')),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_saved_views_user_name ON saved_views(user_id, lower(name));
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS task_template_shares;
+DROP TABLE IF EXISTS task_templates;
@@ -0,0 +1,21 @@
+-- Task templates: a named list of tasks, stored as the JSON of their inputs
+-- with dates as day offsets. Shares let other users create tasks from them.
+CREATE TABLE task_templates (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    items JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_task_templates_user_name ON task_templates(user_id, lower(name));
+
+CREATE TABLE task_template_shares (
+    template_id UUID NOT NULL REFERENCES task_templates(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (template_id, user_id)
+);
+
+CREATE INDEX idx_task_template_shares_user_id ON task_template_shares(user_id);
@@ -0,0 +1,17 @@
+-- Versions for optimistic concurrency: every update of a task or category
+-- bumps its version, whichever code path makes it, and clients send back the
+-- version they saw to detect concurrent edits
+ALTER TABLE tasks ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE categories ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+CREATE OR REPLACE FUNCTION bump_version() RETURNS TRIGGER AS $$
+BEGIN
+    NEW.version := OLD.version + 1;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER tasks_bump_version BEFORE UPDATE ON tasks
+    FOR EACH ROW EXECUTE FUNCTION bump_version();
+CREATE TRIGGER categories_bump_version BEFORE UPDATE ON categories
+    FOR EACH ROW EXECUTE FUNCTION bump_version();
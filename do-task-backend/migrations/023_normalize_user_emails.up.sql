@@ -0,0 +1,33 @@
+-- Emails are stored trimmed and lowercased and are unique regardless of case.
+-- Accounts whose addresses differ only in case have to be merged by hand
+-- before this runs.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM users GROUP BY lower(trim(email)) HAVING count(*) > 1) THEN
+        RAISE EXCEPTION 'users with emails that differ only in case or spacing must be merged first';
+    END IF;
+END $$;
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key;
+UPDATE users SET email = lower(trim(email)) WHERE email <> lower(trim(email));
+CREATE UNIQUE INDEX idx_users_email_lower ON users(lower(email));
+ALTER TABLE users ADD CONSTRAINT users_email_normalized CHECK (email = lower(trim(email)));
+
+-- An address is verified through a link mailed to it. A changed address waits
+-- in pending_email until its link is followed.
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP WITH TIME ZONE;
+-- Accounts from before verification existed have been receiving mail at
+-- their address all along, so they count as verified
+UPDATE users SET email_verified_at = NOW();
+ALTER TABLE users ADD COLUMN pending_email VARCHAR(255);
+
+CREATE TABLE email_verifications (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_email_verifications_user_id ON email_verifications(user_id);
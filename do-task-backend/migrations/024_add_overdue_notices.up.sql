@@ -0,0 +1,8 @@
+-- One overdue notification per due date: a task is claimed here when the
+-- reminder scheduler raises its TASK_OVERDUE alert, and again only once its
+-- due date has changed and passed
+CREATE TABLE overdue_notices (
+    task_id UUID PRIMARY KEY REFERENCES tasks(id) ON DELETE CASCADE,
+    due_date TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
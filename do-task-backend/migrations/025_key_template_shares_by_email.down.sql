@@ -0,0 +1,15 @@
+-- Shares with addresses that have no account cannot be kept
+ALTER TABLE task_template_shares ADD COLUMN user_id UUID REFERENCES users(id) ON DELETE CASCADE;
+
+UPDATE task_template_shares s SET user_id = u.id
+FROM users u WHERE lower(u.email) = s.email;
+
+DELETE FROM task_template_shares WHERE user_id IS NULL;
+
+DROP INDEX idx_task_template_shares_email;
+ALTER TABLE task_template_shares DROP CONSTRAINT task_template_shares_pkey;
+ALTER TABLE task_template_shares DROP COLUMN email;
+ALTER TABLE task_template_shares ALTER COLUMN user_id SET NOT NULL;
+ALTER TABLE task_template_shares ADD PRIMARY KEY (template_id, user_id);
+
+CREATE INDEX idx_task_template_shares_user_id ON task_template_shares(user_id);
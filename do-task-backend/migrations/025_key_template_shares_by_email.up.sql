@@ -0,0 +1,15 @@
+-- Template shares are kept by email address and apply to whoever has verified
+-- it, now or after signing up, so sharing never reveals whether an address
+-- belongs to an account
+ALTER TABLE task_template_shares ADD COLUMN email VARCHAR(255);
+
+UPDATE task_template_shares s SET email = lower(u.email)
+FROM users u WHERE u.id = s.user_id;
+
+ALTER TABLE task_template_shares DROP CONSTRAINT task_template_shares_pkey;
+DROP INDEX idx_task_template_shares_user_id;
+ALTER TABLE task_template_shares DROP COLUMN user_id;
+ALTER TABLE task_template_shares ALTER COLUMN email SET NOT NULL;
+ALTER TABLE task_template_shares ADD PRIMARY KEY (template_id, email);
+
+CREATE INDEX idx_task_template_shares_email ON task_template_shares(email);
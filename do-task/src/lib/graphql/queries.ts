@@ -136,7 +136,10 @@ export const UPDATE_CATEGORY = gql`
 // Delete category mutation
 export const DELETE_CATEGORY = gql`
 	mutation DeleteCategory($id: ID!) {
-		deleteCategory(id: $id)
+		deleteCategory(id: $id) {
+			success
+			affectedTasks
+		}
 	}
 `;
 
@@ -148,6 +151,8 @@ export const ME_QUERY = gql`
 			id
 			name
 			email
+			emailVerified
+			pendingEmail
 			createdAt
 			updatedAt
 		}
@@ -191,6 +196,8 @@ export const UPDATE_PROFILE_MUTATION = gql`
 			id
 			name
 			email
+			emailVerified
+			pendingEmail
 			createdAt
 			updatedAt
 		}
@@ -202,3 +209,23 @@ export const CHANGE_PASSWORD_MUTATION = gql`
 		changePassword(input: $input)
 	}
 `;
+
+export const VERIFY_EMAIL_MUTATION = gql`
+	mutation VerifyEmail($token: String!) {
+		verifyEmail(token: $token) {
+			id
+			name
+			email
+			emailVerified
+			pendingEmail
+			createdAt
+			updatedAt
+		}
+	}
+`;
+
+export const RESEND_VERIFICATION_EMAIL_MUTATION = gql`
+	mutation ResendVerificationEmail {
+		resendVerificationEmail
+	}
+`;
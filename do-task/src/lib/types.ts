@@ -60,6 +60,9 @@ export interface User {
 	id: string;
 	name: string;
 	email: string;
+	emailVerified?: boolean;
+	// A new email waiting for the user to follow its verification link
+	pendingEmail?: string | null;
 	createdAt: string;
 	updatedAt: string;
 }